@@ -8,7 +8,17 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/voage/sharprender-api/db"
+	"github.com/voage/sharprender-api/internal/sblob"
 	"github.com/voage/sharprender-api/shttp"
+	"github.com/voage/sharprender-api/shttp/alert"
+	"github.com/voage/sharprender-api/shttp/apikey"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/crawl"
+	"github.com/voage/sharprender-api/shttp/retention"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"github.com/voage/sharprender-api/shttp/schedule"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"github.com/voage/sharprender-api/shttp/webhook"
 )
 
 func main() {
@@ -20,18 +30,77 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	mongoClient, err := db.InitMongoDB(ctx)
-	if err != nil {
-		log.Fatalf("Error initializing MongoDB: %s", err)
-	}
-	defer mongoClient.Disconnect(ctx)
-
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	router := shttp.NewRouter(mongoClient)
+	artifacts, err := sblob.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Error opening blob store: %s", err)
+	}
+
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring authentication: %s", err)
+	}
+
+	limits, err := usage.LimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring limits: %s", err)
+	}
+
+	var router http.Handler
+	if os.Getenv("MONGO_URI") == "" && os.Getenv("SCAN_STORE") == "bolt" {
+		// Without Mongo only the scan API can run, from the local store.
+		scans, err := scan.NewStoreFromEnv(nil)
+		if err != nil {
+			log.Fatalf("Error opening scan store: %s", err)
+		}
+		log.Printf("MONGO_URI is not set; serving personal scans from the local store only")
+		router = shttp.NewLocalRouter(scans, artifacts, verifier, limits)
+	} else {
+		mongoClient, err := db.InitMongoDB(ctx)
+		if err != nil {
+			log.Fatalf("Error initializing MongoDB: %s", err)
+		}
+		defer mongoClient.Disconnect(ctx)
+
+		if err := db.Migrate(ctx, mongoClient.Database("sharprenderdb"), shttp.Migrations); err != nil {
+			log.Fatalf("Error migrating database: %s", err)
+		}
+
+		scans, err := scan.NewStoreFromEnv(mongoClient.Client)
+		if err != nil {
+			log.Fatalf("Error opening scan store: %s", err)
+		}
+
+		verifier.AcceptAPIKeys(apikey.NewAuthenticatorFromClient(mongoClient.Client))
+
+		defaultRetention, err := retention.RetentionFromEnv()
+		if err != nil {
+			log.Fatalf("Error configuring retention: %s", err)
+		}
+
+		notifiers, err := alert.NotifiersFromEnv()
+		if err != nil {
+			log.Fatalf("Error configuring alerts: %s", err)
+		}
+
+		router = shttp.NewRouter(mongoClient, scans, artifacts, verifier, limits, notifiers)
+
+		scheduler := schedule.NewSchedulerFromClient(mongoClient.Client, scans, artifacts, notifiers)
+		go scheduler.Run(ctx)
+
+		janitor := retention.NewJanitorFromClient(mongoClient.Client, scans, artifacts, defaultRetention)
+		go janitor.Run(ctx)
+
+		dispatcher := webhook.NewDispatcherFromClient(mongoClient.Client)
+		go dispatcher.Run(ctx)
+
+		crawls := crawl.NewCrawlServiceFromClient(mongoClient.Client, scans, artifacts, usage.NewUsageServiceFromClient(mongoClient.Client, limits))
+		go crawls.ReapStale(ctx)
+	}
 
 	log.Printf("Starting server on :%s", port)
 
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/voage/sharprender-api/shttp/budget"
+)
+
+const defaultConfigPath = "sharprender.json"
+
+// Config is read from sharprender.json, for example:
+//
+//	{
+//	  "budgets": [
+//	    {"name": "home", "url_pattern": "https://example.com/*", "limits": {"max_total_bytes": 500000}}
+//	  ]
+//	}
+type Config struct {
+	Budgets []budget.Budget `json:"budgets"`
+}
+
+// loadConfig reads the config at path. The default path may be absent; an
+// explicitly given one may not.
+func loadConfig(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	for _, b := range config.Budgets {
+		if err := b.Limits.Validate(); err != nil {
+			return nil, fmt.Errorf("budget %q: %w", b.Name, err)
+		}
+	}
+	return &config, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/voage/sharprender-api/internal/sreport"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const defaultStorePath = ".sharprender/scans.db"
+
+// localUser owns the scans saved by the CLI; a local store has a single user.
+const localUser = "local"
+
+func runHistory(args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	storePath := flags.String("store", defaultStorePath, "local scan store file")
+	limit := flags.Int("limit", 20, "number of scans to list, newest first")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: sharprender history [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if flags.NArg() != 0 || *limit < 1 {
+		flags.Usage()
+		return exitError
+	}
+	if _, err := os.Stat(*storePath); err != nil {
+		fmt.Fprintf(os.Stderr, "no scan store at %s; save scans with \"sharprender scan -store %s <url>\"\n", *storePath, *storePath)
+		return exitError
+	}
+
+	store, err := scan.OpenBoltScanStore(*storePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer store.Close()
+
+	newestFirst := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	scans, err := store.FindHistory(context.Background(), bson.M{"user_id": localUser}, bson.M{}, newestFirst, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read scans: %v\n", err)
+		return exitError
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tIMAGES\tSIZE\tFINDINGS\tURL")
+	for _, s := range scans {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%s\n",
+			s.ID.Hex(), s.CreatedAt.Local().Format("2006-01-02 15:04"), s.Summary.ImageCount,
+			sreport.FormatBytes(s.Summary.TotalBytes), s.Summary.FindingsCount, truncate(s.URL, 80))
+	}
+	if err := tw.Flush(); err != nil {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,51 @@
+// Command sharprender runs image scans locally, without the API server or
+// MongoDB, so they can gate CI pipelines.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// Exit codes let CI tell a regression apart from a broken run.
+const (
+	exitOK        = 0
+	exitViolation = 1
+	exitError     = 2
+)
+
+const usage = `Usage: sharprender <command> [flags]
+
+Commands:
+  scan <url>        Scan a page's images and evaluate budgets
+  optimize <dir>    Compress the images in a local directory
+  history           List the scans saved with "scan -store"
+
+Run "sharprender <command> -h" for the flags of a command.
+`
+
+func main() {
+	// .env is optional for the CLI; it only supplies OPENAI_KEY for --ai.
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitError)
+	}
+
+	switch os.Args[1] {
+	case "scan":
+		os.Exit(runScan(os.Args[2:]))
+	case "optimize":
+		os.Exit(runOptimize(os.Args[2:]))
+	case "history":
+		os.Exit(runHistory(os.Args[2:]))
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(exitError)
+	}
+}
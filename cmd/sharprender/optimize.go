@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
+)
+
+var optimizeExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+	".avif": true,
+}
+
+var optimizeFormats = map[string]string{
+	"keep": "",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+	"avif": "image/avif",
+}
+
+// optimizeJob is one image below the source directory and what became of it.
+type optimizeJob struct {
+	rel    string
+	result *simage.OptimizeResult
+	output string
+	err    error
+}
+
+func runOptimize(args []string) int {
+	flags := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	outDir := flags.String("out", "", "mirror directory for the optimized files (default <dir>-optimized)")
+	inPlace := flags.Bool("in-place", false, "overwrite the source files, keeping originals in --backup-dir")
+	backupDir := flags.String("backup-dir", "", "where --in-place keeps originals (default <dir>-backup)")
+	format := flags.String("format", "keep", "output format: keep, jpeg, png, webp or avif")
+	targetPSNR := flags.Float64("min-psnr", 0, "lowest acceptable PSNR in dB (default 40)")
+	maxWidth := flags.Int("max-width", 0, "downscale images wider than this, 0 for no limit")
+	workers := flags.Int("workers", 4, "images optimized in parallel")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: sharprender optimize [flags] <dir>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+	srcDir := filepath.Clean(flags.Arg(0))
+
+	mime, ok := optimizeFormats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return exitError
+	}
+	if *inPlace && *outDir != "" {
+		fmt.Fprintln(os.Stderr, "--out and --in-place are mutually exclusive")
+		return exitError
+	}
+	if *outDir == "" {
+		*outDir = srcDir + "-optimized"
+	}
+	if *backupDir == "" {
+		*backupDir = srcDir + "-backup"
+	}
+	opts := simage.OptimizeOptions{Format: mime, MaxWidth: *maxWidth, TargetPSNR: *targetPSNR}
+
+	jobs, err := findImages(srcDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read %s: %v\n", srcDir, err)
+		return exitError
+	}
+	if err := checkOutputs(srcDir, jobs, mime, *inPlace); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	claims := &outputClaims{paths: map[string]string{}}
+	var wg sync.WaitGroup
+	queue := make(chan *optimizeJob)
+	for w := 0; w < max(*workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if *inPlace {
+					job.output, job.err = optimizeInPlace(srcDir, *backupDir, job, opts, claims)
+				} else {
+					job.output, job.err = optimizeToMirror(srcDir, *outDir, job, opts, claims)
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := printSavings(os.Stdout, jobs); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return exitError
+	}
+
+	for _, job := range jobs {
+		if job.err != nil {
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// findImages lists the images below dir by path relative to it.
+func findImages(dir string) ([]*optimizeJob, error) {
+	var jobs []*optimizeJob
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !optimizeExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, &optimizeJob{rel: rel})
+		return nil
+	})
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].rel < jobs[j].rel })
+	return jobs, err
+}
+
+// checkOutputs fails when two images would be written to the same file, such
+// as a.jpg and a.png both converted to a.webp, or when an in-place conversion
+// would overwrite an existing file. Every image reserves its own path, where
+// it goes when its format stays, and its converted path.
+func checkOutputs(srcDir string, jobs []*optimizeJob, format string, inPlace bool) error {
+	owners := map[string]string{}
+	for _, job := range jobs {
+		owners[job.rel] = job.rel
+	}
+
+	var collisions []string
+	for _, job := range jobs {
+		if format == "" {
+			continue
+		}
+		converted := outputPath(job.rel, format)
+		if converted == job.rel {
+			continue
+		}
+		if owner, ok := owners[converted]; ok {
+			collisions = append(collisions, fmt.Sprintf("%s and %s would both be written to %s", owner, job.rel, converted))
+			continue
+		}
+		owners[converted] = job.rel
+		if _, err := os.Stat(filepath.Join(srcDir, converted)); inPlace && err == nil {
+			collisions = append(collisions, fmt.Sprintf("converting %s would overwrite %s", job.rel, converted))
+		}
+	}
+
+	if len(collisions) > 0 {
+		return fmt.Errorf("output collisions, nothing was written:\n  %s", strings.Join(collisions, "\n  "))
+	}
+	return nil
+}
+
+// outputClaims hands out output paths while images are written, catching
+// collisions checkOutputs can't predict, such as a .jpg file holding a PNG.
+type outputClaims struct {
+	mu    sync.Mutex
+	paths map[string]string
+}
+
+func (c *outputClaims) claim(path, rel string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if owner, ok := c.paths[path]; ok && owner != rel {
+		return fmt.Errorf("%s is already written for %s", path, owner)
+	}
+	c.paths[path] = rel
+	return nil
+}
+
+func optimizeFile(srcDir string, job *optimizeJob, opts simage.OptimizeOptions) error {
+	data, err := os.ReadFile(filepath.Join(srcDir, job.rel))
+	if err != nil {
+		return err
+	}
+	job.result, err = simage.Optimize(data, opts)
+	return err
+}
+
+// optimizeToMirror writes the optimized image to the same relative path below
+// outDir, with the extension of its output format.
+func optimizeToMirror(srcDir, outDir string, job *optimizeJob, opts simage.OptimizeOptions, claims *outputClaims) (string, error) {
+	if err := optimizeFile(srcDir, job, opts); err != nil {
+		return "", err
+	}
+	output := filepath.Join(outDir, outputPath(job.rel, job.result.Format))
+	if err := claims.claim(output, job.rel); err != nil {
+		return "", err
+	}
+	return output, writeFile(output, job.result.Data)
+}
+
+// optimizeInPlace overwrites the source when the format is unchanged, after
+// copying the original to backupDir. A converted image is written next to the
+// original, which is left untouched, and never replaces an existing file.
+func optimizeInPlace(srcDir, backupDir string, job *optimizeJob, opts simage.OptimizeOptions, claims *outputClaims) (string, error) {
+	if err := optimizeFile(srcDir, job, opts); err != nil {
+		return "", err
+	}
+	source := filepath.Join(srcDir, job.rel)
+	output := filepath.Join(srcDir, outputPath(job.rel, job.result.Format))
+	if job.result.Savings <= 0 {
+		return source, nil
+	}
+	if err := claims.claim(output, job.rel); err != nil {
+		return "", err
+	}
+	if output != source {
+		if _, err := os.Stat(output); err == nil {
+			return "", fmt.Errorf("%s already exists", output)
+		}
+	}
+
+	if output == source {
+		original, err := os.ReadFile(source)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFile(filepath.Join(backupDir, job.rel), original); err != nil {
+			return "", fmt.Errorf("failed to back up original: %w", err)
+		}
+	}
+	return output, writeFile(output, job.result.Data)
+}
+
+// outputPath swaps the extension of rel when the format changed.
+func outputPath(rel, format string) string {
+	ext := filepath.Ext(rel)
+	want := "." + simage.FormatExtension(format)
+	if strings.EqualFold(ext, want) || (want == ".jpg" && strings.EqualFold(ext, ".jpeg")) {
+		return rel
+	}
+	return strings.TrimSuffix(rel, ext) + want
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func printSavings(out io.Writer, jobs []*optimizeJob) error {
+	var before, after int64
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BEFORE\tAFTER\tSAVED\tFORMAT\tQUALITY\tPSNR\tFILE")
+	for _, job := range jobs {
+		if job.err != nil {
+			fmt.Fprintf(tw, "-\t-\t-\t-\t-\t-\t%s: %v\n", job.rel, job.err)
+			continue
+		}
+		r := job.result
+		before += int64(r.OriginalSize)
+		after += int64(r.Size)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%.1f\t%s\n",
+			sreport.FormatBytes(int64(r.OriginalSize)), sreport.FormatBytes(int64(r.Size)), savedShare(r.Savings, r.OriginalSize),
+			simage.FormatExtension(r.Format), r.Quality, r.PSNR, job.rel)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n%d images, %s -> %s (%s saved)\n",
+		len(jobs), sreport.FormatBytes(before), sreport.FormatBytes(after), savedShare(int(before-after), int(before)))
+	return nil
+}
+
+func savedShare(saved, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(saved)/float64(total))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckOutputs(t *testing.T) {
+	jobs := func(rels ...string) []*optimizeJob {
+		var jobs []*optimizeJob
+		for _, rel := range rels {
+			jobs = append(jobs, &optimizeJob{rel: rel})
+		}
+		return jobs
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.webp"), []byte("not an image we found"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		jobs    []*optimizeJob
+		format  string
+		inPlace bool
+		want    string
+	}{
+		{"keep format", jobs("a.jpg", "a.png"), "", false, ""},
+		{"distinct names", jobs("a.jpg", "b.png"), "image/webp", false, ""},
+		{"same name", jobs("a.jpg", "a.png"), "image/webp", false, "a.jpg and a.png would both be written to a.webp"},
+		{"onto a source", jobs("a.png", "a.webp"), "image/webp", false, "a.webp and a.png would both be written to a.webp"},
+		{"onto an existing file in place", jobs("logo.png"), "image/webp", true, "converting logo.png would overwrite logo.webp"},
+		{"existing file in the source of a mirror", jobs("logo.png"), "image/webp", false, ""},
+	} {
+		err := checkOutputs(dir, tc.jobs, tc.format, tc.inPlace)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
+	"github.com/voage/sharprender-api/shttp/scan"
+)
+
+func runScan(args []string) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table, json, junit, sarif or markdown")
+	output := flags.String("output", "", "write the report to this file instead of stdout")
+	configPath := flags.String("config", "", "budget config file (default ./"+defaultConfigPath+" if present)")
+	profile := flags.String("profile", "No Throttling", "named network profile")
+	cpuSlowdown := flags.Float64("cpu-slowdown", 1, "CPU throttling factor, 1 for none")
+	withAI := flags.Bool("ai", false, "include OpenAI recommendations (needs OPENAI_KEY)")
+	timeout := flags.Duration("timeout", 5*time.Minute, "overall scan timeout")
+	storePath := flags.String("store", "", "also save the scan to this local store file, e.g. "+defaultStorePath)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: sharprender scan [flags] <url>")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+	targetURL := flags.Arg(0)
+
+	write, ok := reportWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return exitError
+	}
+	if *withAI && os.Getenv("OPENAI_KEY") == "" {
+		fmt.Fprintln(os.Stderr, "--ai needs OPENAI_KEY to be set")
+		return exitError
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	scraper := simage.NewImageScraper()
+	scraper.SetHeadless(true)
+	if err := scraper.SetNetworkProfile(*profile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if err := scraper.SetCPUSlowdown(*cpuSlowdown); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	analysis, err := scraper.Analyze(ctx, targetURL, simage.AnalyzeOptions{AIRecommendations: *withAI})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scan failed: %v\n", err)
+		return exitError
+	}
+
+	report := sreport.Report{
+		URL:        analysis.URL,
+		Metadata:   analysis.Metadata,
+		Conditions: analysis.Conditions,
+		Images:     analysis.Images,
+		Findings:   analysis.Findings,
+		Duplicates: analysis.Duplicates,
+		Budgets:    []simage.BudgetResult{},
+		CreatedAt:  time.Now(),
+	}
+	for _, b := range config.Budgets {
+		if b.Matches(targetURL) {
+			report.Budgets = append(report.Budgets, b.Evaluate(analysis.Images))
+		}
+	}
+	report.Passed = simage.BudgetsPassed(report.Budgets)
+
+	if *storePath != "" {
+		if err := saveScan(ctx, *storePath, report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to save scan: %v\n", err)
+			return exitError
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create output: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := write(out, report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return exitError
+	}
+
+	if !report.Passed {
+		return exitViolation
+	}
+	return exitOK
+}
+
+var reportWriters = map[string]func(io.Writer, sreport.Report) error{
+	"table":    printTable,
+	"json":     printJSON,
+	"junit":    sreport.WriteJUnit,
+	"sarif":    sreport.WriteSARIF,
+	"markdown": sreport.WriteMarkdown,
+}
+
+// saveScan records the scan in a local store, where "sharprender history"
+// lists it.
+func saveScan(ctx context.Context, path string, report sreport.Report) error {
+	store, err := scan.OpenBoltScanStore(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	_, err = store.Create(ctx, &scan.Scan{
+		UserID:     localUser,
+		URL:        report.URL,
+		Metadata:   report.Metadata,
+		Images:     report.Images,
+		Findings:   report.Findings,
+		Duplicates: report.Duplicates,
+		Budgets:    report.Budgets,
+		Conditions: report.Conditions,
+		Summary:    scan.Summarize(report.Images, report.Findings),
+		CreatedAt:  report.CreatedAt,
+	})
+	return err
+}
+
+func printJSON(out io.Writer, report sreport.Report) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func printTable(out io.Writer, report sreport.Report) error {
+	fmt.Fprintf(out, "%s (%s)\n%d images, %s total\n\n",
+		report.URL, report.Conditions.Profile, len(report.Images), sreport.FormatBytes(report.TotalBytes()))
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tFORMAT\tDIMENSIONS\tLOAD\tLCP\tSRC")
+	for _, img := range report.Images {
+		lcp := ""
+		if img.LCP {
+			lcp = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%dx%d\t%.2fs\t%s\t%s\n",
+			sreport.FormatBytes(int64(img.Size)), img.Format, img.Width, img.Height, img.Network.LoadTime, lcp, truncate(img.Src, 80))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Findings) > 0 {
+		fmt.Fprintln(out, "\nFindings:")
+		for _, f := range report.Findings {
+			fmt.Fprintf(out, "  [%s] %s: %s\n", f.Severity, f.RuleID, f.Message)
+		}
+	}
+
+	if len(report.Budgets) > 0 {
+		fmt.Fprintln(out, "\nBudgets:")
+		for _, b := range report.Budgets {
+			status := "PASS"
+			if !b.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(out, "  %s %s\n", status, b.Name)
+			for _, c := range b.Checks {
+				if !c.Passed {
+					fmt.Fprintf(out, "      %s: %v (limit %v)\n", c.Metric, c.Actual, c.Limit)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const migrationsCollection = "schema_migrations"
+
+// Migration is one versioned change to the database, such as creating indexes
+// or backfilling fields. Up must be safe to run again: a migration that fails
+// part way, or runs on two instances starting at once, is retried in full.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+}
+
+// appliedMigration records a migration in the schema_migrations collection.
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrate applies the migrations not yet recorded in schema_migrations, in
+// version order, and records each one once it succeeds.
+func Migrate(ctx context.Context, database *mongo.Database, migrations []Migration) error {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return fmt.Errorf("migration %d is listed after %d; versions must increase", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+
+	collection := database.Collection(migrationsCollection)
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	var applied []appliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	done := map[int]bool{}
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s", m.Version, m.Description)
+		if err := m.Up(ctx, database); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		_, err := collection.InsertOne(ctx, appliedMigration{Version: m.Version, Description: m.Description, AppliedAt: time.Now()})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// CreateIndexes creates indexes on a collection. Creating an index that
+// already exists with the same options does nothing.
+func CreateIndexes(ctx context.Context, database *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	if _, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", collection, err)
+	}
+	return nil
+}
+
+// CreateCollectionIndexes creates the indexes of several collections, in
+// collection name order.
+func CreateCollectionIndexes(ctx context.Context, database *mongo.Database, indexes map[string][]mongo.IndexModel) error {
+	collections := make([]string, 0, len(indexes))
+	for collection := range indexes {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		if err := CreateIndexes(ctx, database, collection, indexes[collection]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
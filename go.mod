@@ -11,8 +11,11 @@ require (
 	github.com/go-chi/cors v1.2.1
 	github.com/h2non/bimg v1.1.9
 	github.com/joho/godotenv v1.5.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/sashabaranov/go-openai v1.32.4
+	go.etcd.io/bbolt v1.3.11
 	go.mongodb.org/mongo-driver v1.17.1
+	golang.org/x/image v0.18.0
 	golang.org/x/time v0.8.0
 )
 
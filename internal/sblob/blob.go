@@ -0,0 +1,93 @@
+// Package sblob stores binary artifacts such as optimized images.
+package sblob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultDir = "data/blobs"
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash-separated keys. Keys sharing a prefix can be
+// removed together, which is how a scan's artifacts are cleaned up.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// FileStore keeps blobs as files below a root directory.
+type FileStore struct {
+	root string
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+// NewStoreFromEnv opens the file store at BLOB_DIR, defaulting to ./data/blobs.
+func NewStoreFromEnv() (*FileStore, error) {
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = defaultDir
+	}
+	return NewFileStore(dir)
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// DeletePrefix removes every blob whose key starts with prefix. The prefix
+// must name a directory, e.g. "scans/<id>/".
+func (s *FileStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if !strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("blob prefix %q must end with /", prefix)
+	}
+	path, err := s.path(strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// path maps a key to a file below root, rejecting keys that would escape it.
+func (s *FileStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
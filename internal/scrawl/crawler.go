@@ -0,0 +1,164 @@
+package scrawl
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	UserAgent          = "sharprender"
+	defaultMaxDepth    = 2
+	defaultMaxPages    = 20
+	defaultConcurrency = 2
+)
+
+// VisitFunc scans a single page and returns the links found on it.
+type VisitFunc func(ctx context.Context, pageURL string, depth int) ([]string, error)
+
+// Page is the outcome of visiting one URL during a crawl.
+type Page struct {
+	URL   string
+	Depth int
+	Err   error
+}
+
+// Crawler walks same-origin links breadth-first from a seed URL.
+type Crawler struct {
+	MaxDepth    int
+	MaxPages    int
+	Concurrency int
+	UseSitemap  bool
+	Client      *http.Client
+}
+
+func NewCrawler() *Crawler {
+	return &Crawler{
+		MaxDepth:    defaultMaxDepth,
+		MaxPages:    defaultMaxPages,
+		Concurrency: defaultConcurrency,
+		Client:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Crawl visits the seed and the pages reachable from it, level by level, until
+// MaxDepth or MaxPages is reached. Pages disallowed by robots.txt are skipped.
+func (c *Crawler) Crawl(ctx context.Context, seedURL string, visit VisitFunc) ([]Page, error) {
+	seed, err := url.Parse(seedURL)
+	if err != nil || seed.Host == "" {
+		return nil, fmt.Errorf("invalid seed URL %q", seedURL)
+	}
+
+	robots, err := FetchRobots(ctx, c.Client, seed, UserAgent)
+	if err != nil {
+		log.Printf("Warning: %v, crawling without robots rules", err)
+	}
+
+	seen := make(map[string]bool)
+	var level []string
+	enqueue := func(raw string, next *[]string) {
+		u, ok := sameOrigin(seed, raw)
+		if !ok || seen[u.String()] || !robots.Allowed(u) {
+			return
+		}
+		seen[u.String()] = true
+		*next = append(*next, u.String())
+	}
+
+	enqueue(seedURL, &level)
+	if c.UseSitemap {
+		sitemapPages, err := FetchSitemap(ctx, c.Client, seed, UserAgent)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		for _, p := range sitemapPages {
+			enqueue(p, &level)
+		}
+	}
+
+	var pages []Page
+	for depth := 0; depth <= c.MaxDepth && len(level) > 0; depth++ {
+		if remaining := c.MaxPages - len(pages); len(level) > remaining {
+			level = level[:remaining]
+		}
+
+		results := c.visitLevel(ctx, level, depth, visit)
+
+		var next []string
+		for _, r := range results {
+			pages = append(pages, r.page)
+			for _, link := range r.links {
+				enqueue(link, &next)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return pages, err
+		}
+		if len(pages) >= c.MaxPages {
+			break
+		}
+		level = next
+	}
+
+	return pages, nil
+}
+
+type visitResult struct {
+	page  Page
+	links []string
+}
+
+// visitLevel visits the URLs of one depth with at most Concurrency visits in flight.
+func (c *Crawler) visitLevel(ctx context.Context, urls []string, depth int, visit VisitFunc) []visitResult {
+	results := make([]visitResult, len(urls))
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, pageURL := range urls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = visitResult{page: Page{URL: pageURL, Depth: depth, Err: ctx.Err()}}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, pageURL string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			links, err := visit(ctx, pageURL, depth)
+			results[i] = visitResult{
+				page:  Page{URL: pageURL, Depth: depth, Err: err},
+				links: links,
+			}
+		}(i, pageURL)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// sameOrigin resolves raw against the seed and reports whether it shares the
+// seed's scheme and host. Fragments are dropped so anchors don't count as pages.
+func sameOrigin(seed *url.URL, raw string) (*url.URL, bool) {
+	u, err := seed.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, false
+	}
+	if u.Scheme != seed.Scheme || !strings.EqualFold(u.Host, seed.Host) {
+		return nil, false
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, true
+}
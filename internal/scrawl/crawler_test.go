@@ -0,0 +1,79 @@
+package scrawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// links maps each page to the links the visit finds on it.
+	links := map[string][]string{
+		"/":    {"/a", "b", "https://elsewhere.example.com/x", "/private/p", "/a#top", "/"},
+		"/a":   {"/a/1", "/a/2"},
+		"/b":   {"/b/1", "/a/1"},
+		"/a/1": {"/a/1/deep"},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		maxDepth int
+		maxPages int
+		want     []string
+	}{
+		{"seed only", 0, 20, []string{"/@0"}},
+		{"one level", 1, 20, []string{"/@0", "/a@1", "/b@1"}},
+		{"two levels", 2, 20, []string{"/@0", "/a@1", "/b@1", "/a/1@2", "/a/2@2", "/b/1@2"}},
+		{"page limit", 2, 4, []string{"/@0", "/a@1", "/b@1", "/a/1@2"}},
+	} {
+		crawler := NewCrawler()
+		crawler.MaxDepth = tc.maxDepth
+		crawler.MaxPages = tc.maxPages
+		crawler.Client = server.Client()
+
+		var mu sync.Mutex
+		visited := map[string]int{}
+		pages, err := crawler.Crawl(context.Background(), server.URL+"/", func(ctx context.Context, pageURL string, depth int) ([]string, error) {
+			path := strings.TrimPrefix(pageURL, server.URL)
+			mu.Lock()
+			visited[path]++
+			mu.Unlock()
+			return links[path], nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		var got []string
+		for _, p := range pages {
+			got = append(got, fmt.Sprintf("%s@%d", strings.TrimPrefix(p.URL, server.URL), p.Depth))
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("%s: pages = %v, want %v", tc.name, got, tc.want)
+		}
+		for path, n := range visited {
+			if n > 1 {
+				t.Errorf("%s: visited %s %d times", tc.name, path, n)
+			}
+		}
+	}
+}
+
+func TestCrawlRejectsInvalidSeed(t *testing.T) {
+	if _, err := NewCrawler().Crawl(context.Background(), "/relative", nil); err == nil {
+		t.Error("Crawl accepted a seed without a host")
+	}
+}
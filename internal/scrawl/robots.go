@@ -0,0 +1,143 @@
+package scrawl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Robots holds the robots.txt rules that apply to our user agent.
+type Robots struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// FetchRobots downloads and parses robots.txt for the origin of siteURL. A
+// missing robots.txt allows everything, as the standard requires.
+func FetchRobots(ctx context.Context, client *http.Client, siteURL *url.URL, userAgent string) (*Robots, error) {
+	robotsURL := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/robots.txt"}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &Robots{}, nil
+	}
+
+	return ParseRobots(io.LimitReader(resp.Body, 512*1024), userAgent), nil
+}
+
+// ParseRobots reads the groups matching userAgent, falling back to the "*" group.
+func ParseRobots(r io.Reader, userAgent string) *Robots {
+	agent := strings.ToLower(userAgent)
+
+	var specific, wildcard []robotsRule
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: compileRobotsPattern(value),
+			}
+			for _, ua := range groupAgents {
+				if ua == "*" {
+					wildcard = append(wildcard, rule)
+				} else if strings.Contains(agent, ua) {
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+
+	if len(specific) > 0 {
+		return &Robots{rules: specific}
+	}
+	return &Robots{rules: wildcard}
+}
+
+// Allowed reports whether the path (with query) may be crawled. The longest
+// matching rule wins and Allow wins ties.
+func (r *Robots) Allowed(u *url.URL) bool {
+	if r == nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, best := true, -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > best || (rule.length == best && rule.allow) {
+			allowed, best = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
+
+func compileRobotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
@@ -0,0 +1,73 @@
+package scrawl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := ParseRobots(strings.NewReader(`
+User-agent: *
+Disallow: /
+
+User-agent: Sharprender
+User-agent: other-bot
+Disallow: /private
+Allow: /private/press   # longer rules win
+Disallow: /*.pdf$
+Disallow: /search?
+Allow: /tmp
+Disallow: /tmp
+`), UserAgent)
+
+	for _, tc := range []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/about", true},
+		{"/private", false},
+		{"/private/team", false},
+		{"/private/press/2024", true},
+		{"/files/report.pdf", false},
+		{"/files/report.pdf?download=1", true},
+		{"/search?q=shoes", false},
+		{"/search", true},
+		// Allow wins a tie.
+		{"/tmp/x", true},
+	} {
+		u, err := url.Parse("https://example.com" + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := robots.Allowed(u); got != tc.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRobotsGroups(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		robots string
+		want   bool
+	}{
+		{"wildcard only", "User-agent: *\nDisallow: /blog", false},
+		// A group naming us replaces the wildcard group.
+		{"own group", "User-agent: *\nDisallow: /blog\n\nUser-agent: sharprender\nDisallow: /admin", true},
+		{"other agent", "User-agent: googlebot\nDisallow: /blog", true},
+		{"empty disallow", "User-agent: *\nDisallow:", true},
+		{"comments only", "# nothing here", true},
+	} {
+		robots := ParseRobots(strings.NewReader(tc.robots), UserAgent)
+		if got := robots.Allowed(&url.URL{Path: "/blog/post"}); got != tc.want {
+			t.Errorf("%s: Allowed(/blog/post) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	var missing *Robots
+	if !missing.Allowed(&url.URL{Path: "/blog"}) {
+		t.Error("a missing robots.txt disallows pages")
+	}
+}
@@ -0,0 +1,88 @@
+package scrawl
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxSitemaps bounds how many child sitemaps of a sitemap index are fetched.
+const maxSitemaps = 10
+
+type sitemapDocument struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// FetchSitemap returns the page URLs listed in /sitemap.xml for the origin of
+// siteURL, following one level of sitemap index. A child sitemap that can't be
+// fetched is logged and skipped so the rest of the index is still crawled.
+func FetchSitemap(ctx context.Context, client *http.Client, siteURL *url.URL, userAgent string) ([]string, error) {
+	sitemapURL := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/sitemap.xml"}
+
+	doc, err := fetchSitemapDocument(ctx, client, sitemapURL.String(), userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := locs(doc.URLs)
+	for i, child := range doc.Sitemaps {
+		if i == maxSitemaps {
+			break
+		}
+		childDoc, err := fetchSitemapDocument(ctx, client, strings.TrimSpace(child.Loc), userAgent)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Warning: skipping child sitemap: %v", err)
+			continue
+		}
+		pages = append(pages, locs(childDoc.URLs)...)
+	}
+
+	return pages, nil
+}
+
+func fetchSitemapDocument(ctx context.Context, client *http.Client, sitemapURL, userAgent string) (*sitemapDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching sitemap %s: %d", sitemapURL, resp.StatusCode)
+	}
+
+	var doc sitemapDocument
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 10*1024*1024)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+	return &doc, nil
+}
+
+func locs(entries []sitemapLoc) []string {
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if loc := strings.TrimSpace(e.Loc); loc != "" {
+			out = append(out, loc)
+		}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package scrawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestFetchSitemapSkipsFailingChildren(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex>
+			<sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+			<sitemap><loc>%[1]s/missing.xml</loc></sitemap>
+			<sitemap><loc>%[1]s/broken.xml</loc></sitemap>
+			<sitemap><loc>%[1]s/posts.xml</loc></sitemap>
+		</sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset><url><loc>%s/about</loc></url></urlset>`, server.URL)
+	})
+	mux.HandleFunc("/broken.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urlset><url><loc>`)
+	})
+	mux.HandleFunc("/posts.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset><url><loc>%s/posts/1</loc></url></urlset>`, server.URL)
+	})
+
+	site, _ := url.Parse(server.URL)
+	pages, err := FetchSitemap(context.Background(), server.Client(), site, UserAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{server.URL + "/about", server.URL + "/posts/1"}
+	if len(pages) != len(want) || pages[0] != want[0] || pages[1] != want[1] {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+}
@@ -0,0 +1,89 @@
+package simage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/sblob"
+)
+
+const aiTimeout = 5 * time.Minute
+
+type AnalyzeOptions struct {
+	// AIRecommendations asks OpenAI for advice on every image; it needs OPENAI_KEY.
+	AIRecommendations bool
+	// Optimize re-encodes every image to estimate savings; nil skips it.
+	Optimize *OptimizeOptions
+	// Artifacts, when set, keeps each optimized image under ArtifactPrefix.
+	Artifacts      sblob.Store
+	ArtifactPrefix string
+}
+
+// Analysis is everything learned about a page, independent of where it is stored.
+type Analysis struct {
+	URL        string
+	Metadata   WebsiteMetadata
+	Images     []Image
+	Links      []string
+	Findings   []Finding
+	Duplicates []DuplicateGroup
+	Conditions ScanConditions
+}
+
+// Analyze scrapes the page and runs hashing, duplicate detection and the
+// finding rules over its images.
+func (s *ImageScraper) Analyze(ctx context.Context, targetURL string, opts AnalyzeOptions) (*Analysis, error) {
+	page, err := s.ScrapePage(ctx, targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape: %w", err)
+	}
+
+	images := processImages(ctx, page.Images, func(img *Image, data []byte) {
+		hashImage(img, data)
+		if opts.Optimize != nil {
+			optimizeImage(ctx, img, data, opts)
+		}
+	})
+
+	if opts.AIRecommendations {
+		aiCtx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+		defer cancel()
+
+		images, err = CreateAIRecommendations(aiCtx, images)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AI recommendations: %w", err)
+		}
+	}
+
+	return &Analysis{
+		URL:        targetURL,
+		Metadata:   page.Metadata,
+		Images:     images,
+		Links:      page.Links,
+		Findings:   AnalyzeFindings(images),
+		Duplicates: GroupDuplicates(images),
+		Conditions: s.Conditions(),
+	}, nil
+}
+
+// optimizeImage records the best re-encoding of the image and stores it as an
+// artifact when it saves bytes.
+func optimizeImage(ctx context.Context, img *Image, data []byte, opts AnalyzeOptions) {
+	result, err := Optimize(data, *opts.Optimize)
+	if err != nil {
+		log.Printf("Warning: failed to optimize %s: %v", img.Src, err)
+		return
+	}
+
+	if result.Savings > 0 && opts.Artifacts != nil && img.ContentHash != "" {
+		key := opts.ArtifactPrefix + img.ContentHash[:16] + "." + FormatExtension(result.Format)
+		if err := opts.Artifacts.Put(ctx, key, result.Data); err != nil {
+			log.Printf("Warning: failed to store optimized %s: %v", img.Src, err)
+		} else {
+			result.ArtifactKey = key
+		}
+	}
+	img.Optimization = result
+}
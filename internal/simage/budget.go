@@ -0,0 +1,122 @@
+package simage
+
+import "fmt"
+
+const (
+	MetricTotalBytes        = "total_bytes"
+	MetricImageBytes        = "image_bytes"
+	MetricImageCount        = "image_count"
+	MetricModernFormatShare = "modern_format_share"
+	MetricLCPLoadTime       = "lcp_load_time"
+)
+
+var modernFormats = map[string]bool{
+	"image/webp": true,
+	"image/avif": true,
+	"image/jxl":  true,
+}
+
+// Validate rejects limits that can never be met.
+func (b Budget) Validate() error {
+	if b.MaxTotalBytes < 0 || b.MaxImageBytes < 0 || b.MaxImageCount < 0 || b.MaxLCPLoadTime < 0 {
+		return fmt.Errorf("budget limits must not be negative")
+	}
+	if b.MinModernFormatShare < 0 || b.MinModernFormatShare > 1 {
+		return fmt.Errorf("min_modern_format_share must be between 0 and 1")
+	}
+	if b == (Budget{}) {
+		return fmt.Errorf("budget sets no limits")
+	}
+	return nil
+}
+
+// EvaluateBudget checks every limit set on the budget against the images.
+func EvaluateBudget(name string, b Budget, images []Image) BudgetResult {
+	result := BudgetResult{Name: name, Passed: true, Checks: []BudgetCheck{}}
+	add := func(check BudgetCheck) {
+		result.Checks = append(result.Checks, check)
+		result.Passed = result.Passed && check.Passed
+	}
+
+	var totalBytes int64
+	var modern, known int
+	var lcp *Image
+	var oversized []string
+	largest := 0
+	for i, img := range images {
+		totalBytes += int64(img.Size)
+		largest = max(largest, img.Size)
+		if b.MaxImageBytes > 0 && img.Size > b.MaxImageBytes {
+			oversized = append(oversized, img.Src)
+		}
+		if img.Format != "" {
+			known++
+			if modernFormats[img.Format] {
+				modern++
+			}
+		}
+		if img.LCP {
+			lcp = &images[i]
+		}
+	}
+
+	if b.MaxTotalBytes > 0 {
+		add(BudgetCheck{
+			Metric: MetricTotalBytes,
+			Limit:  float64(b.MaxTotalBytes),
+			Actual: float64(totalBytes),
+			Passed: totalBytes <= b.MaxTotalBytes,
+		})
+	}
+	if b.MaxImageBytes > 0 {
+		add(BudgetCheck{
+			Metric: MetricImageBytes,
+			Limit:  float64(b.MaxImageBytes),
+			Actual: float64(largest),
+			Passed: len(oversized) == 0,
+			Images: oversized,
+		})
+	}
+	if b.MaxImageCount > 0 {
+		add(BudgetCheck{
+			Metric: MetricImageCount,
+			Limit:  float64(b.MaxImageCount),
+			Actual: float64(len(images)),
+			Passed: len(images) <= b.MaxImageCount,
+		})
+	}
+	if b.MinModernFormatShare > 0 {
+		share := 1.0
+		if known > 0 {
+			share = float64(modern) / float64(known)
+		}
+		add(BudgetCheck{
+			Metric: MetricModernFormatShare,
+			Limit:  b.MinModernFormatShare,
+			Actual: share,
+			Passed: share >= b.MinModernFormatShare,
+		})
+	}
+	if b.MaxLCPLoadTime > 0 {
+		// Without an LCP image there is nothing to hold to the limit.
+		check := BudgetCheck{Metric: MetricLCPLoadTime, Limit: b.MaxLCPLoadTime, Passed: true}
+		if lcp != nil {
+			check.Actual = lcp.Network.LoadTime
+			check.Passed = lcp.Network.LoadTime <= b.MaxLCPLoadTime
+			check.Images = []string{lcp.Src}
+		}
+		add(check)
+	}
+
+	return result
+}
+
+// BudgetsPassed reports whether every budget result passed.
+func BudgetsPassed(results []BudgetResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
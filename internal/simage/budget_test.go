@@ -0,0 +1,85 @@
+package simage
+
+import "testing"
+
+func TestEvaluateBudget(t *testing.T) {
+	lcp := Image{Src: "https://a.com/hero.webp", Format: "image/webp", Size: 300, LCP: true}
+	lcp.Network.LoadTime = 2.5
+	images := []Image{
+		lcp,
+		{Src: "https://a.com/logo.png", Format: "image/png", Size: 100},
+		{Src: "https://a.com/photo.jpg", Format: "image/jpeg", Size: 600},
+		{Src: "https://a.com/icon.avif", Format: "image/avif", Size: 50},
+	}
+	unknownFormats := []Image{{Src: "https://a.com/a", Size: 10}, {Src: "https://a.com/b", Size: 20}}
+	noLCP := images[1:]
+
+	for _, tc := range []struct {
+		name   string
+		budget Budget
+		images []Image
+		metric string
+		actual float64
+		passed bool
+		bad    []string
+	}{
+		{"total bytes within", Budget{MaxTotalBytes: 1050}, images, MetricTotalBytes, 1050, true, nil},
+		{"total bytes over", Budget{MaxTotalBytes: 1049}, images, MetricTotalBytes, 1050, false, nil},
+		{"image bytes within", Budget{MaxImageBytes: 600}, images, MetricImageBytes, 600, true, nil},
+		{"image bytes over", Budget{MaxImageBytes: 250}, images, MetricImageBytes, 600, false, []string{"https://a.com/hero.webp", "https://a.com/photo.jpg"}},
+		{"image count within", Budget{MaxImageCount: 4}, images, MetricImageCount, 4, true, nil},
+		{"image count over", Budget{MaxImageCount: 3}, images, MetricImageCount, 4, false, nil},
+		{"modern share met", Budget{MinModernFormatShare: 0.5}, images, MetricModernFormatShare, 0.5, true, nil},
+		{"modern share missed", Budget{MinModernFormatShare: 0.75}, images, MetricModernFormatShare, 0.5, false, nil},
+		// Images without a known format don't count against the share.
+		{"modern share without known formats", Budget{MinModernFormatShare: 1}, unknownFormats, MetricModernFormatShare, 1, true, nil},
+		{"LCP load time within", Budget{MaxLCPLoadTime: 2.5}, images, MetricLCPLoadTime, 2.5, true, []string{"https://a.com/hero.webp"}},
+		{"LCP load time over", Budget{MaxLCPLoadTime: 2}, images, MetricLCPLoadTime, 2.5, false, []string{"https://a.com/hero.webp"}},
+		{"LCP load time without LCP image", Budget{MaxLCPLoadTime: 2}, noLCP, MetricLCPLoadTime, 0, true, nil},
+	} {
+		result := EvaluateBudget("page", tc.budget, tc.images)
+		if result.Name != "page" || len(result.Checks) != 1 {
+			t.Errorf("%s: result = %+v, want one check", tc.name, result)
+			continue
+		}
+		check := result.Checks[0]
+		if check.Metric != tc.metric || check.Actual != tc.actual || check.Passed != tc.passed || result.Passed != tc.passed {
+			t.Errorf("%s: check = %+v (passed %v), want %s = %v passed %v", tc.name, check, result.Passed, tc.metric, tc.actual, tc.passed)
+		}
+		if !equalStrings(check.Images, tc.bad) {
+			t.Errorf("%s: images = %v, want %v", tc.name, check.Images, tc.bad)
+		}
+	}
+}
+
+func TestEvaluateBudgetCombinesChecks(t *testing.T) {
+	images := []Image{{Src: "https://a.com/a.png", Format: "image/png", Size: 500}}
+	result := EvaluateBudget("page", Budget{MaxTotalBytes: 1000, MinModernFormatShare: 0.5}, images)
+
+	if len(result.Checks) != 2 {
+		t.Fatalf("checks = %+v, want total bytes and modern share only", result.Checks)
+	}
+	if result.Passed || !result.Checks[0].Passed || result.Checks[1].Passed {
+		t.Errorf("result = %+v, want one failed check to fail the budget", result)
+	}
+	if !BudgetsPassed(nil) || BudgetsPassed([]BudgetResult{{Passed: true}, result}) {
+		t.Error("BudgetsPassed should fail when any budget fails")
+	}
+}
+
+func TestBudgetValidate(t *testing.T) {
+	for _, tc := range []struct {
+		budget  Budget
+		wantErr bool
+	}{
+		{Budget{MaxTotalBytes: 1}, false},
+		{Budget{MinModernFormatShare: 1}, false},
+		{Budget{}, true},
+		{Budget{MaxImageBytes: -1}, true},
+		{Budget{MinModernFormatShare: 1.5}, true},
+	} {
+		if err := tc.budget.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%+v: err = %v, want error %v", tc.budget, err, tc.wantErr)
+		}
+	}
+}
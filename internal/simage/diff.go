@@ -0,0 +1,166 @@
+package simage
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiffImage identifies an image that only exists on one side of a diff.
+type DiffImage struct {
+	Src    string `json:"src" bson:"src"`
+	Format string `json:"format" bson:"format"`
+	Size   int    `json:"size" bson:"size"`
+}
+
+// ImageChange is an image present in both scans. BaseSrc differs from Src when
+// the pair was matched by perceptual hash rather than URL.
+type ImageChange struct {
+	Src        string `json:"src" bson:"src"`
+	BaseSrc    string `json:"base_src" bson:"base_src"`
+	BaseFormat string `json:"base_format" bson:"base_format"`
+	HeadFormat string `json:"head_format" bson:"head_format"`
+	BaseSize   int    `json:"base_size" bson:"base_size"`
+	HeadSize   int    `json:"head_size" bson:"head_size"`
+	SizeDelta  int    `json:"size_delta" bson:"size_delta"`
+}
+
+type ScanDiff struct {
+	Added            []DiffImage   `json:"added" bson:"added"`
+	Removed          []DiffImage   `json:"removed" bson:"removed"`
+	Grown            []ImageChange `json:"grown" bson:"grown"`
+	Shrunk           []ImageChange `json:"shrunk" bson:"shrunk"`
+	FormatChanged    []ImageChange `json:"format_changed" bson:"format_changed"`
+	NewFindings      []Finding     `json:"new_findings" bson:"new_findings"`
+	ResolvedFindings []Finding     `json:"resolved_findings" bson:"resolved_findings"`
+	BaseTotalBytes   int64         `json:"base_total_bytes" bson:"base_total_bytes"`
+	HeadTotalBytes   int64         `json:"head_total_bytes" bson:"head_total_bytes"`
+	TotalBytesDelta  int64         `json:"total_bytes_delta" bson:"total_bytes_delta"`
+	BaseImageCount   int           `json:"base_image_count" bson:"base_image_count"`
+	HeadImageCount   int           `json:"head_image_count" bson:"head_image_count"`
+}
+
+// DiffScans compares the images and findings of two scans. Images are paired
+// by normalized URL first; the rest are paired by content or perceptual hash
+// so a renamed or re-encoded asset shows up as a change, not an add/remove.
+func DiffScans(base, head []Image, baseFindings, headFindings []Finding) ScanDiff {
+	diff := ScanDiff{
+		Added:            []DiffImage{},
+		Removed:          []DiffImage{},
+		Grown:            []ImageChange{},
+		Shrunk:           []ImageChange{},
+		FormatChanged:    []ImageChange{},
+		NewFindings:      []Finding{},
+		ResolvedFindings: []Finding{},
+		BaseImageCount:   len(base),
+		HeadImageCount:   len(head),
+	}
+
+	for _, img := range base {
+		diff.BaseTotalBytes += int64(img.Size)
+	}
+	for _, img := range head {
+		diff.HeadTotalBytes += int64(img.Size)
+	}
+	diff.TotalBytesDelta = diff.HeadTotalBytes - diff.BaseTotalBytes
+
+	baseByURL := make(map[string]int)
+	for i, img := range base {
+		if _, ok := baseByURL[cleanURL(img.Src)]; !ok {
+			baseByURL[cleanURL(img.Src)] = i
+		}
+	}
+
+	matchedBase := make(map[int]bool)
+	var unmatchedHead []Image
+	for _, img := range head {
+		i, ok := baseByURL[cleanURL(img.Src)]
+		if !ok || matchedBase[i] {
+			unmatchedHead = append(unmatchedHead, img)
+			continue
+		}
+		matchedBase[i] = true
+		diff.addChange(base[i], img)
+	}
+
+	for _, img := range unmatchedHead {
+		match := -1
+		for i := range base {
+			if !matchedBase[i] && sameImage(base[i], img) {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			diff.Added = append(diff.Added, DiffImage{Src: img.Src, Format: img.Format, Size: img.Size})
+			continue
+		}
+		matchedBase[match] = true
+		diff.addChange(base[match], img)
+	}
+
+	for i, img := range base {
+		if !matchedBase[i] {
+			diff.Removed = append(diff.Removed, DiffImage{Src: img.Src, Format: img.Format, Size: img.Size})
+		}
+	}
+
+	baseKeys := findingKeys(baseFindings)
+	headKeys := findingKeys(headFindings)
+	for _, f := range headFindings {
+		if !baseKeys[FindingKey(f)] {
+			diff.NewFindings = append(diff.NewFindings, f)
+		}
+	}
+	for _, f := range baseFindings {
+		if !headKeys[FindingKey(f)] {
+			diff.ResolvedFindings = append(diff.ResolvedFindings, f)
+		}
+	}
+
+	sort.SliceStable(diff.Grown, func(i, j int) bool { return diff.Grown[i].SizeDelta > diff.Grown[j].SizeDelta })
+	sort.SliceStable(diff.Shrunk, func(i, j int) bool { return diff.Shrunk[i].SizeDelta < diff.Shrunk[j].SizeDelta })
+
+	return diff
+}
+
+func (d *ScanDiff) addChange(base, head Image) {
+	change := ImageChange{
+		Src:        head.Src,
+		BaseSrc:    base.Src,
+		BaseFormat: base.Format,
+		HeadFormat: head.Format,
+		BaseSize:   base.Size,
+		HeadSize:   head.Size,
+		SizeDelta:  head.Size - base.Size,
+	}
+
+	switch {
+	case change.SizeDelta > 0:
+		d.Grown = append(d.Grown, change)
+	case change.SizeDelta < 0:
+		d.Shrunk = append(d.Shrunk, change)
+	}
+	if base.Format != head.Format {
+		d.FormatChanged = append(d.FormatChanged, change)
+	}
+}
+
+// FindingKey identifies a finding across scans by its rule and affected URLs,
+// ignoring their order and resizing parameters. Diffs, alerts and webhooks
+// all use it to tell new findings from known ones.
+func FindingKey(f Finding) string {
+	srcs := make([]string, len(f.Images))
+	for i, src := range f.Images {
+		srcs[i] = cleanURL(src)
+	}
+	sort.Strings(srcs)
+	return f.RuleID + "|" + strings.Join(srcs, "|")
+}
+
+func findingKeys(findings []Finding) map[string]bool {
+	keys := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		keys[FindingKey(f)] = true
+	}
+	return keys
+}
@@ -0,0 +1,201 @@
+package simage
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestDiffScans(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		base, head    []Image
+		added         []string
+		removed       []string
+		grown         []string
+		shrunk        []string
+		formatChanged []string
+		totalDelta    int64
+	}{
+		{
+			name:       "unchanged",
+			base:       []Image{{Src: "https://a.com/x.jpg", Format: "jpeg", Size: 100}},
+			head:       []Image{{Src: "https://a.com/x.jpg", Format: "jpeg", Size: 100}},
+			totalDelta: 0,
+		},
+		{
+			name:       "URL match ignores resizing parameters",
+			base:       []Image{{Src: "https://a.com/x.jpg?w=200&q=80", Format: "jpeg", Size: 100}},
+			head:       []Image{{Src: "https://a.com/x.jpg?w=400", Format: "jpeg", Size: 300}},
+			grown:      []string{"https://a.com/x.jpg?w=400"},
+			totalDelta: 200,
+		},
+		{
+			name: "grown and shrunk are ordered by delta",
+			base: []Image{
+				{Src: "https://a.com/1.jpg", Format: "jpeg", Size: 100},
+				{Src: "https://a.com/2.jpg", Format: "jpeg", Size: 100},
+				{Src: "https://a.com/3.jpg", Format: "jpeg", Size: 500},
+				{Src: "https://a.com/4.jpg", Format: "jpeg", Size: 500},
+			},
+			head: []Image{
+				{Src: "https://a.com/1.jpg", Format: "jpeg", Size: 150},
+				{Src: "https://a.com/2.jpg", Format: "jpeg", Size: 400},
+				{Src: "https://a.com/3.jpg", Format: "jpeg", Size: 450},
+				{Src: "https://a.com/4.jpg", Format: "jpeg", Size: 100},
+			},
+			grown:      []string{"https://a.com/2.jpg", "https://a.com/1.jpg"},
+			shrunk:     []string{"https://a.com/4.jpg", "https://a.com/3.jpg"},
+			totalDelta: -100,
+		},
+		{
+			name:          "format change at the same URL",
+			base:          []Image{{Src: "https://a.com/x", Format: "jpeg", Size: 100}},
+			head:          []Image{{Src: "https://a.com/x", Format: "webp", Size: 60}},
+			shrunk:        []string{"https://a.com/x"},
+			formatChanged: []string{"https://a.com/x"},
+			totalDelta:    -40,
+		},
+		{
+			name:          "renamed asset matched by perceptual hash",
+			base:          []Image{{Src: "https://a.com/hero.jpg", Format: "jpeg", Size: 100, PerceptualHash: "f0f0f0f0f0f0f0f0"}},
+			head:          []Image{{Src: "https://a.com/hero.webp", Format: "webp", Size: 50, PerceptualHash: "f0f0f0f0f0f0f0f1"}},
+			shrunk:        []string{"https://a.com/hero.webp"},
+			formatChanged: []string{"https://a.com/hero.webp"},
+			totalDelta:    -50,
+		},
+		{
+			name:       "renamed asset matched by content hash",
+			base:       []Image{{Src: "https://a.com/old.png", Format: "png", Size: 80, ContentHash: "abc"}},
+			head:       []Image{{Src: "https://a.com/new.png", Format: "png", Size: 80, ContentHash: "abc"}},
+			totalDelta: 0,
+		},
+		{
+			name: "URL matches win over hash matches",
+			base: []Image{
+				{Src: "https://a.com/a.jpg", Format: "jpeg", Size: 100, ContentHash: "same"},
+				{Src: "https://a.com/b.jpg", Format: "jpeg", Size: 100, ContentHash: "same"},
+			},
+			head: []Image{
+				{Src: "https://a.com/c.jpg", Format: "jpeg", Size: 100, ContentHash: "same"},
+				{Src: "https://a.com/b.jpg", Format: "jpeg", Size: 200, ContentHash: "other"},
+			},
+			grown:      []string{"https://a.com/b.jpg"},
+			totalDelta: 100,
+		},
+		{
+			name:    "flat images don't match by hash",
+			base:    []Image{{Src: "https://a.com/blank1.png", Format: "png", Size: 10, PerceptualHash: flatImageHash}},
+			head:    []Image{{Src: "https://a.com/blank2.png", Format: "png", Size: 10, PerceptualHash: flatImageHash}},
+			added:   []string{"https://a.com/blank2.png"},
+			removed: []string{"https://a.com/blank1.png"},
+		},
+		{
+			name:       "added and removed",
+			base:       []Image{{Src: "https://a.com/gone.jpg", Format: "jpeg", Size: 100, PerceptualHash: "ffffffff00000000"}},
+			head:       []Image{{Src: "https://a.com/new.jpg", Format: "jpeg", Size: 300, PerceptualHash: "00000000ffffffff"}},
+			added:      []string{"https://a.com/new.jpg"},
+			removed:    []string{"https://a.com/gone.jpg"},
+			totalDelta: 200,
+		},
+	} {
+		diff := DiffScans(tc.base, tc.head, nil, nil)
+
+		check := func(bucket string, got, want []string) {
+			if !equalStrings(got, want) {
+				t.Errorf("%s: %s = %v, want %v", tc.name, bucket, got, want)
+			}
+		}
+		check("added", diffImageSrcs(diff.Added), tc.added)
+		check("removed", diffImageSrcs(diff.Removed), tc.removed)
+		check("grown", changeSrcs(diff.Grown), tc.grown)
+		check("shrunk", changeSrcs(diff.Shrunk), tc.shrunk)
+		check("format changed", changeSrcs(diff.FormatChanged), tc.formatChanged)
+
+		if diff.TotalBytesDelta != tc.totalDelta {
+			t.Errorf("%s: total bytes delta = %d, want %d", tc.name, diff.TotalBytesDelta, tc.totalDelta)
+		}
+		if diff.BaseImageCount != len(tc.base) || diff.HeadImageCount != len(tc.head) {
+			t.Errorf("%s: image counts = %d/%d, want %d/%d", tc.name, diff.BaseImageCount, diff.HeadImageCount, len(tc.base), len(tc.head))
+		}
+	}
+}
+
+func TestDiffScansHashMatchKeepsBaseSrc(t *testing.T) {
+	base := []Image{{Src: "https://a.com/hero.jpg", Format: "jpeg", Size: 100, ContentHash: "h"}}
+	head := []Image{{Src: "https://a.com/hero-v2.jpg", Format: "jpeg", Size: 120, ContentHash: "h"}}
+
+	diff := DiffScans(base, head, nil, nil)
+	if len(diff.Grown) != 1 {
+		t.Fatalf("grown = %+v, want one change", diff.Grown)
+	}
+	change := diff.Grown[0]
+	if change.BaseSrc != "https://a.com/hero.jpg" || change.Src != "https://a.com/hero-v2.jpg" ||
+		change.BaseSize != 100 || change.HeadSize != 120 || change.SizeDelta != 20 {
+		t.Errorf("change = %+v", change)
+	}
+}
+
+func TestDiffScansFindings(t *testing.T) {
+	duplicate := Finding{RuleID: RuleDuplicateImage, Images: []string{"https://a.com/a.jpg", "https://a.com/b.jpg"}}
+	reordered := Finding{RuleID: RuleDuplicateImage, Images: []string{"https://a.com/b.jpg?w=100", "https://a.com/a.jpg"}}
+	oversized := Finding{RuleID: RuleOversizedImage, Images: []string{"https://a.com/big.jpg"}}
+	heavy := Finding{RuleID: RuleHeavyLCPImage, Images: []string{"https://a.com/big.jpg"}}
+
+	for _, tc := range []struct {
+		name           string
+		base, head     []Finding
+		added, removed []string
+	}{
+		{"none", nil, nil, nil, nil},
+		{"same finding", []Finding{oversized}, []Finding{oversized}, nil, nil},
+		{"reordered and resized images are the same finding", []Finding{duplicate}, []Finding{reordered}, nil, nil},
+		{"new finding", []Finding{oversized}, []Finding{oversized, heavy}, []string{RuleHeavyLCPImage}, nil},
+		{"resolved finding", []Finding{duplicate, oversized}, []Finding{oversized}, nil, []string{RuleDuplicateImage}},
+		{"rule changes on the same image", []Finding{oversized}, []Finding{heavy}, []string{RuleHeavyLCPImage}, []string{RuleOversizedImage}},
+	} {
+		diff := DiffScans(nil, nil, tc.base, tc.head)
+		if got := findingRules(diff.NewFindings); !equalStrings(got, tc.added) {
+			t.Errorf("%s: new findings = %v, want %v", tc.name, got, tc.added)
+		}
+		if got := findingRules(diff.ResolvedFindings); !equalStrings(got, tc.removed) {
+			t.Errorf("%s: resolved findings = %v, want %v", tc.name, got, tc.removed)
+		}
+	}
+}
+
+func diffImageSrcs(images []DiffImage) []string {
+	var srcs []string
+	for _, img := range images {
+		srcs = append(srcs, img.Src)
+	}
+	return srcs
+}
+
+func changeSrcs(changes []ImageChange) []string {
+	var srcs []string
+	for _, c := range changes {
+		srcs = append(srcs, c.Src)
+	}
+	return srcs
+}
+
+func findingRules(findings []Finding) []string {
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.RuleID)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
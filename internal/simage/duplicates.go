@@ -0,0 +1,110 @@
+package simage
+
+import "sort"
+
+const (
+	DuplicateExact = "exact"
+	DuplicateNear  = "near"
+
+	// nearDuplicateDistance is the largest dHash bit difference still treated
+	// as the same picture.
+	nearDuplicateDistance = 5
+	// flatImageHash is what dHash yields for flat or steadily brightening
+	// images, which says nothing about their content.
+	flatImageHash = "0000000000000000"
+)
+
+// GroupDuplicates clusters images that carry the same picture under different
+// URLs, either byte for byte or by perceptual hash. Groups are ordered by the
+// bytes that consolidating them would save.
+func GroupDuplicates(images []Image) []DuplicateGroup {
+	var unique []Image
+	seen := make(map[string]bool)
+	for _, img := range images {
+		if img.Src == "" || seen[img.Src] || (img.ContentHash == "" && img.PerceptualHash == "") {
+			continue
+		}
+		seen[img.Src] = true
+		unique = append(unique, img)
+	}
+
+	parent := make([]int, len(unique))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range unique {
+		for j := i + 1; j < len(unique); j++ {
+			if sameImage(unique[i], unique[j]) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]Image)
+	var roots []int
+	for i, img := range unique {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], img)
+	}
+
+	var groups []DuplicateGroup
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			groups = append(groups, newDuplicateGroup(members[root]))
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].WastedBytes > groups[j].WastedBytes
+	})
+	return groups
+}
+
+func sameImage(a, b Image) bool {
+	if a.ContentHash != "" && a.ContentHash == b.ContentHash {
+		return true
+	}
+	if a.PerceptualHash == "" || b.PerceptualHash == "" ||
+		a.PerceptualHash == flatImageHash || b.PerceptualHash == flatImageHash {
+		return false
+	}
+	distance, err := HashDistance(a.PerceptualHash, b.PerceptualHash)
+	return err == nil && distance <= nearDuplicateDistance
+}
+
+func newDuplicateGroup(images []Image) DuplicateGroup {
+	group := DuplicateGroup{Kind: DuplicateExact}
+
+	total, smallest := 0, images[0].Size
+	for _, img := range images {
+		if img.ContentHash == "" || img.ContentHash != images[0].ContentHash {
+			group.Kind = DuplicateNear
+		}
+		if group.PerceptualHash == "" {
+			group.PerceptualHash = img.PerceptualHash
+		}
+		group.Images = append(group.Images, DuplicateImage{
+			Src:    img.Src,
+			Format: img.Format,
+			Width:  img.Width,
+			Height: img.Height,
+			Size:   img.Size,
+		})
+		total += img.Size
+		smallest = min(smallest, img.Size)
+	}
+
+	group.WastedBytes = total - smallest
+	return group
+}
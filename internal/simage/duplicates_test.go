@@ -0,0 +1,142 @@
+package simage
+
+import "testing"
+
+type wantGroup struct {
+	kind   string
+	srcs   []string
+	wasted int
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		images []Image
+		// want lists the groups, largest waste first.
+		want []wantGroup
+	}{
+		{
+			name: "no duplicates",
+			images: []Image{
+				{Src: "a.png", Size: 100, ContentHash: "1", PerceptualHash: "ffffffff00000000"},
+				{Src: "b.png", Size: 100, ContentHash: "2", PerceptualHash: "00000000ffffffff"},
+			},
+		},
+		{
+			name: "exact duplicates share content hashes",
+			images: []Image{
+				{Src: "a.png", Size: 100, ContentHash: "1"},
+				{Src: "b.png", Size: 100, ContentHash: "1"},
+			},
+			want: []wantGroup{{DuplicateExact, []string{"a.png", "b.png"}, 100}},
+		},
+		{
+			name: "near duplicates are within the hash distance",
+			images: []Image{
+				{Src: "a.jpg", Size: 300, ContentHash: "1", PerceptualHash: "f0f0f0f0f0f0f0f0"},
+				{Src: "a.webp", Size: 100, ContentHash: "2", PerceptualHash: "f0f0f0f0f0f0f0f3"},
+				{Src: "b.jpg", Size: 100, ContentHash: "3", PerceptualHash: "f0f0f0f0f0f0ffff"},
+			},
+			want: []wantGroup{{DuplicateNear, []string{"a.jpg", "a.webp"}, 300}},
+		},
+		{
+			// c is too far from a but close to b, so union-find joins all three.
+			name: "groups are transitive",
+			images: []Image{
+				{Src: "a.jpg", Size: 100, PerceptualHash: "000000000000000f"},
+				{Src: "b.jpg", Size: 200, PerceptualHash: "00000000000000ff"},
+				{Src: "c.jpg", Size: 300, PerceptualHash: "00000000000003ff"},
+			},
+			want: []wantGroup{{DuplicateNear, []string{"a.jpg", "b.jpg", "c.jpg"}, 500}},
+		},
+		{
+			name: "an exact pair with a near member is near",
+			images: []Image{
+				{Src: "a.png", Size: 100, ContentHash: "1", PerceptualHash: "f0f0f0f0f0f0f0f0"},
+				{Src: "b.png", Size: 100, ContentHash: "1", PerceptualHash: "f0f0f0f0f0f0f0f0"},
+				{Src: "c.webp", Size: 50, ContentHash: "2", PerceptualHash: "f0f0f0f0f0f0f0f1"},
+			},
+			want: []wantGroup{{DuplicateNear, []string{"a.png", "b.png", "c.webp"}, 200}},
+		},
+		{
+			name: "flat images only match by content",
+			images: []Image{
+				{Src: "blank1.png", Size: 10, ContentHash: "1", PerceptualHash: flatImageHash},
+				{Src: "blank2.png", Size: 10, ContentHash: "2", PerceptualHash: flatImageHash},
+			},
+		},
+		{
+			name: "repeated URLs and unhashed images are skipped",
+			images: []Image{
+				{Src: "a.png", Size: 100, ContentHash: "1"},
+				{Src: "a.png", Size: 100, ContentHash: "1"},
+				{Src: "b.png", Size: 100},
+				{Src: "", Size: 100, ContentHash: "1"},
+			},
+		},
+		{
+			name: "groups are ordered by wasted bytes",
+			images: []Image{
+				{Src: "small1.png", Size: 10, ContentHash: "s"},
+				{Src: "small2.png", Size: 10, ContentHash: "s"},
+				{Src: "big1.png", Size: 1000, ContentHash: "b"},
+				{Src: "big2.png", Size: 1000, ContentHash: "b"},
+			},
+			want: []wantGroup{
+				{DuplicateExact, []string{"big1.png", "big2.png"}, 1000},
+				{DuplicateExact, []string{"small1.png", "small2.png"}, 10},
+			},
+		},
+	} {
+		groups := GroupDuplicates(tc.images)
+		if len(groups) != len(tc.want) {
+			t.Errorf("%s: groups = %+v, want %d", tc.name, groups, len(tc.want))
+			continue
+		}
+		for i, want := range tc.want {
+			group := groups[i]
+			var srcs []string
+			for _, img := range group.Images {
+				srcs = append(srcs, img.Src)
+			}
+			if group.Kind != want.kind || !equalStrings(srcs, want.srcs) || group.WastedBytes != want.wasted {
+				t.Errorf("%s: group %d = %s %v wasting %d, want %s %v wasting %d",
+					tc.name, i, group.Kind, srcs, group.WastedBytes, want.kind, want.srcs, want.wasted)
+			}
+		}
+	}
+}
+
+func TestGroupDuplicatesHashedFixtures(t *testing.T) {
+	hash := func(data []byte) Image {
+		phash, err := PerceptualHash(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Image{ContentHash: ContentHash(data), PerceptualHash: phash, Size: len(data)}
+	}
+	png := encodePNG(t, checkerboard(72, 9))
+
+	original := hash(png)
+	original.Src = "hero.png"
+	copied := hash(png)
+	copied.Src = "copy/hero.png"
+	resized := hash(encodeJPEG(t, checkerboard(216, 9)))
+	resized.Src = "hero-large.jpg"
+	other := hash(encodePNG(t, checkerboard(72, 3)))
+	other.Src = "other.png"
+	blank := hash(encodePNG(t, flat(72)))
+	blank.Src = "blank.png"
+	blankJPEG := hash(encodeJPEG(t, flat(144)))
+	blankJPEG.Src = "blank.jpg"
+
+	exact := GroupDuplicates([]Image{original, copied, other, blank, blankJPEG})
+	if len(exact) != 1 || exact[0].Kind != DuplicateExact || len(exact[0].Images) != 2 {
+		t.Errorf("exact groups = %+v, want hero.png with its copy", exact)
+	}
+
+	near := GroupDuplicates([]Image{original, resized, other, blank, blankJPEG})
+	if len(near) != 1 || near[0].Kind != DuplicateNear || len(near[0].Images) != 2 {
+		t.Errorf("near groups = %+v, want hero.png with its resized JPEG", near)
+	}
+}
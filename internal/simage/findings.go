@@ -0,0 +1,144 @@
+package simage
+
+import "fmt"
+
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+
+	RuleDuplicateImage     = "duplicate-image"
+	RuleNearDuplicateImage = "near-duplicate-image"
+	RuleCompressibleImage  = "compressible-image"
+	RuleOversizedImage     = "oversized-image"
+	RuleHeavyLCPImage      = "heavy-lcp-image"
+
+	// An image is flagged as compressible when re-encoding saves at least
+	// this many bytes and this share of its size.
+	compressibleMinBytes = 10 * 1024
+	compressibleMinShare = 0.2
+
+	// Images over oversizedMinBytes are critical wherever they are; the LCP
+	// image is critical from heavyLCPMinBytes, since the page's largest paint
+	// waits for it.
+	oversizedMinBytes = 1024 * 1024
+	heavyLCPMinBytes  = 200 * 1024
+)
+
+// SeverityRank orders severities from info (1) to critical (3); unknown
+// severities rank 0.
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// RuleInfo describes a rule for report formats that list rules up front.
+type RuleInfo struct {
+	ID          string
+	Description string
+	Severity    string
+}
+
+var ruleCatalog = []RuleInfo{
+	{RuleDuplicateImage, "The same image bytes are served from several URLs", SeverityWarning},
+	{RuleNearDuplicateImage, "The same picture is served in several sizes or formats", SeverityInfo},
+	{RuleCompressibleImage, "Re-encoding the image saves a significant share of its bytes", SeverityWarning},
+	{RuleOversizedImage, "The image is over 1 MB", SeverityCritical},
+	{RuleHeavyLCPImage, "The largest contentful paint waits on an image over 200 KB", SeverityCritical},
+}
+
+// Rules lists every rule AnalyzeFindings can report.
+func Rules() []RuleInfo {
+	return ruleCatalog
+}
+
+// rules are run in order by AnalyzeFindings; each inspects the full image set.
+var rules = []func(images []Image) []Finding{
+	duplicateFindings,
+	compressibleFindings,
+	sizeFindings,
+}
+
+// AnalyzeFindings runs every analysis rule over the images of a page or site.
+func AnalyzeFindings(images []Image) []Finding {
+	findings := []Finding{}
+	for _, rule := range rules {
+		findings = append(findings, rule(images)...)
+	}
+	return findings
+}
+
+func duplicateFindings(images []Image) []Finding {
+	var findings []Finding
+	for _, group := range GroupDuplicates(images) {
+		srcs := make([]string, len(group.Images))
+		for i, img := range group.Images {
+			srcs[i] = img.Src
+		}
+
+		finding := Finding{
+			RuleID:   RuleDuplicateImage,
+			Severity: SeverityWarning,
+			Images:   srcs,
+			Message: fmt.Sprintf("These %d URLs serve identical bytes; consolidate to one optimized asset to save %d bytes",
+				len(srcs), group.WastedBytes),
+		}
+		if group.Kind == DuplicateNear {
+			finding.RuleID = RuleNearDuplicateImage
+			finding.Severity = SeverityInfo
+			finding.Message = fmt.Sprintf("These %d URLs are the same picture; consolidate to one optimized asset to save up to %d bytes",
+				len(srcs), group.WastedBytes)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func compressibleFindings(images []Image) []Finding {
+	var findings []Finding
+	for _, img := range images {
+		opt := img.Optimization
+		if opt == nil || opt.OriginalSize == 0 || opt.Savings < compressibleMinBytes ||
+			float64(opt.Savings)/float64(opt.OriginalSize) < compressibleMinShare {
+			continue
+		}
+		findings = append(findings, Finding{
+			RuleID:   RuleCompressibleImage,
+			Severity: SeverityWarning,
+			Images:   []string{img.Src},
+			Message: fmt.Sprintf("Re-encoding as %s at quality %d saves %d bytes (%.0f%%)",
+				FormatExtension(opt.Format), opt.Quality, opt.Savings, 100*float64(opt.Savings)/float64(opt.OriginalSize)),
+		})
+	}
+	return findings
+}
+
+func sizeFindings(images []Image) []Finding {
+	var findings []Finding
+	for _, img := range images {
+		if img.Size >= oversizedMinBytes {
+			findings = append(findings, Finding{
+				RuleID:   RuleOversizedImage,
+				Severity: SeverityCritical,
+				Images:   []string{img.Src},
+				Message:  fmt.Sprintf("The image is %d bytes; resize or compress it below %d", img.Size, oversizedMinBytes),
+			})
+		}
+		if img.LCP && img.Size >= heavyLCPMinBytes {
+			findings = append(findings, Finding{
+				RuleID:   RuleHeavyLCPImage,
+				Severity: SeverityCritical,
+				Images:   []string{img.Src},
+				Message:  fmt.Sprintf("The largest contentful paint waits on this %d byte image; keep it below %d", img.Size, heavyLCPMinBytes),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,28 @@
+package simage
+
+import "testing"
+
+func TestSizeFindings(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		image Image
+		want  []string
+	}{
+		{"small", Image{Src: "a.jpg", Size: 50 * 1024}, nil},
+		{"oversized", Image{Src: "a.jpg", Size: oversizedMinBytes}, []string{RuleOversizedImage}},
+		{"light LCP", Image{Src: "a.jpg", Size: heavyLCPMinBytes - 1, LCP: true}, nil},
+		{"heavy LCP", Image{Src: "a.jpg", Size: heavyLCPMinBytes, LCP: true}, []string{RuleHeavyLCPImage}},
+		{"oversized LCP", Image{Src: "a.jpg", Size: 2 * oversizedMinBytes, LCP: true}, []string{RuleOversizedImage, RuleHeavyLCPImage}},
+	} {
+		findings := AnalyzeFindings([]Image{tc.image})
+		if len(findings) != len(tc.want) {
+			t.Errorf("%s: findings = %+v, want %v", tc.name, findings, tc.want)
+			continue
+		}
+		for i, f := range findings {
+			if f.RuleID != tc.want[i] || f.Severity != SeverityCritical || f.Images[0] != "a.jpg" {
+				t.Errorf("%s: finding %d = %+v, want a critical %s", tc.name, i, f, tc.want[i])
+			}
+		}
+	}
+}
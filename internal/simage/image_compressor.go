@@ -1,13 +1,70 @@
 package simage
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"image"
 	"io"
+	"log"
+	"math"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/h2non/bimg"
 )
 
+// DefaultOptimizeOptions is what scan analysis uses: WebP at the lowest
+// quality that stays visually close to the source.
+var DefaultOptimizeOptions = OptimizeOptions{
+	Format:     "image/webp",
+	TargetPSNR: defaultTargetPSNR,
+}
+
+const (
+	defaultMinQuality = 30
+	defaultMaxQuality = 90
+	defaultTargetPSNR = 40
+	// maxPSNR stands in for the infinite PSNR of identical images, which JSON
+	// can't represent.
+	maxPSNR = 100
+)
+
+var outputTypes = map[string]bimg.ImageType{
+	"image/jpeg": bimg.JPEG,
+	"image/png":  bimg.PNG,
+	"image/webp": bimg.WEBP,
+	"image/avif": bimg.AVIF,
+}
+
+// OptimizeOptions controls Optimize. Format is a MIME type such as
+// "image/webp"; empty keeps the source format. The quality search picks the
+// lowest quality in [MinQuality, MaxQuality] whose output still reaches
+// TargetPSNR decibels against the source.
+type OptimizeOptions struct {
+	Format     string
+	MaxWidth   int
+	MinQuality int
+	MaxQuality int
+	TargetPSNR float64
+}
+
+// OptimizeResult is the best encoding found. When re-encoding doesn't beat
+// the source, Data is the source itself and Savings is zero.
+type OptimizeResult struct {
+	Data         []byte  `json:"-" bson:"-"`
+	Format       string  `json:"format" bson:"format"`
+	Quality      int     `json:"quality" bson:"quality"`
+	Width        int     `json:"width" bson:"width"`
+	Height       int     `json:"height" bson:"height"`
+	OriginalSize int     `json:"original_size" bson:"original_size"`
+	Size         int     `json:"size" bson:"size"`
+	Savings      int     `json:"savings" bson:"savings"`
+	PSNR         float64 `json:"psnr" bson:"psnr"`
+	ArtifactKey  string  `json:"artifact_key,omitempty" bson:"artifact_key,omitempty"`
+}
+
 func CompressImages(ip ImageParams, i Image) error {
 
 	options := bimg.Options{
@@ -17,12 +74,12 @@ func CompressImages(ip ImageParams, i Image) error {
 		Type:    bimg.WEBP,
 	}
 
-	imageData, err := fetchImageData(i.Src)
+	imageData, err := fetchImageData(context.Background(), i.Src)
 	if err != nil {
 		return fmt.Errorf("failed to fetch image: %w", err)
 	}
 
-	newImage, err := bimg.NewImage(imageData).Process(options)
+	newImage, err := encode(imageData, options)
 	if err != nil {
 		return fmt.Errorf("failed to process image: %w", err)
 	}
@@ -34,8 +91,163 @@ func CompressImages(ip ImageParams, i Image) error {
 	return nil
 }
 
-func fetchImageData(src string) ([]byte, error) {
-	resp, err := http.Get(src)
+// Optimize re-encodes image data, optionally converting format and capping
+// width, with a binary search over quality.
+func Optimize(data []byte, opts OptimizeOptions) (*OptimizeResult, error) {
+	source := bimg.NewImage(data)
+	sourceFormat := "image/" + source.Type()
+
+	format := opts.Format
+	if format == "" {
+		format = sourceFormat
+	}
+	outputType, ok := outputTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+
+	size, err := source.Size()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image size: %w", err)
+	}
+	width := size.Width
+	if opts.MaxWidth > 0 && width > opts.MaxWidth {
+		width = opts.MaxWidth
+	}
+
+	minQuality, maxQuality, targetPSNR := opts.MinQuality, opts.MaxQuality, opts.TargetPSNR
+	if minQuality <= 0 {
+		minQuality = defaultMinQuality
+	}
+	if maxQuality <= 0 {
+		maxQuality = defaultMaxQuality
+	}
+	if targetPSNR <= 0 {
+		targetPSNR = defaultTargetPSNR
+	}
+
+	// The reference is the source at the output size, losslessly encoded, so
+	// resizing itself doesn't count as quality loss.
+	reference, err := decodeForComparison(data, width)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *OptimizeResult
+	lo, hi := minQuality, maxQuality
+	if outputType == bimg.PNG {
+		// PNG is lossless; quality doesn't apply, so there is nothing to search.
+		lo = hi
+	}
+	for lo <= hi {
+		quality := (lo + hi) / 2
+		encoded, err := encode(data, bimg.Options{
+			Width:         width,
+			Quality:       quality,
+			Type:          outputType,
+			StripMetadata: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+
+		candidate, err := decodeForComparison(encoded, 0)
+		if err != nil {
+			return nil, err
+		}
+		psnr := PSNR(reference, candidate)
+
+		if psnr >= targetPSNR || outputType == bimg.PNG {
+			best = &OptimizeResult{Data: encoded, Format: format, Quality: quality, PSNR: psnr}
+			hi = quality - 1
+		} else {
+			lo = quality + 1
+		}
+	}
+
+	if best == nil || len(best.Data) >= len(data) {
+		best = &OptimizeResult{Data: data, Format: sourceFormat, Width: size.Width, Height: size.Height, PSNR: maxPSNR}
+	} else {
+		outSize, err := bimg.NewImage(best.Data).Size()
+		if err == nil {
+			best.Width, best.Height = outSize.Width, outSize.Height
+		}
+	}
+
+	best.OriginalSize = len(data)
+	best.Size = len(best.Data)
+	best.Savings = best.OriginalSize - best.Size
+	return best, nil
+}
+
+// encode runs a single bimg pass. Scan analysis and local optimization both
+// encode through here so they produce identical output.
+func encode(data []byte, options bimg.Options) ([]byte, error) {
+	return bimg.NewImage(data).Process(options)
+}
+
+// decodeForComparison converts image data to PNG with libvips, which reads
+// formats Go can't (such as AVIF), and decodes it for pixel comparison.
+func decodeForComparison(data []byte, width int) (image.Image, error) {
+	png, err := encode(data, bimg.Options{Width: width, Type: bimg.PNG})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert image for comparison: %w", err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(png))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image for comparison: %w", err)
+	}
+	return img, nil
+}
+
+// PSNR returns the peak signal-to-noise ratio of b against a in decibels over
+// the RGB channels, capped at maxPSNR for identical images. Images are compared
+// from their top-left corner over the area they share.
+func PSNR(a, b image.Image) float64 {
+	ab, bb := a.Bounds(), b.Bounds()
+	w, h := min(ab.Dx(), bb.Dx()), min(ab.Dy(), bb.Dy())
+	if w == 0 || h == 0 {
+		return 0
+	}
+
+	var sum float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r1, g1, b1, _ := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r2, g2, b2, _ := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			for _, d := range []float64{
+				float64(r1>>8) - float64(r2>>8),
+				float64(g1>>8) - float64(g2>>8),
+				float64(b1>>8) - float64(b2>>8),
+			} {
+				sum += d * d
+			}
+		}
+	}
+
+	mse := sum / float64(w*h*3)
+	if mse == 0 {
+		return maxPSNR
+	}
+	return min(10*math.Log10(255*255/mse), maxPSNR)
+}
+
+// FormatExtension returns the file extension for an image MIME type.
+func FormatExtension(format string) string {
+	ext := strings.TrimPrefix(format, "image/")
+	if ext == "jpeg" {
+		return "jpg"
+	}
+	return ext
+}
+
+func fetchImageData(ctx context.Context, src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build image request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch image from URL: %w", err)
 	}
@@ -62,3 +274,26 @@ func saveImages(imageData []byte, alt string) error {
 	fmt.Printf("Image compressed and saved to %s\n", outputPath)
 	return nil
 }
+
+// Thumbnails downloads the images and returns a JPEG of each, scaled down to
+// at most width pixels wide, keyed by image URL. Images that fail are omitted.
+func Thumbnails(ctx context.Context, images []Image, width int) map[string][]byte {
+	var mu sync.Mutex
+	thumbs := make(map[string][]byte)
+	processImages(ctx, images, func(img *Image, data []byte) {
+		thumb, err := encode(data, bimg.Options{
+			Width:         width,
+			Type:          bimg.JPEG,
+			Quality:       70,
+			StripMetadata: true,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to create thumbnail for %s: %v", img.Src, err)
+			return
+		}
+		mu.Lock()
+		thumbs[img.Src] = thumb
+		mu.Unlock()
+	})
+	return thumbs
+}
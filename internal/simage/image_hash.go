@@ -0,0 +1,145 @@
+package simage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"math/bits"
+	"strconv"
+	"sync"
+
+	_ "golang.org/x/image/webp"
+)
+
+const fetchWorkers = 8
+
+// ComputeImageHashes downloads every image and records its SHA-256 content hash
+// and 64-bit difference hash. Images that can't be fetched or decoded keep
+// whatever hashes could be computed.
+func ComputeImageHashes(ctx context.Context, images []Image) []Image {
+	return processImages(ctx, images, hashImage)
+}
+
+func hashImage(img *Image, data []byte) {
+	img.ContentHash = ContentHash(data)
+	if phash, err := PerceptualHash(data); err == nil {
+		img.PerceptualHash = phash
+	}
+}
+
+// processImages downloads each image once and hands its bytes to fn, which
+// may update the image in place. It returns updated copies of the images.
+func processImages(ctx context.Context, images []Image, fn func(img *Image, data []byte)) []Image {
+	processed := make([]Image, len(images))
+	copy(processed, images)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < fetchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				data, err := fetchImageData(ctx, processed[i].Src)
+				if err != nil {
+					log.Printf("Warning: skipping %s: %v", processed[i].Src, err)
+					continue
+				}
+				fn(&processed[i], data)
+			}
+		}()
+	}
+
+	for i := range processed {
+		if processed[i].Src == "" {
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return processed
+}
+
+// ContentHash returns the hex SHA-256 of the raw image bytes.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PerceptualHash decodes the image and returns its difference hash (dHash) as
+// 16 hex digits. Visually identical images hash within a few bits of each
+// other regardless of size, format or compression.
+func PerceptualHash(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	return fmt.Sprintf("%016x", dHash(img)), nil
+}
+
+// HashDistance returns the number of differing bits between two perceptual hashes.
+func HashDistance(a, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
+
+// dHash shrinks the image to a 9x8 grayscale grid and sets one bit per pixel
+// that is brighter than its right-hand neighbour.
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	grid := grayGrid(img, w, h)
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if grid[y*w+x] > grid[y*w+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// grayGrid box-filters the image down to w x h luminance values.
+func grayGrid(img image.Image, w, h int) []float64 {
+	bounds := img.Bounds()
+	grid := make([]float64, w*h)
+	counts := make([]float64, w*h)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		gy := (y - bounds.Min.Y) * h / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gx := (x - bounds.Min.X) * w / bounds.Dx()
+			r, g, b, _ := img.At(x, y).RGBA()
+			grid[gy*w+gx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[gy*w+gx]++
+		}
+	}
+
+	for i := range grid {
+		if counts[i] > 0 {
+			grid[i] /= counts[i]
+		}
+	}
+	return grid
+}
@@ -0,0 +1,127 @@
+package simage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// checkerboard draws cells x cells squares of alternating shades, scaled to
+// size pixels, so the same picture can be rendered at several sizes.
+func checkerboard(size, cells int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			shade := uint8(40)
+			if (x*cells/size+y*cells/size)%2 == 0 {
+				shade = 220
+			}
+			img.SetGray(x, y, color.Gray{Y: shade})
+		}
+	}
+	return img
+}
+
+func flat(size int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDHash(t *testing.T) {
+	if got := dHash(flat(64)); got != 0 {
+		t.Errorf("flat image hash = %016x, want 0", got)
+	}
+
+	// Brightness falling to the right sets every bit.
+	gradient := image.NewGray(image.Rect(0, 0, 90, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 90; x++ {
+			gradient.SetGray(x, y, color.Gray{Y: uint8(255 - x*2)})
+		}
+	}
+	if got := dHash(gradient); got != ^uint64(0) {
+		t.Errorf("falling gradient hash = %016x, want all bits set", got)
+	}
+
+	if dHash(checkerboard(72, 9)) != dHash(checkerboard(288, 9)) {
+		t.Error("the same picture hashes differently at another size")
+	}
+	if dHash(checkerboard(72, 9)) == dHash(checkerboard(72, 3)) {
+		t.Error("different pictures hash the same")
+	}
+}
+
+func TestPerceptualHash(t *testing.T) {
+	small, err := PerceptualHash(encodePNG(t, checkerboard(72, 9)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(small) != 16 {
+		t.Errorf("hash %q, want 16 hex digits", small)
+	}
+	large, err := PerceptualHash(encodeJPEG(t, checkerboard(360, 9)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := HashDistance(small, large); err != nil || d > nearDuplicateDistance {
+		t.Errorf("PNG and resized JPEG are %d bits apart (%v), want at most %d", d, err, nearDuplicateDistance)
+	}
+
+	if hash, err := PerceptualHash(encodePNG(t, flat(32))); err != nil || hash != flatImageHash {
+		t.Errorf("flat image hash = %q (%v), want %s", hash, err, flatImageHash)
+	}
+	if _, err := PerceptualHash([]byte("not an image")); err == nil {
+		t.Error("expected an error for undecodable data")
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{"0000000000000000", "0000000000000000", 0, false},
+		{"0000000000000000", "0000000000000001", 1, false},
+		{"00000000000000ff", "0000000000000000", 8, false},
+		{"ffffffffffffffff", "0000000000000000", 64, false},
+		{"f0f0f0f0f0f0f0f0", "0f0f0f0f0f0f0f0f", 64, false},
+		{"zz", "0000000000000000", 0, true},
+		{"0000000000000000", "", 0, true},
+	} {
+		got, err := HashDistance(tc.a, tc.b)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("HashDistance(%q, %q) = %d, %v; want %d, error %v", tc.a, tc.b, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if ContentHash([]byte("a")) != ContentHash([]byte("a")) || ContentHash([]byte("a")) == ContentHash([]byte("b")) {
+		t.Error("content hash should depend only on the bytes")
+	}
+}
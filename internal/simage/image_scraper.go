@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"sort"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
@@ -14,15 +16,35 @@ import (
 
 // TODO: Take scripts out in their own files
 const (
-	defaultTimeout = 10 * time.Second
-	evalScript     = `
+	defaultTimeout        = 10 * time.Second
+	defaultNetworkProfile = "No Throttling"
+	customNetworkProfile  = "Custom"
+	// evalScript runs after the page was scrolled, so above_fold compares the
+	// image's position on the page rather than in the current viewport.
+	evalScript = `
 		Array.from(document.images).map(img => ({
 			src: img.currentSrc || img.src,
 			alt: img.alt,
 			width: img.width,
-			height: img.height
+			height: img.height,
+			above_fold: img.getBoundingClientRect().top + window.scrollY < window.innerHeight
 		}))
 	`
+	// lcpScript resolves to the URL of the largest contentful paint element,
+	// or "" when it isn't an image.
+	lcpScript = `
+		new Promise(resolve => {
+			new PerformanceObserver(list => {
+				const entries = list.getEntries();
+				const last = entries[entries.length - 1];
+				resolve((last && last.url) || "");
+			}).observe({ type: 'largest-contentful-paint', buffered: true });
+			setTimeout(() => resolve(""), 1000);
+		})
+	`
+	linksScript = `
+		Array.from(document.querySelectorAll('a[href]')).map(a => a.href)
+	`
 	scrollScript = `
 		async function smoothScroll() {
 					const height = document.documentElement.scrollHeight;
@@ -119,7 +141,9 @@ const (
 
 type ImageScraper struct {
 	timeout          time.Duration
+	networkProfile   string
 	networkCondition *network.EmulateNetworkConditionsParams
+	cpuSlowdown      float64
 	headless         bool
 }
 
@@ -145,17 +169,76 @@ func (s *ImageScraper) SetNetworkProfile(profile string) error {
 		return fmt.Errorf("network profile %q not found", profile)
 	}
 
+	s.networkProfile = profile
+	s.setNetworkCondition(p)
+
+	return nil
+}
+
+// SetCustomNetwork emulates arbitrary network conditions instead of a named profile.
+func (s *ImageScraper) SetCustomNetwork(p NetworkProfile) error {
+	if p.Download == 0 || p.Download < -1 || p.Upload == 0 || p.Upload < -1 {
+		return fmt.Errorf("throughput must be positive or -1 to disable throttling")
+	}
+	if p.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+
+	s.networkProfile = customNetworkProfile
+	s.setNetworkCondition(p)
+
+	return nil
+}
+
+// SetCPUSlowdown throttles the page's CPU by the given factor, where 1 means no slowdown.
+func (s *ImageScraper) SetCPUSlowdown(rate float64) error {
+	if rate < 1 {
+		return fmt.Errorf("CPU slowdown rate must be at least 1, got %v", rate)
+	}
+	s.cpuSlowdown = rate
+	return nil
+}
+
+// Conditions reports the network and CPU emulation the scraper will apply.
+func (s *ImageScraper) Conditions() ScanConditions {
+	conditions := ScanConditions{
+		Profile:     defaultNetworkProfile,
+		Network:     getNetworkProfiles()[defaultNetworkProfile],
+		CPUSlowdown: 1,
+	}
+	if s.networkCondition != nil {
+		conditions.Profile = s.networkProfile
+		conditions.Network = NetworkProfile{
+			Download: s.networkCondition.DownloadThroughput,
+			Upload:   s.networkCondition.UploadThroughput,
+			Latency:  s.networkCondition.Latency,
+		}
+	}
+	if s.cpuSlowdown > 0 {
+		conditions.CPUSlowdown = s.cpuSlowdown
+	}
+	return conditions
+}
+
+func (s *ImageScraper) setNetworkCondition(p NetworkProfile) {
 	s.networkCondition = &network.EmulateNetworkConditionsParams{
 		Latency:            p.Latency,
 		DownloadThroughput: p.Download,
 		UploadThroughput:   p.Upload,
 		Offline:            false,
 	}
-
-	return nil
 }
 
 func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Image, WebsiteMetadata, error) {
+	page, err := s.ScrapePage(ctx, targetURL)
+	if err != nil {
+		return nil, WebsiteMetadata{}, err
+	}
+	return page.Images, page.Metadata, nil
+}
+
+// ScrapePage loads the URL and collects its images, metadata and outgoing links.
+func (s *ImageScraper) ScrapePage(ctx context.Context, targetURL string) (*Page, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -181,6 +264,8 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 
 	var metadata WebsiteMetadata
 	var imgElements []Image
+	var links []string
+	var lcpURL string
 
 	err := chromedp.Run(ctx,
 		network.Enable(),
@@ -197,11 +282,20 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 				}
 				log.Printf("Network conditions set: %+v", s.networkCondition)
 			}
+			if s.cpuSlowdown > 1 {
+				if err := emulation.SetCPUThrottlingRate(s.cpuSlowdown).Do(ctx); err != nil {
+					return fmt.Errorf("failed to set CPU throttling: %w", err)
+				}
+				log.Printf("CPU throttling set: %vx", s.cpuSlowdown)
+			}
 			return nil
 		}),
 		chromedp.Navigate(targetURL),
 		chromedp.Sleep(2*time.Second),
 		chromedp.Evaluate(metadataScript, &metadata),
+		chromedp.Evaluate(lcpScript, &lcpURL, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
 		chromedp.ActionFunc(func(ctx context.Context) error {
 			_, exp, err := runtime.Evaluate(
 				scrollScript,
@@ -218,14 +312,19 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 	)
 
 	if err != nil {
-		return nil, WebsiteMetadata{}, fmt.Errorf("error navigating to URL: %w", err)
+		return nil, fmt.Errorf("error navigating to URL: %w", err)
 	}
 
 	err = chromedp.Run(ctx,
 		chromedp.Evaluate(evalScript, &imgElements),
 	)
 	if err != nil {
-		return nil, WebsiteMetadata{}, fmt.Errorf("error extracting images from DOM: %w", err)
+		return nil, fmt.Errorf("error extracting images from DOM: %w", err)
+	}
+
+	err = chromedp.Run(ctx, chromedp.Evaluate(linksScript, &links))
+	if err != nil {
+		log.Printf("Warning: failed to extract links: %v", err)
 	}
 
 	var images []Image
@@ -242,6 +341,7 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 				netImg.Width = img.Width
 				netImg.Height = img.Height
 				netImg.Alt = img.Alt
+				netImg.AboveFold = img.AboveFold
 				imagesByRequestID[id] = netImg
 				found = true
 				break
@@ -250,10 +350,11 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 
 		if !found {
 			images = append(images, Image{
-				Src:    src,
-				Width:  img.Width,
-				Height: img.Height,
-				Alt:    img.Alt,
+				Src:       src,
+				Width:     img.Width,
+				Height:    img.Height,
+				Alt:       img.Alt,
+				AboveFold: img.AboveFold,
 			})
 		}
 	}
@@ -283,8 +384,18 @@ func (s *ImageScraper) ScrapeImages(ctx context.Context, targetURL string) ([]Im
 		}
 	}
 
+	if lcpURL != "" {
+		lcpSrc := cleanURL(lcpURL)
+		for i := range images {
+			if images[i].Src == lcpSrc {
+				images[i].LCP = true
+				break
+			}
+		}
+	}
+
 	log.Printf("Found %d unique images", len(images))
-	return images, metadata, nil
+	return &Page{Images: images, Metadata: metadata, Links: links}, nil
 }
 
 func handleImageEvents(ev interface{}, imagesByRequestID map[network.RequestID]Image) {
@@ -379,9 +490,36 @@ func normalizeFormat(mimeType string) string {
 	return mimeType
 }
 
+// NetworkProfileNames lists the built-in network profiles accepted by SetNetworkProfile.
+func NetworkProfileNames() []string {
+	names := make([]string, 0, len(getNetworkProfiles()))
+	for name := range getNetworkProfiles() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Matches reports whether two scans ran under the same emulation. Scans stored
+// before conditions were recorded always ran unthrottled.
+func (c ScanConditions) Matches(other ScanConditions) bool {
+	return c.normalized() == other.normalized()
+}
+
+func (c ScanConditions) normalized() ScanConditions {
+	if c.Profile == "" {
+		c.Profile = defaultNetworkProfile
+		c.Network = getNetworkProfiles()[defaultNetworkProfile]
+	}
+	if c.CPUSlowdown == 0 {
+		c.CPUSlowdown = 1
+	}
+	return c
+}
+
 func getNetworkProfiles() map[string]NetworkProfile {
 	return map[string]NetworkProfile{
-		"No Throttling": {
+		defaultNetworkProfile: {
 			Download: -1,
 			Upload:   -1,
 			Latency:  0,
@@ -396,6 +534,26 @@ func getNetworkProfiles() map[string]NetworkProfile {
 			Upload:   ((750 * 1000) / 8) * 0.9,
 			Latency:  150 * 3.75,
 		},
+		"Slow 4G": {
+			Download: (1.6 * 1000 * 1000) / 8,
+			Upload:   (750 * 1000) / 8,
+			Latency:  150,
+		},
+		"Regular 4G": {
+			Download: (4 * 1000 * 1000) / 8,
+			Upload:   (3 * 1000 * 1000) / 8,
+			Latency:  20,
+		},
+		"DSL": {
+			Download: (2 * 1000 * 1000) / 8,
+			Upload:   (1 * 1000 * 1000) / 8,
+			Latency:  5,
+		},
+		"Cable": {
+			Download: (5 * 1000 * 1000) / 8,
+			Upload:   (1 * 1000 * 1000) / 8,
+			Latency:  28,
+		},
 	}
 }
 
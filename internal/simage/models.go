@@ -37,6 +37,12 @@ type Image struct {
 	Network          NetworkInfo    `json:"network" bson:"network"`
 	Timing           TimingInfo     `json:"timing" bson:"timing"`
 	AIRecommendation Recommendation `json:"ai_recommendation" bson:"ai_recommendation"`
+	ContentHash      string         `json:"content_hash,omitempty" bson:"content_hash,omitempty"`
+	PerceptualHash   string         `json:"perceptual_hash,omitempty" bson:"perceptual_hash,omitempty"`
+	LCP              bool           `json:"lcp" bson:"lcp"`
+	// AboveFold is set for images that start within the first viewport height.
+	AboveFold    bool            `json:"above_fold" bson:"above_fold"`
+	Optimization *OptimizeResult `json:"optimization,omitempty" bson:"optimization,omitempty"`
 }
 
 type NetworkInfo struct {
@@ -73,10 +79,20 @@ type ImageOverview struct {
 	AverageTotalTime    float64        `json:"average_total_time" bson:"average_total_time"`
 }
 
+// NetworkProfile holds CDP network emulation values: throughput in bytes per
+// second (-1 disables throttling) and latency in milliseconds.
 type NetworkProfile struct {
-	Download float64
-	Upload   float64
-	Latency  float64
+	Download float64 `json:"download" bson:"download"`
+	Upload   float64 `json:"upload" bson:"upload"`
+	Latency  float64 `json:"latency" bson:"latency"`
+}
+
+// ScanConditions records the network and CPU emulation a scan ran under.
+// Results are only comparable between scans with equal conditions.
+type ScanConditions struct {
+	Profile     string         `json:"profile" bson:"profile"`
+	Network     NetworkProfile `json:"network" bson:"network"`
+	CPUSlowdown float64        `json:"cpu_slowdown" bson:"cpu_slowdown"`
 }
 
 type ResourceTimingEntry struct {
@@ -115,6 +131,66 @@ type WebsiteMetadata struct {
 	Language    string `json:"language" bson:"language"`
 }
 
+// DuplicateGroup is a set of image URLs showing the same picture. Exact groups
+// share identical bytes; near groups differ in size, format or compression.
+type DuplicateGroup struct {
+	Kind           string           `json:"kind" bson:"kind"`
+	PerceptualHash string           `json:"perceptual_hash" bson:"perceptual_hash"`
+	Images         []DuplicateImage `json:"images" bson:"images"`
+	WastedBytes    int              `json:"wasted_bytes" bson:"wasted_bytes"`
+}
+
+type DuplicateImage struct {
+	Src    string `json:"src" bson:"src"`
+	Format string `json:"format" bson:"format"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	Size   int    `json:"size" bson:"size"`
+}
+
+// Budget sets limits on a page's images. Zero values leave a limit unchecked.
+// MaxLCPLoadTime is in seconds, like NetworkInfo.LoadTime; MinModernFormatShare
+// is a fraction between 0 and 1.
+type Budget struct {
+	MaxTotalBytes        int64   `json:"max_total_bytes,omitempty" bson:"max_total_bytes,omitempty"`
+	MaxImageBytes        int     `json:"max_image_bytes,omitempty" bson:"max_image_bytes,omitempty"`
+	MaxImageCount        int     `json:"max_image_count,omitempty" bson:"max_image_count,omitempty"`
+	MinModernFormatShare float64 `json:"min_modern_format_share,omitempty" bson:"min_modern_format_share,omitempty"`
+	MaxLCPLoadTime       float64 `json:"max_lcp_load_time,omitempty" bson:"max_lcp_load_time,omitempty"`
+}
+
+type BudgetResult struct {
+	BudgetID string        `json:"budget_id,omitempty" bson:"budget_id,omitempty"`
+	Name     string        `json:"name" bson:"name"`
+	Passed   bool          `json:"passed" bson:"passed"`
+	Checks   []BudgetCheck `json:"checks" bson:"checks"`
+}
+
+// BudgetCheck is the outcome of one limit. Images lists the offending URLs
+// for per-image limits.
+type BudgetCheck struct {
+	Metric string   `json:"metric" bson:"metric"`
+	Limit  float64  `json:"limit" bson:"limit"`
+	Actual float64  `json:"actual" bson:"actual"`
+	Passed bool     `json:"passed" bson:"passed"`
+	Images []string `json:"images,omitempty" bson:"images,omitempty"`
+}
+
+// Finding is a problem detected by one of the analysis rules.
+type Finding struct {
+	RuleID   string   `json:"rule_id" bson:"rule_id"`
+	Severity string   `json:"severity" bson:"severity"`
+	Message  string   `json:"message" bson:"message"`
+	Images   []string `json:"images" bson:"images"`
+}
+
+// Page is everything ScrapePage collects from a single URL.
+type Page struct {
+	Images   []Image
+	Metadata WebsiteMetadata
+	Links    []string
+}
+
 type Scan struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	ScanID    string             `json:"scan_id" bson:"scan_id"`
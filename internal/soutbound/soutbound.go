@@ -0,0 +1,113 @@
+// Package soutbound makes HTTP requests to URLs chosen by users, such as
+// webhook endpoints, without letting them reach the server's own network:
+// loopback, private, link-local and other non-public addresses are refused
+// when connecting, and redirects are not followed.
+package soutbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for hosts that resolve to a non-public address.
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// ErrRedirect is returned instead of following a redirect, which could point
+// anywhere.
+var ErrRedirect = errors.New("redirects are not followed")
+
+// blockedNets are non-public ranges net.IP has no predicate for.
+var blockedNets = parseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, which can reach IPv4 private ranges
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// Public reports whether ip is a publicly routable unicast address.
+func Public(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewClient returns a client that only connects to public addresses. The
+// check runs on the resolved address of every connection, so DNS answers that
+// change after CheckURL are covered too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !Public(net.ParseIP(host)) {
+				return fmt.Errorf("%s: %w", host, ErrBlockedAddress)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: it would make the connection on our behalf.
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return ErrRedirect
+		},
+	}
+}
+
+// CheckURL validates a URL before it is stored: it must be absolute http or
+// https and its host must resolve only to public addresses.
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.ParseRequestURI(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("can't resolve %s", u.Hostname())
+	}
+	for _, ip := range ips {
+		if !Public(ip) {
+			return fmt.Errorf("%s: %w", u.Hostname(), ErrBlockedAddress)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+// Package squery evaluates MongoDB query and sort documents against documents
+// held in memory, so stores without a query engine can answer the filters the
+// API builds for Mongo. It supports the subset the API uses: equality,
+// $and, $or, $nor, $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists and
+// regular expressions, on dotted field paths.
+package squery
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Normalize round-trips a document through BSON, so Go values such as
+// time.Time, int or structs compare like the values stored by Mongo.
+func Normalize(doc interface{}) (bson.M, error) {
+	if doc == nil {
+		return bson.M{}, nil
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Decode reads a raw BSON document for matching.
+func Decode(data []byte) (bson.M, error) {
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Match reports whether the document matches the query. Both must be
+// normalized.
+func Match(doc, query bson.M) (bool, error) {
+	for key, cond := range query {
+		var ok bool
+		var err error
+		switch key {
+		case "$and":
+			ok, err = matchAll(doc, cond)
+		case "$or":
+			ok, err = matchAny(doc, cond)
+		case "$nor":
+			ok, err = matchAny(doc, cond)
+			ok = !ok
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported operator %s", key)
+			}
+			ok, err = matchField(Lookup(doc, key), cond)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchAll(doc bson.M, clauses interface{}) (bool, error) {
+	queries, err := subqueries(clauses)
+	if err != nil {
+		return false, err
+	}
+	for _, q := range queries {
+		if ok, err := Match(doc, q); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchAny(doc bson.M, clauses interface{}) (bool, error) {
+	queries, err := subqueries(clauses)
+	if err != nil {
+		return false, err
+	}
+	for _, q := range queries {
+		if ok, err := Match(doc, q); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func subqueries(clauses interface{}) ([]bson.M, error) {
+	list, ok := clauses.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("logical operators need an array, got %T", clauses)
+	}
+	queries := make([]bson.M, len(list))
+	for i, c := range list {
+		q, ok := c.(bson.M)
+		if !ok {
+			return nil, fmt.Errorf("logical operators need documents, got %T", c)
+		}
+		queries[i] = q
+	}
+	return queries, nil
+}
+
+// matchField matches the values found at a path against a condition, which
+// is a value, a regular expression or a document of operators.
+func matchField(values []interface{}, cond interface{}) (bool, error) {
+	if ops, ok := cond.(bson.M); ok && isOperators(ops) {
+		for op, arg := range ops {
+			ok, err := matchOperator(values, op, arg)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if re, ok := cond.(primitive.Regex); ok {
+		return matchRegex(values, re)
+	}
+	return equalsAny(values, cond), nil
+}
+
+func isOperators(m bson.M) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func matchOperator(values []interface{}, op string, arg interface{}) (bool, error) {
+	switch op {
+	case "$eq":
+		return equalsAny(values, arg), nil
+	case "$ne":
+		return !equalsAny(values, arg), nil
+	case "$in", "$nin":
+		list, ok := arg.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array, got %T", op, arg)
+		}
+		found := false
+		for _, want := range list {
+			if equalsAny(values, want) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "$in"), nil
+	case "$exists":
+		want, ok := arg.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists needs a boolean, got %T", arg)
+		}
+		return (len(values) > 0) == want, nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, v := range expand(values) {
+			if typeOrder(v) != typeOrder(arg) {
+				continue
+			}
+			c := Compare(v, arg)
+			if op == "$gt" && c > 0 || op == "$gte" && c >= 0 || op == "$lt" && c < 0 || op == "$lte" && c <= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported operator %s", op)
+}
+
+func matchRegex(values []interface{}, re primitive.Regex) (bool, error) {
+	pattern := re.Pattern
+	if strings.Contains(re.Options, "i") {
+		pattern = "(?i)" + pattern
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range expand(values) {
+		if s, ok := v.(string); ok && compiled.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// equalsAny follows Mongo's equality: null matches missing fields, and arrays
+// match when one of their elements is equal.
+func equalsAny(values []interface{}, want interface{}) bool {
+	if want == nil && len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if equal(v, want) {
+			return true
+		}
+		if list, ok := v.(bson.A); ok {
+			for _, item := range list {
+				if equal(item, want) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if typeOrder(a) != typeOrder(b) {
+		return false
+	}
+	return Compare(a, b) == 0
+}
+
+// expand flattens array values so operators apply to their elements.
+func expand(values []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range values {
+		if list, ok := v.(bson.A); ok {
+			out = append(out, list...)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// Lookup returns the values at a dotted path. Arrays along the path are
+// searched element by element, as Mongo does; a missing field has no values.
+func Lookup(doc bson.M, path string) []interface{} {
+	head, rest, nested := strings.Cut(path, ".")
+	v, ok := doc[head]
+	if !ok {
+		return nil
+	}
+	if !nested {
+		return []interface{}{v}
+	}
+
+	switch v := v.(type) {
+	case bson.M:
+		return Lookup(v, rest)
+	case bson.A:
+		var out []interface{}
+		for _, item := range v {
+			if m, ok := item.(bson.M); ok {
+				out = append(out, Lookup(m, rest)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Less orders documents by a sort document, as Mongo's sort stage does.
+// Missing fields sort first in ascending order.
+func Less(a, b bson.M, sort bson.D) bool {
+	for _, key := range sort {
+		c := Compare(first(Lookup(a, key.Key)), first(Lookup(b, key.Key)))
+		if c == 0 {
+			continue
+		}
+		if direction, ok := key.Value.(int); ok && direction < 0 {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+func first(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// typeOrder ranks BSON types in Mongo's comparison order; numbers of every
+// width share a rank.
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, int:
+		return 2
+	case string:
+		return 3
+	case bson.M, bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+// Compare orders two normalized values, first by type and then by value.
+func Compare(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return cmp(ta, tb)
+	}
+
+	switch a := a.(type) {
+	case int32, int64, float64, int:
+		return cmp(number(a), number(b))
+	case string:
+		return strings.Compare(a, b.(string))
+	case primitive.ObjectID:
+		id := b.(primitive.ObjectID)
+		return bytes.Compare(a[:], id[:])
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	case primitive.DateTime:
+		return cmp(a, b.(primitive.DateTime))
+	case bson.A:
+		list := b.(bson.A)
+		for i := 0; i < len(a) && i < len(list); i++ {
+			if c := Compare(a[i], list[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp(len(a), len(list))
+	case nil, primitive.Null, primitive.Undefined:
+		return 0
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func cmp[T int | float64 | primitive.DateTime](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,233 @@
+package sreport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+// ImageRow is one image of one scan flattened into spreadsheet columns. The
+// JSON tags double as the CSV header.
+type ImageRow struct {
+	ScanID    string    `json:"scan_id"`
+	ScanURL   string    `json:"scan_url"`
+	ScannedAt time.Time `json:"scanned_at"`
+
+	Src    string `json:"src"`
+	Alt    string `json:"alt"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
+	Size   int    `json:"size"`
+	LCP    bool   `json:"lcp"`
+
+	DocumentURL       string  `json:"document_url"`
+	InitiatorType     string  `json:"initiator_type"`
+	InitiatorURL      string  `json:"initiator_url"`
+	InitiatorLine     float64 `json:"initiator_line_no"`
+	InitiatorColumn   float64 `json:"initiator_col_no"`
+	Method            string  `json:"method"`
+	Status            int64   `json:"status"`
+	MimeType          string  `json:"mime_type"`
+	Protocol          string  `json:"protocol"`
+	RemoteIPAddress   string  `json:"remote_ip_address"`
+	RemotePort        int64   `json:"remote_port"`
+	EncodedDataLength int     `json:"encoded_data_length"`
+	LoadTime          float64 `json:"load_time"`
+
+	DNSLookup           float64 `json:"dns_lookup"`
+	ConnectionTime      float64 `json:"connection_time"`
+	SSLTime             float64 `json:"ssl_time"`
+	TTFB                float64 `json:"ttfb"`
+	ContentDownloadTime float64 `json:"content_download_time"`
+	TransferSize        float64 `json:"transfer_size"`
+	EncodedBodySize     float64 `json:"encoded_body_size"`
+	DecodedBodySize     float64 `json:"decoded_body_size"`
+
+	OptimizedFormat string `json:"optimized_format"`
+	OptimizedSize   int    `json:"optimized_size"`
+	Savings         int    `json:"savings"`
+
+	FindingCount int    `json:"finding_count"`
+	Findings     string `json:"findings"`
+}
+
+// imageColumns is the CSV header, in ImageRow field order.
+var imageColumns = []string{
+	"scan_id", "scan_url", "scanned_at",
+	"src", "alt", "width", "height", "format", "size", "lcp",
+	"document_url", "initiator_type", "initiator_url", "initiator_line_no", "initiator_col_no",
+	"method", "status", "mime_type", "protocol", "remote_ip_address", "remote_port",
+	"encoded_data_length", "load_time",
+	"dns_lookup", "connection_time", "ssl_time", "ttfb", "content_download_time",
+	"transfer_size", "encoded_body_size", "decoded_body_size",
+	"optimized_format", "optimized_size", "savings",
+	"finding_count", "findings",
+}
+
+// ImageRows flattens a scan's images. Findings are listed per image as
+// semicolon-separated rule IDs.
+func ImageRows(scanID, scanURL string, scannedAt time.Time, images []simage.Image, findings []simage.Finding) []ImageRow {
+	rules := make(map[string][]string)
+	for _, f := range findings {
+		for _, src := range f.Images {
+			rules[src] = append(rules[src], f.RuleID)
+		}
+	}
+
+	rows := make([]ImageRow, len(images))
+	for i, img := range images {
+		imageRules := rules[img.Src]
+		sort.Strings(imageRules)
+
+		rows[i] = ImageRow{
+			ScanID:    scanID,
+			ScanURL:   scanURL,
+			ScannedAt: scannedAt,
+
+			Src:    img.Src,
+			Alt:    img.Alt,
+			Width:  img.Width,
+			Height: img.Height,
+			Format: img.Format,
+			Size:   img.Size,
+			LCP:    img.LCP,
+
+			DocumentURL:       img.Network.DocumentURL,
+			InitiatorType:     string(img.Network.InitiatorType),
+			InitiatorURL:      img.Network.InitiatorURL,
+			InitiatorLine:     img.Network.InitiatorLineNo,
+			InitiatorColumn:   img.Network.InitiatorColNo,
+			Method:            img.Network.Method,
+			Status:            img.Network.Status,
+			MimeType:          img.Network.MimeType,
+			Protocol:          img.Network.Protocol,
+			RemoteIPAddress:   img.Network.RemoteIPAddress,
+			RemotePort:        img.Network.RemotePort,
+			EncodedDataLength: img.Network.EncodedDataLength,
+			LoadTime:          img.Network.LoadTime,
+
+			DNSLookup:           img.Timing.DNSLookup,
+			ConnectionTime:      img.Timing.ConnectionTime,
+			SSLTime:             img.Timing.SSLTime,
+			TTFB:                img.Timing.TTFB,
+			ContentDownloadTime: img.Timing.ContentDownloadTime,
+			TransferSize:        img.Timing.TransferSize,
+			EncodedBodySize:     img.Timing.EncodedBodySize,
+			DecodedBodySize:     img.Timing.DecodedBodySize,
+
+			FindingCount: len(imageRules),
+			Findings:     strings.Join(imageRules, ";"),
+		}
+		if opt := img.Optimization; opt != nil {
+			rows[i].OptimizedFormat = opt.Format
+			rows[i].OptimizedSize = opt.Size
+			rows[i].Savings = opt.Savings
+		}
+	}
+	return rows
+}
+
+// ImageRowWriter writes rows in batches, so exports spanning many scans can
+// be written one scan at a time. Flush must be called after the last batch.
+type ImageRowWriter interface {
+	Write(rows []ImageRow) error
+	Flush() error
+}
+
+// NewImagesCSVWriter writes CSV with a header line, even when no rows follow.
+func NewImagesCSVWriter(w io.Writer) ImageRowWriter {
+	return &csvRowWriter{cw: csv.NewWriter(w)}
+}
+
+type csvRowWriter struct {
+	cw     *csv.Writer
+	header bool
+}
+
+func (c *csvRowWriter) Write(rows []ImageRow) error {
+	if !c.header {
+		if err := c.cw.Write(imageColumns); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	for _, row := range rows {
+		if err := c.cw.Write(row.record()); err != nil {
+			return err
+		}
+	}
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+func (c *csvRowWriter) Flush() error {
+	if !c.header {
+		return c.Write(nil)
+	}
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// NewImagesJSONLWriter writes one JSON object per row and line.
+func NewImagesJSONLWriter(w io.Writer) ImageRowWriter {
+	return jsonlRowWriter{enc: json.NewEncoder(w)}
+}
+
+type jsonlRowWriter struct {
+	enc *json.Encoder
+}
+
+func (j jsonlRowWriter) Write(rows []ImageRow) error {
+	for _, row := range rows {
+		if err := j.enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j jsonlRowWriter) Flush() error {
+	return nil
+}
+
+// WriteImagesCSV writes the rows with a header line.
+func WriteImagesCSV(w io.Writer, rows []ImageRow) error {
+	return writeAll(NewImagesCSVWriter(w), rows)
+}
+
+// WriteImagesJSONL writes one JSON object per row and line.
+func WriteImagesJSONL(w io.Writer, rows []ImageRow) error {
+	return writeAll(NewImagesJSONLWriter(w), rows)
+}
+
+func writeAll(rw ImageRowWriter, rows []ImageRow) error {
+	if err := rw.Write(rows); err != nil {
+		return err
+	}
+	return rw.Flush()
+}
+
+func (r ImageRow) record() []string {
+	return []string{
+		r.ScanID, r.ScanURL, r.ScannedAt.UTC().Format(time.RFC3339),
+		r.Src, r.Alt, strconv.Itoa(r.Width), strconv.Itoa(r.Height), r.Format, strconv.Itoa(r.Size), strconv.FormatBool(r.LCP),
+		r.DocumentURL, r.InitiatorType, r.InitiatorURL, formatFloat(r.InitiatorLine), formatFloat(r.InitiatorColumn),
+		r.Method, strconv.FormatInt(r.Status, 10), r.MimeType, r.Protocol, r.RemoteIPAddress, strconv.FormatInt(r.RemotePort, 10),
+		strconv.Itoa(r.EncodedDataLength), formatFloat(r.LoadTime),
+		formatFloat(r.DNSLookup), formatFloat(r.ConnectionTime), formatFloat(r.SSLTime), formatFloat(r.TTFB), formatFloat(r.ContentDownloadTime),
+		formatFloat(r.TransferSize), formatFloat(r.EncodedBodySize), formatFloat(r.DecodedBodySize),
+		r.OptimizedFormat, strconv.Itoa(r.OptimizedSize), strconv.Itoa(r.Savings),
+		strconv.Itoa(r.FindingCount), r.Findings,
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,50 @@
+package sreport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImagesCSVWriter(t *testing.T) {
+	var empty bytes.Buffer
+	rw := NewImagesCSVWriter(&empty)
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(empty.String()); got != strings.Join(imageColumns, ",") {
+		t.Errorf("empty export = %q, want only the header", got)
+	}
+
+	var buf bytes.Buffer
+	rw = NewImagesCSVWriter(&buf)
+	for _, batch := range [][]ImageRow{{{Src: "a.jpg"}}, nil, {{Src: "b.jpg"}, {Src: "c.jpg"}}} {
+		if err := rw.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != strings.Join(imageColumns, ",") {
+		t.Errorf("batched export = %q, want one header and three rows", lines)
+	}
+}
+
+func TestImagesJSONLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	rw := NewImagesJSONLWriter(&buf)
+	for _, batch := range [][]ImageRow{{{Src: "a.jpg"}}, {{Src: "b.jpg"}}} {
+		if err := rw.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"src":"b.jpg"`) {
+		t.Errorf("export = %q, want one line per row", lines)
+	}
+}
@@ -0,0 +1,187 @@
+package sreport
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+const (
+	chartWidth      = 640
+	chartBarHeight  = 22
+	chartLabelWidth = 180
+	chartMaxBars    = 10
+)
+
+//go:embed report.html.tmpl
+var htmlTemplateText string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes":  func(n int) string { return FormatBytes(int64(n)) },
+	"metric": formatMetric,
+}).Parse(htmlTemplateText))
+
+// htmlReport is the view the HTML template renders.
+type htmlReport struct {
+	Report
+	Summary      summary
+	FormatChart  barChart
+	LargestChart barChart
+	Images       []htmlImage
+}
+
+type htmlImage struct {
+	simage.Image
+	Thumbnail template.URL
+	Findings  []simage.Finding
+}
+
+// summary holds the headline aggregations shown at the top of the report.
+type summary struct {
+	ImageCount  int
+	TotalBytes  int
+	AverageSize int
+	AverageLoad float64
+	LCPImage    string
+}
+
+type barChart struct {
+	Width      int
+	Height     int
+	LabelWidth int
+	Bars       []bar
+}
+
+type bar struct {
+	Label  string
+	Value  string
+	Y      int
+	Width  int
+	ValueX int
+}
+
+// WriteHTML renders a single self-contained HTML page: styles, charts and
+// thumbnails are all inline, so the file can be emailed or archived as is.
+// Thumbnails come from Report.Thumbnails; images without one are listed
+// without a preview.
+func WriteHTML(w io.Writer, r Report) error {
+	byImage := make(map[string][]simage.Finding)
+	for _, f := range r.Findings {
+		for _, src := range f.Images {
+			byImage[src] = append(byImage[src], f)
+		}
+	}
+
+	images := make([]htmlImage, len(r.Images))
+	for i, img := range r.Images {
+		images[i] = htmlImage{Image: img, Findings: byImage[img.Src]}
+		if thumb, ok := r.Thumbnails[img.Src]; ok {
+			images[i].Thumbnail = template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb))
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Size > images[j].Size })
+
+	return htmlTemplate.Execute(w, htmlReport{
+		Report:       r,
+		Summary:      r.summary(),
+		FormatChart:  formatChart(r.Images),
+		LargestChart: largestChart(r.Images),
+		Images:       images,
+	})
+}
+
+func (r Report) summary() summary {
+	s := summary{ImageCount: len(r.Images), TotalBytes: int(r.TotalBytes())}
+	var load float64
+	for _, img := range r.Images {
+		load += img.Network.LoadTime
+		if img.LCP {
+			s.LCPImage = img.Src
+		}
+	}
+	if s.ImageCount > 0 {
+		s.AverageSize = s.TotalBytes / s.ImageCount
+		s.AverageLoad = load / float64(s.ImageCount)
+	}
+	return s
+}
+
+// formatChart sums the bytes served per image format.
+func formatChart(images []simage.Image) barChart {
+	totals := make(map[string]int)
+	for _, img := range images {
+		format := img.Format
+		if format == "" {
+			format = "unknown"
+		}
+		totals[format] += img.Size
+	}
+
+	formats := make([]string, 0, len(totals))
+	for format := range totals {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return totals[formats[i]] > totals[formats[j]] })
+
+	labels := make([]string, len(formats))
+	values := make([]int, len(formats))
+	for i, format := range formats {
+		labels[i], values[i] = format, totals[format]
+	}
+	return newBarChart(labels, values)
+}
+
+// largestChart shows the heaviest images by transfer size.
+func largestChart(images []simage.Image) barChart {
+	sorted := make([]simage.Image, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Size > sorted[j].Size })
+	if len(sorted) > chartMaxBars {
+		sorted = sorted[:chartMaxBars]
+	}
+
+	labels := make([]string, len(sorted))
+	values := make([]int, len(sorted))
+	for i, img := range sorted {
+		labels[i], values[i] = truncateMiddle(img.Src, 28), img.Size
+	}
+	return newBarChart(labels, values)
+}
+
+// newBarChart lays out a horizontal bar chart scaled to the largest value.
+func newBarChart(labels []string, values []int) barChart {
+	if len(labels) > chartMaxBars {
+		labels, values = labels[:chartMaxBars], values[:chartMaxBars]
+	}
+
+	peak := 1
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	chart := barChart{Width: chartWidth, Height: len(labels) * chartBarHeight, LabelWidth: chartLabelWidth}
+	span := chartWidth - chartLabelWidth - 80
+	for i, label := range labels {
+		width := max(values[i]*span/peak, 1)
+		chart.Bars = append(chart.Bars, bar{
+			Label:  label,
+			Value:  FormatBytes(int64(values[i])),
+			Y:      i * chartBarHeight,
+			Width:  width,
+			ValueX: chartLabelWidth + width + 6,
+		})
+	}
+	return chart
+}
+
+func truncateMiddle(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	half := (n - 3) / 2
+	return s[:half] + "..." + s[len(s)-half:]
+}
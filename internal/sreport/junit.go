@@ -0,0 +1,94 @@
+package sreport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders one test suite of rule checks with a test case per rule
+// and image, plus a suite of budget checks.
+func WriteJUnit(w io.Writer, r Report) error {
+	byRule := r.findingsByImage()
+
+	rulesSuite := junitTestSuite{Name: "image rules: " + r.URL}
+	if !r.CreatedAt.IsZero() {
+		rulesSuite.Timestamp = r.CreatedAt.UTC().Format("2006-01-02T15:04:05")
+	}
+	for _, rule := range simage.Rules() {
+		for _, img := range r.Images {
+			tc := junitTestCase{ClassName: rule.ID, Name: img.Src}
+			if f, ok := byRule[rule.ID][img.Src]; ok {
+				tc.Failure = &junitFailure{Message: f.Message, Type: f.Severity, Text: f.Message}
+			}
+			rulesSuite.add(tc)
+		}
+	}
+
+	budgetSuite := junitTestSuite{Name: "budgets: " + r.URL, Timestamp: rulesSuite.Timestamp}
+	for _, b := range r.Budgets {
+		for _, c := range b.Checks {
+			tc := junitTestCase{ClassName: "budget." + b.Name, Name: c.Metric}
+			if !c.Passed {
+				msg := fmt.Sprintf("%s is %v, limit %v", c.Metric, c.Actual, c.Limit)
+				tc.Failure = &junitFailure{Message: msg, Type: "budget", Text: msg}
+			}
+			budgetSuite.add(tc)
+		}
+	}
+
+	suites := junitTestSuites{Name: "sharprender", Suites: []junitTestSuite{rulesSuite, budgetSuite}}
+	for _, s := range suites.Suites {
+		suites.Tests += s.Tests
+		suites.Failures += s.Failures
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (s *junitTestSuite) add(tc junitTestCase) {
+	s.Cases = append(s.Cases, tc)
+	s.Tests++
+	if tc.Failure != nil {
+		s.Failures++
+	}
+}
@@ -0,0 +1,87 @@
+package sreport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	r := Report{
+		URL: "https://example.com/",
+		Images: []simage.Image{
+			{Src: "https://example.com/a.jpg"},
+			{Src: "https://example.com/b.jpg"},
+		},
+		Findings: []simage.Finding{
+			{RuleID: simage.RuleDuplicateImage, Severity: simage.SeverityWarning, Message: "duplicated",
+				Images: []string{"https://example.com/a.jpg", "https://example.com/b.jpg"}},
+			{RuleID: simage.RuleOversizedImage, Severity: simage.SeverityCritical, Message: "too big",
+				Images: []string{"https://example.com/a.jpg"}},
+		},
+		Budgets: []simage.BudgetResult{{Name: "home", Checks: []simage.BudgetCheck{
+			{Metric: simage.MetricTotalBytes, Limit: 100, Actual: 200, Passed: false},
+			{Metric: simage.MetricImageCount, Limit: 5, Actual: 2, Passed: true},
+		}}},
+		CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+
+	// Every rule is a test case for every image; budgets add one per check.
+	ruleCases := len(simage.Rules()) * len(r.Images)
+	if suites.Tests != ruleCases+2 || suites.Failures != 4 {
+		t.Errorf("totals = %d tests, %d failures; want %d, 4", suites.Tests, suites.Failures, ruleCases+2)
+	}
+	if len(suites.Suites) != 2 {
+		t.Fatalf("suites = %d, want rules and budgets", len(suites.Suites))
+	}
+
+	rules, budgets := suites.Suites[0], suites.Suites[1]
+	if rules.Tests != ruleCases || rules.Failures != 3 || rules.Timestamp != "2026-03-01T12:00:00" {
+		t.Errorf("rules suite = %d tests, %d failures at %q", rules.Tests, rules.Failures, rules.Timestamp)
+	}
+	if budgets.Tests != 2 || budgets.Failures != 1 {
+		t.Errorf("budget suite = %d tests, %d failures; want 2, 1", budgets.Tests, budgets.Failures)
+	}
+
+	for _, tc := range rules.Cases {
+		if tc.ClassName == simage.RuleOversizedImage && tc.Name == "https://example.com/a.jpg" {
+			if tc.Failure == nil || tc.Failure.Type != simage.SeverityCritical || tc.Failure.Message != "too big" {
+				t.Errorf("oversized case failure = %+v", tc.Failure)
+			}
+		}
+	}
+	for _, tc := range budgets.Cases {
+		if tc.ClassName != "budget.home" {
+			t.Errorf("budget case class = %q", tc.ClassName)
+		}
+		if failed := tc.Failure != nil; failed != (tc.Name == simage.MetricTotalBytes) {
+			t.Errorf("budget case %s failure = %+v", tc.Name, tc.Failure)
+		}
+	}
+}
+
+func TestWriteJUnitEmptyReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, Report{URL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 0 || suites.Failures != 0 || suites.Suites[0].Timestamp != "" {
+		t.Errorf("empty report = %+v", suites)
+	}
+}
@@ -0,0 +1,109 @@
+package sreport
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+const markdownLargestImages = 10
+
+// WriteMarkdown renders a summary sized for a pull request comment: headline
+// numbers, budget results, findings and the heaviest images.
+func WriteMarkdown(w io.Writer, r Report) error {
+	var b strings.Builder
+
+	status := "✅ All budgets passed"
+	if !r.Passed {
+		status = "❌ Budget violations"
+	}
+	if len(r.Budgets) == 0 {
+		status = "No budgets configured"
+	}
+
+	fmt.Fprintf(&b, "## Sharprender image report\n\n")
+	fmt.Fprintf(&b, "**%s** — %s\n\n", escapeMarkdown(r.URL), status)
+	if r.ImagesPurgedAt != nil {
+		fmt.Fprintf(&b, "_Images were removed by retention on %s; findings and budgets are from the original scan._\n\n", r.ImagesPurgedAt.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&b, "| Images | Total size | Findings | Network |\n|---:|---:|---:|---|\n")
+	fmt.Fprintf(&b, "| %d | %s | %d | %s |\n\n", len(r.Images), FormatBytes(r.TotalBytes()), len(r.Findings), escapeMarkdown(r.Conditions.Profile))
+
+	if len(r.Budgets) > 0 {
+		fmt.Fprintf(&b, "### Budgets\n\n| | Budget | Metric | Actual | Limit |\n|---|---|---|---:|---:|\n")
+		for _, budget := range r.Budgets {
+			for _, c := range budget.Checks {
+				mark := "✅"
+				if !c.Passed {
+					mark = "❌"
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+					mark, escapeMarkdown(budget.Name), c.Metric, formatMetric(c.Metric, c.Actual), formatMetric(c.Metric, c.Limit))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.Findings) > 0 {
+		fmt.Fprintf(&b, "### Findings\n\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "- **%s** `%s`: %s\n", f.Severity, f.RuleID, escapeMarkdown(f.Message))
+			for _, src := range f.Images {
+				fmt.Fprintf(&b, "  - %s\n", escapeMarkdown(src))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.Images) > 0 {
+		images := make([]simage.Image, len(r.Images))
+		copy(images, r.Images)
+		sort.SliceStable(images, func(i, j int) bool { return images[i].Size > images[j].Size })
+		if len(images) > markdownLargestImages {
+			images = images[:markdownLargestImages]
+		}
+
+		fmt.Fprintf(&b, "<details>\n<summary>Largest images</summary>\n\n")
+		fmt.Fprintf(&b, "| Size | Format | Dimensions | Image |\n|---:|---|---|---|\n")
+		for _, img := range images {
+			fmt.Fprintf(&b, "| %s | %s | %dx%d | %s |\n",
+				FormatBytes(int64(img.Size)), img.Format, img.Width, img.Height, escapeMarkdown(img.Src))
+		}
+		fmt.Fprintf(&b, "\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// FormatBytes renders a byte count with a binary unit.
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func formatMetric(metric string, v float64) string {
+	switch metric {
+	case simage.MetricTotalBytes, simage.MetricImageBytes:
+		return FormatBytes(int64(v))
+	case simage.MetricModernFormatShare:
+		return fmt.Sprintf("%.0f%%", v*100)
+	case simage.MetricLCPLoadTime:
+		return fmt.Sprintf("%.2fs", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// escapeMarkdown keeps URLs and page titles from breaking table cells or
+// being rendered as markup.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;", ">", "&gt;", "\n", " ").Replace(s)
+}
@@ -0,0 +1,62 @@
+// Package sreport renders scan results in formats other tools ingest.
+package sreport
+
+import (
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+// Report is the scan data every writer renders, whether it came from the
+// database or a local CLI run.
+type Report struct {
+	URL        string                  `json:"url"`
+	Metadata   simage.WebsiteMetadata  `json:"metadata"`
+	Conditions simage.ScanConditions   `json:"conditions"`
+	Images     []simage.Image          `json:"images"`
+	Findings   []simage.Finding        `json:"findings"`
+	Duplicates []simage.DuplicateGroup `json:"duplicates"`
+	Budgets    []simage.BudgetResult   `json:"budgets"`
+	Passed     bool                    `json:"passed"`
+	CreatedAt  time.Time               `json:"created_at"`
+	// ImagesPurgedAt is set when retention removed the scan's images, which
+	// leaves Images empty while Findings and Budgets still describe them.
+	ImagesPurgedAt *time.Time `json:"images_purged_at,omitempty"`
+
+	// Thumbnails holds small JPEG previews keyed by image URL for the HTML
+	// and PDF reports. Other writers ignore it.
+	Thumbnails map[string][]byte `json:"-"`
+}
+
+// TotalBytes sums the transfer size of every image.
+func (r Report) TotalBytes() int64 {
+	var total int64
+	for _, img := range r.Images {
+		total += int64(img.Size)
+	}
+	return total
+}
+
+// findingsByImage maps each rule to the images it flagged and the finding
+// that flagged them.
+func (r Report) findingsByImage() map[string]map[string]simage.Finding {
+	byRule := make(map[string]map[string]simage.Finding)
+	for _, f := range r.Findings {
+		if byRule[f.RuleID] == nil {
+			byRule[f.RuleID] = make(map[string]simage.Finding)
+		}
+		for _, src := range f.Images {
+			byRule[f.RuleID][src] = f
+		}
+	}
+	return byRule
+}
+
+func (r Report) image(src string) (simage.Image, bool) {
+	for _, img := range r.Images {
+		if img.Src == src {
+			return img, true
+		}
+	}
+	return simage.Image{}, false
+}
@@ -0,0 +1,154 @@
+package sreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolURI      = "https://github.com/voage/sharprender"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// WriteSARIF renders one result per finding and image, located at the script
+// or document that initiated the image request, plus one per failed budget check.
+func WriteSARIF(w io.Writer, r Report) error {
+	driver := sarifDriver{Name: "sharprender", InformationURI: toolURI, Rules: []sarifRule{}}
+	for _, rule := range simage.Rules() {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, f := range r.Findings {
+		for _, src := range f.Images {
+			results = append(results, sarifResult{
+				RuleID:    f.RuleID,
+				Level:     sarifLevel(f.Severity),
+				Message:   sarifMessage{Text: fmt.Sprintf("%s (%s)", f.Message, src)},
+				Locations: []sarifLocation{r.imageLocation(src)},
+			})
+		}
+	}
+
+	for _, b := range r.Budgets {
+		for _, c := range b.Checks {
+			if c.Passed {
+				continue
+			}
+			results = append(results, sarifResult{
+				RuleID:  "budget/" + c.Metric,
+				Level:   "error",
+				Message: sarifMessage{Text: fmt.Sprintf("Budget %q: %s is %v, limit %v", b.Name, c.Metric, c.Actual, c.Limit)},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: r.URL}},
+				}},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// imageLocation points at the initiator captured in NetworkInfo. CDP line and
+// column numbers are zero-based; SARIF's are one-based.
+func (r Report) imageLocation(src string) sarifLocation {
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: src}}}
+
+	img, ok := r.image(src)
+	if !ok || img.Network.InitiatorURL == "" {
+		return loc
+	}
+
+	loc.PhysicalLocation.ArtifactLocation.URI = img.Network.InitiatorURL
+	loc.PhysicalLocation.Region = &sarifRegion{
+		StartLine:   int(img.Network.InitiatorLineNo) + 1,
+		StartColumn: int(img.Network.InitiatorColNo) + 1,
+	}
+	return loc
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case simage.SeverityCritical:
+		return "error"
+	case simage.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
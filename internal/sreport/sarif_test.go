@@ -0,0 +1,86 @@
+package sreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/simage"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	scripted := simage.Image{Src: "https://example.com/lazy.jpg"}
+	scripted.Network.InitiatorURL = "https://example.com/app.js"
+	scripted.Network.InitiatorLineNo = 0
+	scripted.Network.InitiatorColNo = 41
+
+	r := Report{
+		URL:    "https://example.com/",
+		Images: []simage.Image{scripted, {Src: "https://example.com/static.png"}},
+		Findings: []simage.Finding{
+			{RuleID: simage.RuleOversizedImage, Severity: simage.SeverityCritical, Message: "too big",
+				Images: []string{"https://example.com/lazy.jpg"}},
+			{RuleID: simage.RuleNearDuplicateImage, Severity: simage.SeverityInfo, Message: "same picture",
+				Images: []string{"https://example.com/static.png"}},
+		},
+		Budgets: []simage.BudgetResult{{Name: "home", Checks: []simage.BudgetCheck{
+			{Metric: simage.MetricTotalBytes, Limit: 100, Actual: 200, Passed: false},
+			{Metric: simage.MetricImageCount, Limit: 5, Actual: 2, Passed: true},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("log = %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(simage.Rules()) {
+		t.Errorf("driver lists %d rules, want %d", len(run.Tool.Driver.Rules), len(simage.Rules()))
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("results = %+v, want two findings and one failed budget check", run.Results)
+	}
+
+	oversized := run.Results[0]
+	loc := oversized.Locations[0].PhysicalLocation
+	if oversized.Level != "error" || loc.ArtifactLocation.URI != "https://example.com/app.js" {
+		t.Errorf("oversized result = %+v", oversized)
+	}
+	// CDP positions are zero-based, SARIF regions one-based.
+	if loc.Region == nil || loc.Region.StartLine != 1 || loc.Region.StartColumn != 42 {
+		t.Errorf("region = %+v, want line 1 column 42", loc.Region)
+	}
+
+	nearDuplicate := run.Results[1]
+	loc = nearDuplicate.Locations[0].PhysicalLocation
+	if nearDuplicate.Level != "note" || loc.ArtifactLocation.URI != "https://example.com/static.png" || loc.Region != nil {
+		t.Errorf("image without an initiator = %+v", nearDuplicate)
+	}
+
+	budget := run.Results[2]
+	if budget.RuleID != "budget/"+simage.MetricTotalBytes || budget.Level != "error" ||
+		budget.Locations[0].PhysicalLocation.ArtifactLocation.URI != r.URL {
+		t.Errorf("budget result = %+v", budget)
+	}
+}
+
+func TestSarifLevel(t *testing.T) {
+	for severity, want := range map[string]string{
+		simage.SeverityCritical: "error",
+		simage.SeverityWarning:  "warning",
+		simage.SeverityInfo:     "note",
+		"":                      "note",
+	} {
+		if got := sarifLevel(severity); got != want {
+			t.Errorf("sarifLevel(%q) = %q, want %q", severity, got, want)
+		}
+	}
+}
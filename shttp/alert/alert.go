@@ -0,0 +1,37 @@
+package alert
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewAlertRoutes(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, notifiers Notifiers) *chi.Mux {
+	handler := NewAlertHandler(NewAlertServiceFromClient(mongoClient, scans, artifacts, notifiers))
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Get("/", handler.GetAlerts)
+	router.Post("/rules", handler.CreateRule)
+	router.Get("/rules", handler.GetRules)
+	router.Delete("/rules/{id}", handler.DeleteRule)
+	router.Post("/rules/{id}/test", handler.TestRule)
+
+	return router
+}
+
+// NewAlertServiceFromClient builds the service the scheduler checks scans
+// through.
+func NewAlertServiceFromClient(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, notifiers Notifiers) *AlertService {
+	return NewAlertService(
+		NewRuleRepository(mongoClient),
+		NewAlertRepository(mongoClient),
+		scans,
+		scan.NewScanServiceFromClient(mongoClient, scans, artifacts),
+		org.NewAccessServiceFromClient(mongoClient),
+		notifiers,
+	)
+}
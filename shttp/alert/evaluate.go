@@ -0,0 +1,26 @@
+package alert
+
+import (
+	"github.com/voage/sharprender-api/shttp/scan"
+)
+
+// regressions lists the ways current is worse than baseline under the rule.
+func regressions(rule Rule, baseline, current *scan.Scan) []Reason {
+	return scan.Regressions(rule.criteria(), baseline, current)
+}
+
+// newReasons returns the reasons whose key isn't in previous.
+func newReasons(previous, current []Reason) []Reason {
+	seen := map[string]bool{}
+	for _, r := range previous {
+		seen[r.Key] = true
+	}
+
+	var fresh []Reason
+	for _, r := range current {
+		if !seen[r.Key] {
+			fresh = append(fresh, r)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/scan"
+)
+
+func TestRegressions(t *testing.T) {
+	rule := Rule{BytesIncreasePercent: 10, FindingSeverity: simage.SeverityWarning, BudgetViolations: true}
+	baseline := &scan.Scan{
+		Summary:  scan.ScanSummary{TotalBytes: 1000},
+		Findings: []simage.Finding{{RuleID: simage.RuleDuplicateImage, Severity: simage.SeverityWarning, Images: []string{"a.png"}}},
+	}
+	current := &scan.Scan{
+		Summary: scan.ScanSummary{TotalBytes: 1200},
+		Findings: []simage.Finding{
+			{RuleID: simage.RuleDuplicateImage, Severity: simage.SeverityWarning, Images: []string{"a.png"}},
+			{RuleID: simage.RuleCompressibleImage, Severity: simage.SeverityWarning, Images: []string{"b.jpg"}},
+			{RuleID: simage.RuleNearDuplicateImage, Severity: simage.SeverityInfo, Images: []string{"c.webp"}},
+		},
+		Budgets: []simage.BudgetResult{{Name: "hero", Passed: false}, {Name: "total", Passed: true}},
+	}
+
+	reasons := regressions(rule, baseline, current)
+	var kinds []string
+	for _, r := range reasons {
+		kinds = append(kinds, r.Kind)
+	}
+	if len(kinds) != 3 || kinds[0] != ReasonBytes || kinds[1] != ReasonFinding || kinds[2] != ReasonBudget {
+		t.Fatalf("reasons = %v, want bytes, the new warning and the failed budget", kinds)
+	}
+
+	if got := regressions(rule, nil, current); len(got) != 1 || got[0].Kind != ReasonBudget {
+		t.Fatalf("without a baseline got %v, want only the failed budget", got)
+	}
+
+	current.Summary.TotalBytes = 1100
+	if got := regressions(Rule{BytesIncreasePercent: 10}, baseline, current); len(got) != 0 {
+		t.Fatalf("10%% growth got %v, want no regression", got)
+	}
+}
+
+func TestNewReasonsSkipsNotified(t *testing.T) {
+	open := []Reason{{Kind: ReasonBudget, Key: "budget:hero"}}
+	current := []Reason{{Kind: ReasonBudget, Key: "budget:hero"}, {Kind: ReasonBytes, Key: ReasonBytes}}
+
+	fresh := newReasons(open, current)
+	if len(fresh) != 1 || fresh[0].Key != ReasonBytes {
+		t.Fatalf("fresh = %v, want only the bytes regression", fresh)
+	}
+	if fresh := newReasons(current, current); len(fresh) != 0 {
+		t.Fatalf("repeated regression re-notified: %v", fresh)
+	}
+}
@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AlertHandler struct {
+	service *AlertService
+}
+
+func NewAlertHandler(service *AlertService) *AlertHandler {
+	return &AlertHandler{service: service}
+}
+
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req Rule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ProjectID.IsZero() {
+		http.Error(w, "Missing project_id parameter", http.StatusBadRequest)
+		return
+	}
+	if req.URL != "" {
+		if _, err := url.ParseRequestURI(req.URL); err != nil {
+			http.Error(w, "Invalid URL", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.service.checkChannels(r.Context(), req.Channels); err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateRule(r.Context(), auth.UserID(r.Context()), req)
+	if err != nil {
+		org.WriteAccessError(w, err, "Failed to create alert rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// GetRules lists alert rules, optionally of the project named by project_id.
+func (h *AlertHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	projectID, err := parseProjectParam(r)
+	if err != nil {
+		http.Error(w, "Invalid project_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := h.service.GetRules(r.Context(), auth.UserID(r.Context()), projectID)
+	if err != nil {
+		org.WriteAccessError(w, err, "Failed to fetch alert rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"rules":   rules,
+	})
+}
+
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteRule(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestRule sends a test message through the rule's channels.
+func (h *AlertHandler) TestRule(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	failures, err := h.service.TestRule(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to test alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": len(failures) == 0,
+		"errors":  failures,
+	})
+}
+
+// GetAlerts lists recent alerts, optionally of the project named by
+// project_id. open=true leaves out resolved alerts.
+func (h *AlertHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	projectID, err := parseProjectParam(r)
+	if err != nil {
+		http.Error(w, "Invalid project_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	alerts, err := h.service.GetAlerts(r.Context(), auth.UserID(r.Context()), projectID, r.URL.Query().Get("open") == "true")
+	if err != nil {
+		org.WriteAccessError(w, err, "Failed to fetch alerts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"alerts":  alerts,
+	})
+}
+
+func parseProjectParam(r *http.Request) (*primitive.ObjectID, error) {
+	value := r.URL.Query().Get("project_id")
+	if value == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
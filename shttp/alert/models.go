@@ -0,0 +1,149 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Channel types.
+const (
+	ChannelEmail = "email"
+	ChannelSlack = "slack"
+	ChannelTeams = "teams"
+)
+
+// Channel is where a rule's alerts are sent: email recipients, or a Slack or
+// Teams incoming webhook.
+type Channel struct {
+	Type string   `json:"type" bson:"type"`
+	To   []string `json:"to,omitempty" bson:"to,omitempty"`
+	URL  string   `json:"url,omitempty" bson:"url,omitempty"`
+}
+
+func (c Channel) Validate() error {
+	switch c.Type {
+	case ChannelEmail:
+		if len(c.To) == 0 {
+			return errors.New("email channels need at least one recipient")
+		}
+		for _, to := range c.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid recipient %q", to)
+			}
+		}
+	case ChannelSlack, ChannelTeams:
+		u, err := url.ParseRequestURI(c.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%s channels need an https webhook URL", c.Type)
+		}
+	default:
+		return fmt.Errorf("unknown channel type %q", c.Type)
+	}
+	return nil
+}
+
+// Rule alerts on regressions of a project's scheduled scans. Each condition
+// is optional, but at least one must be set.
+type Rule struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `json:"project_id" bson:"project_id"`
+	Name      string             `json:"name" bson:"name"`
+	// URL restricts the rule to one monitored page; empty covers all of the
+	// project's schedules.
+	URL string `json:"url,omitempty" bson:"url,omitempty"`
+	// BytesIncreasePercent alerts when total image bytes grow by more than
+	// this share of the baseline.
+	BytesIncreasePercent float64 `json:"bytes_increase_percent,omitempty" bson:"bytes_increase_percent,omitempty"`
+	// FindingSeverity alerts on new findings of at least this severity.
+	FindingSeverity string `json:"finding_severity,omitempty" bson:"finding_severity,omitempty"`
+	// BudgetViolations alerts when a budget fails.
+	BudgetViolations bool      `json:"budget_violations" bson:"budget_violations"`
+	Channels         []Channel `json:"channels" bson:"channels"`
+	CreatedBy        string    `json:"created_by" bson:"created_by"`
+	CreatedAt        time.Time `json:"created_at" bson:"created_at"`
+}
+
+// maxNameLength keeps rule names short enough for email subjects.
+const maxNameLength = 100
+
+// criteria are the rule's conditions as scan.Regressions takes them.
+func (r Rule) criteria() scan.RegressionCriteria {
+	return scan.RegressionCriteria{
+		BytesIncreasePercent: r.BytesIncreasePercent,
+		FindingSeverity:      r.FindingSeverity,
+		BudgetViolations:     r.BudgetViolations,
+	}
+}
+
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(r.Name) > maxNameLength {
+		return fmt.Errorf("name may be at most %d characters", maxNameLength)
+	}
+	if strings.ContainsAny(r.Name, "\r\n") {
+		return errors.New("name can't contain line breaks")
+	}
+	if r.BytesIncreasePercent < 0 {
+		return errors.New("bytes_increase_percent can't be negative")
+	}
+	if r.FindingSeverity != "" && simage.SeverityRank(r.FindingSeverity) == 0 {
+		return fmt.Errorf("unknown severity %q", r.FindingSeverity)
+	}
+	if r.BytesIncreasePercent == 0 && r.FindingSeverity == "" && !r.BudgetViolations {
+		return errors.New("at least one condition is required")
+	}
+	if len(r.Channels) == 0 {
+		return errors.New("at least one channel is required")
+	}
+	for _, c := range r.Channels {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reason kinds.
+const (
+	ReasonBytes   = scan.RegressionBytes
+	ReasonFinding = scan.RegressionFinding
+	ReasonBudget  = scan.RegressionBudget
+)
+
+// Reason is one way a scan got worse, as found by scan.Regressions.
+type Reason = scan.Regression
+
+// Alert tracks a regression of one page under one rule from the scan it was
+// first seen in until a scan no longer shows it. Only reasons not already on
+// the open alert are notified, so a regression that persists across daily
+// scans is reported once.
+type Alert struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RuleID    primitive.ObjectID `json:"rule_id" bson:"rule_id"`
+	ProjectID primitive.ObjectID `json:"project_id" bson:"project_id"`
+	URL       string             `json:"url" bson:"url"`
+	// BaselineScanID is the last scan before the regression; later scans are
+	// compared against it while the alert is open.
+	BaselineScanID *primitive.ObjectID `json:"baseline_scan_id,omitempty" bson:"baseline_scan_id,omitempty"`
+	ScanID         primitive.ObjectID  `json:"scan_id" bson:"scan_id"`
+	Reasons        []Reason            `json:"reasons" bson:"reasons"`
+	// Scans counts the scans that showed the regression.
+	Scans       int        `json:"scans" bson:"scans"`
+	FirstSeenAt time.Time  `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at" bson:"last_seen_at"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty" bson:"notified_at,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	// Errors lists channels that failed on the last notification.
+	Errors []string `json:"errors,omitempty" bson:"errors,omitempty"`
+}
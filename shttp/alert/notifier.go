@@ -0,0 +1,189 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/soutbound"
+)
+
+// Message is a notification, rendered by each channel as it sees fit.
+type Message struct {
+	Subject string
+	Lines   []string
+}
+
+func (m Message) text() string {
+	return m.Subject + "\n\n" + strings.Join(m.Lines, "\n")
+}
+
+// Notifier sends messages to one kind of channel.
+type Notifier interface {
+	Notify(ctx context.Context, channel Channel, msg Message) error
+}
+
+// Notifiers maps channel types to the notifier that sends them. A type
+// without a notifier can't be used in rules.
+type Notifiers map[string]Notifier
+
+// NotifiersFromEnv enables Slack and Teams channels, and email channels when
+// SMTP_ADDR (host:port) is set. SMTP_FROM is required with it; SMTP_USERNAME
+// and SMTP_PASSWORD are optional.
+func NotifiersFromEnv() (Notifiers, error) {
+	chat := NewChatNotifier()
+	notifiers := Notifiers{ChannelSlack: chat, ChannelTeams: chat}
+
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return notifiers, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("SMTP_ADDR must be host:port")
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		return nil, errors.New("SMTP_FROM is required with SMTP_ADDR")
+	}
+	notifiers[ChannelEmail] = &SMTPNotifier{
+		Addr:     addr,
+		From:     from,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+	return notifiers, nil
+}
+
+// smtpTimeout bounds a whole SMTP conversation, so a server that accepts the
+// connection and stalls can't hold up the scheduler.
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier sends plain text email, upgrading to TLS when the server
+// offers STARTTLS.
+type SMTPNotifier struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, channel Channel, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(n.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range channel.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(channel, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message renders the email. Header values have line breaks removed so rule
+// names and URLs can't add headers, and the subject is encoded per RFC 2047.
+func (n *SMTPNotifier) message(channel Channel, msg Message) []byte {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", headerValue(n.From))
+	fmt.Fprintf(&body, "To: %s\r\n", headerValue(strings.Join(channel.To, ", ")))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(msg.Subject)))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(strings.Join(msg.Lines, "\n"), "\n", "\r\n"))
+	body.WriteString("\r\n")
+	return body.Bytes()
+}
+
+// headerValue replaces line breaks, which would end the header, with spaces.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+// ChatNotifier posts to Slack and Teams incoming webhooks, which both accept
+// a JSON body with a text field. Like webhooks, it won't connect to
+// non-public addresses or follow redirects.
+type ChatNotifier struct {
+	client *http.Client
+}
+
+func NewChatNotifier() *ChatNotifier {
+	return &ChatNotifier{client: soutbound.NewClient(10 * time.Second)}
+}
+
+func (n *ChatNotifier) Notify(ctx context.Context, channel Channel, msg Message) error {
+	text := msg.text()
+	if channel.Type == ChannelTeams {
+		// Teams renders markdown, where single newlines don't break lines.
+		text = strings.ReplaceAll(text, "\n", "\n\n")
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package alert
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RuleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRuleRepository(client *mongo.Client) *RuleRepository {
+	return &RuleRepository{
+		collection: client.Database("sharprenderdb").Collection("alert_rules"),
+	}
+}
+
+func (r *RuleRepository) Create(ctx context.Context, rule *Rule) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, rule)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *RuleRepository) FindOne(ctx context.Context, filter interface{}) (*Rule, error) {
+	var rule Rule
+	err := r.collection.FindOne(ctx, filter).Decode(&rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (r *RuleRepository) FindMany(ctx context.Context, filter interface{}) ([]Rule, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []Rule{}
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *RuleRepository) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+type AlertRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAlertRepository(client *mongo.Client) *AlertRepository {
+	return &AlertRepository{
+		collection: client.Database("sharprenderdb").Collection("alerts"),
+	}
+}
+
+func (r *AlertRepository) Create(ctx context.Context, alert *Alert) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, alert)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+// FindOpen returns the unresolved alert of a rule for a page, or
+// mongo.ErrNoDocuments.
+func (r *AlertRepository) FindOpen(ctx context.Context, ruleID primitive.ObjectID, url string) (*Alert, error) {
+	var alert Alert
+	err := r.collection.FindOne(ctx, bson.M{
+		"rule_id":     ruleID,
+		"url":         url,
+		"resolved_at": bson.M{"$exists": false},
+	}).Decode(&alert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &alert, nil
+}
+
+// FindMany returns up to limit matching alerts, most recently seen first.
+func (r *AlertRepository) FindMany(ctx context.Context, filter interface{}, limit int64) ([]Alert, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []Alert{}
+	if err = cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+func (r *AlertRepository) Update(ctx context.Context, alert *Alert) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": alert.ID}, alert)
+	return err
+}
+
+// DeleteForRule removes the alerts of a deleted rule.
+func (r *AlertRepository) DeleteForRule(ctx context.Context, ruleID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"rule_id": ruleID})
+	return err
+}
@@ -0,0 +1,264 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/soutbound"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// alertLogLimit caps the alerts listed at once.
+const alertLogLimit = 100
+
+// AlertService manages a project's alert rules and checks scheduled scans
+// against them. Rules hold channel addresses, so managing and listing them
+// needs editor rights; viewers can read the alerts.
+type AlertService struct {
+	rules       *RuleRepository
+	alerts      *AlertRepository
+	scans       scan.ScanStore
+	scanService *scan.ScanService
+	access      *org.AccessService
+	notifiers   Notifiers
+}
+
+func NewAlertService(rules *RuleRepository, alerts *AlertRepository, scans scan.ScanStore, scanService *scan.ScanService, access *org.AccessService, notifiers Notifiers) *AlertService {
+	return &AlertService{rules: rules, alerts: alerts, scans: scans, scanService: scanService, access: access, notifiers: notifiers}
+}
+
+// checkChannels rejects channel types this server can't send to, and chat
+// webhooks on non-public hosts.
+func (s *AlertService) checkChannels(ctx context.Context, channels []Channel) error {
+	for _, c := range channels {
+		if s.notifiers[c.Type] == nil {
+			return fmt.Errorf("%s channels aren't configured on this server", c.Type)
+		}
+		if c.URL != "" {
+			if err := soutbound.CheckURL(ctx, c.URL); err != nil {
+				return fmt.Errorf("invalid %s webhook URL: %w", c.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateRule stores a validated rule for a project the user can edit.
+func (s *AlertService) CreateRule(ctx context.Context, userID string, rule Rule) (*Rule, error) {
+	if err := s.access.Authorize(ctx, userID, rule.ProjectID, org.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	rule.ID = primitive.NilObjectID
+	rule.CreatedBy = userID
+	rule.CreatedAt = time.Now()
+	id, err := s.rules.Create(ctx, &rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	rule.ID = id
+
+	return &rule, nil
+}
+
+// GetRules lists the rules of the projects the user can edit, or of one.
+func (s *AlertService) GetRules(ctx context.Context, userID string, projectID *primitive.ObjectID) ([]Rule, error) {
+	filter, err := s.access.ScopeProject(ctx, userID, projectID, bson.M{}, org.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	return s.rules.FindMany(ctx, filter)
+}
+
+// rule loads a rule the user may manage, or mongo.ErrNoDocuments.
+func (s *AlertService) rule(ctx context.Context, userID string, id primitive.ObjectID) (*Rule, error) {
+	filter, err := s.access.Scope(ctx, userID, bson.M{"_id": id}, org.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	return s.rules.FindOne(ctx, filter)
+}
+
+// DeleteRule removes a rule with its alerts.
+func (s *AlertService) DeleteRule(ctx context.Context, userID string, id primitive.ObjectID) error {
+	rule, err := s.rule(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.rules.Delete(ctx, bson.M{"_id": rule.ID}); err != nil {
+		return err
+	}
+	return s.alerts.DeleteForRule(ctx, rule.ID)
+}
+
+// TestRule sends a test message to every channel of a rule and returns the
+// failures.
+func (s *AlertService) TestRule(ctx context.Context, userID string, id primitive.ObjectID) ([]string, error) {
+	rule, err := s.rule(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.send(ctx, rule, Message{
+		Subject: fmt.Sprintf("Sharprender alert test: %s", rule.Name),
+		Lines:   []string{"This channel will receive the regression alerts of this rule."},
+	}), nil
+}
+
+// GetAlerts returns the most recent alerts of the projects the user can see,
+// or of one. With open set, resolved alerts are left out.
+func (s *AlertService) GetAlerts(ctx context.Context, userID string, projectID *primitive.ObjectID, open bool) ([]Alert, error) {
+	filter := bson.M{}
+	if open {
+		filter["resolved_at"] = bson.M{"$exists": false}
+	}
+	filter, err := s.access.ScopeProject(ctx, userID, projectID, filter, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.alerts.FindMany(ctx, filter, alertLogLimit)
+}
+
+// Evaluate checks a scheduled project scan against the project's rules.
+// Failures are logged, as alerts never fail the scan that raised them.
+func (s *AlertService) Evaluate(ctx context.Context, current *scan.Scan) {
+	if current.ProjectID == nil {
+		return
+	}
+
+	rules, err := s.rules.FindMany(ctx, bson.M{
+		"project_id": *current.ProjectID,
+		"$or": bson.A{
+			bson.M{"url": current.URL},
+			bson.M{"url": bson.M{"$exists": false}},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to load alert rules for %s: %v", current.URL, err)
+		return
+	}
+
+	for _, rule := range rules {
+		if err := s.evaluate(ctx, rule, current); err != nil {
+			log.Printf("Failed to evaluate alert rule %s for %s: %v", rule.ID.Hex(), current.URL, err)
+		}
+	}
+}
+
+// evaluate opens, updates or resolves the rule's alert for the scanned page.
+// Only reasons the open alert doesn't already have are notified.
+func (s *AlertService) evaluate(ctx context.Context, rule Rule, current *scan.Scan) error {
+	open, err := s.alerts.FindOpen(ctx, rule.ID, current.URL)
+	if err == mongo.ErrNoDocuments {
+		open = nil
+	} else if err != nil {
+		return err
+	}
+
+	baseline, err := s.baseline(ctx, open, current)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reasons := regressions(rule, baseline, current)
+	if len(reasons) == 0 {
+		if open == nil {
+			return nil
+		}
+		open.ResolvedAt = &now
+		return s.alerts.Update(ctx, open)
+	}
+
+	if open == nil {
+		alert := Alert{
+			RuleID:      rule.ID,
+			ProjectID:   rule.ProjectID,
+			URL:         current.URL,
+			ScanID:      current.ID,
+			Reasons:     reasons,
+			Scans:       1,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		}
+		if baseline != nil {
+			alert.BaselineScanID = &baseline.ID
+		}
+		// Store the alert before notifying, so a failed insert can't send a
+		// notification the next scan sends again.
+		id, err := s.alerts.Create(ctx, &alert)
+		if err != nil {
+			return err
+		}
+		alert.ID = id
+		s.notify(ctx, rule, &alert, reasons, now)
+		return s.alerts.Update(ctx, &alert)
+	}
+
+	fresh := newReasons(open.Reasons, reasons)
+	open.ScanID = current.ID
+	open.Reasons = reasons
+	open.Scans++
+	open.LastSeenAt = now
+	if err := s.alerts.Update(ctx, open); err != nil {
+		return err
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	s.notify(ctx, rule, open, fresh, now)
+	return s.alerts.Update(ctx, open)
+}
+
+// baseline is the scan a page is compared against: the one before an open
+// alert's regression, otherwise the previous comparable scan.
+func (s *AlertService) baseline(ctx context.Context, open *Alert, current *scan.Scan) (*scan.Scan, error) {
+	if open != nil && open.BaselineScanID != nil {
+		baseline, err := s.scans.FindOne(ctx, bson.M{"_id": *open.BaselineScanID})
+		if err == nil {
+			return baseline, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+	return s.scanService.PreviousComparable(ctx, current)
+}
+
+func (s *AlertService) notify(ctx context.Context, rule Rule, alert *Alert, reasons []Reason, now time.Time) {
+	lines := make([]string, 0, len(reasons)+2)
+	for _, r := range reasons {
+		lines = append(lines, "- "+r.Message)
+	}
+	lines = append(lines, "", fmt.Sprintf("Scan %s of %s", alert.ScanID.Hex(), alert.URL))
+
+	alert.Errors = s.send(ctx, &rule, Message{
+		Subject: fmt.Sprintf("Sharprender alert: %s regressed (%s)", alert.URL, rule.Name),
+		Lines:   lines,
+	})
+	alert.NotifiedAt = &now
+}
+
+// send delivers a message to every channel of the rule and returns the
+// failures.
+func (s *AlertService) send(ctx context.Context, rule *Rule, msg Message) []string {
+	var failures []string
+	for _, c := range rule.Channels {
+		notifier := s.notifiers[c.Type]
+		if notifier == nil {
+			failures = append(failures, fmt.Sprintf("%s: not configured", c.Type))
+			continue
+		}
+		if err := notifier.Notify(ctx, c, msg); err != nil {
+			log.Printf("Failed to send %s alert for rule %s: %v", c.Type, rule.ID.Hex(), err)
+			failures = append(failures, fmt.Sprintf("%s: %v", c.Type, err))
+		}
+	}
+	return failures
+}
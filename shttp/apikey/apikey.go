@@ -0,0 +1,27 @@
+package apikey
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewAPIKeyRoutes serves key management, which needs a signed-in session: an
+// API key can't mint or revoke keys.
+func NewAPIKeyRoutes(mongoClient *mongo.Client) *chi.Mux {
+	repo := NewAPIKeyRepository(mongoClient)
+	handler := NewAPIKeyHandler(NewAPIKeyService(repo), repo)
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Post("/", handler.CreateAPIKey)
+	router.Get("/", handler.GetAPIKeys)
+	router.Delete("/{id}", handler.RevokeAPIKey)
+
+	return router
+}
+
+// NewAuthenticatorFromClient builds the API key authenticator for auth.Verifier.
+func NewAuthenticatorFromClient(mongoClient *mongo.Client) *APIKeyService {
+	return NewAPIKeyService(NewAPIKeyRepository(mongoClient))
+}
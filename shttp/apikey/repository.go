@@ -0,0 +1,76 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(client *mongo.Client) *APIKeyRepository {
+	return &APIKeyRepository{
+		collection: client.Database("sharprenderdb").Collection("api_keys"),
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *APIKeyRepository) FindOne(ctx context.Context, filter interface{}) (*APIKey, error) {
+	var key APIKey
+	err := r.collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindMany(ctx context.Context, filter interface{}) ([]APIKey, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []APIKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke marks a key of the user as revoked. It returns the number of keys
+// changed, which is 0 for unknown or already revoked keys.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID, userID string, now time.Time) (int64, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// Touch records a use of the key.
+func (r *APIKeyRepository) Touch(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": now}})
+	return err
+}
@@ -0,0 +1,276 @@
+// Package auth authenticates API requests with the Clerk session JWTs the
+// frontend sends as bearer tokens, or with personal API keys.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// leeway tolerates clock skew between Clerk and this server.
+const leeway = 5 * time.Second
+
+// Scopes an API key can be granted. Session tokens carry every scope.
+const (
+	ScopeScanCreate = "scan:create"
+	ScopeScanRead   = "scan:read"
+	// ScopeScanWrite allows archiving and deleting existing scans.
+	ScopeScanWrite = "scan:write"
+)
+
+// Scopes lists every scope an API key may be granted.
+var Scopes = []string{ScopeScanCreate, ScopeScanRead, ScopeScanWrite}
+
+// APIKeyPrefix marks bearer tokens that are API keys rather than JWTs.
+const APIKeyPrefix = "sr_"
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Principal is whoever authenticated the request. Scopes is nil for session
+// tokens, which may do anything the user can.
+type Principal struct {
+	UserID   string
+	APIKeyID string
+	Scopes   []string
+}
+
+// Session reports whether the principal signed in through Clerk rather than
+// with an API key.
+func (p Principal) Session() bool {
+	return p.APIKeyID == ""
+}
+
+// HasScope reports whether the principal may act with the given scope.
+func (p Principal) HasScope(scope string) bool {
+	if p.Session() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyAuthenticator resolves an API key to the principal it was issued to.
+// It returns an error wrapping ErrInvalidToken for unknown, revoked or
+// expired keys.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*Principal, error)
+}
+
+type contextKey struct{}
+
+// PrincipalFrom returns the authenticated principal of the request.
+func PrincipalFrom(ctx context.Context) Principal {
+	p, _ := ctx.Value(contextKey{}).(Principal)
+	return p
+}
+
+// UserID returns the authenticated user of the request, or "" outside the
+// middleware.
+func UserID(ctx context.Context) string {
+	return PrincipalFrom(ctx).UserID
+}
+
+// WithPrincipal returns a context carrying p as the authenticated principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// WithUserID returns a context carrying a session principal for userID.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return WithPrincipal(ctx, Principal{UserID: userID})
+}
+
+// Claims are the registered JWT claims we check.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// Verifier checks RS256 JWTs against a JWKS and, when set, their issuer.
+// Tokens starting with APIKeyPrefix go to the API key authenticator instead.
+type Verifier struct {
+	keys    *KeySet
+	issuer  string
+	apiKeys APIKeyAuthenticator
+}
+
+func NewVerifier(keys *KeySet, issuer string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer}
+}
+
+// NewVerifierFromEnv reads the JWKS URL from CLERK_JWKS_URL and the optional
+// expected issuer from CLERK_ISSUER.
+func NewVerifierFromEnv() (*Verifier, error) {
+	jwksURL := os.Getenv("CLERK_JWKS_URL")
+	if jwksURL == "" {
+		return nil, errors.New("CLERK_JWKS_URL is not set")
+	}
+	return NewVerifier(NewKeySet(jwksURL), os.Getenv("CLERK_ISSUER")), nil
+}
+
+// AcceptAPIKeys lets the middleware authenticate API keys with a.
+func (v *Verifier) AcceptAPIKeys(a APIKeyAuthenticator) {
+	v.apiKeys = a
+}
+
+// Verify checks the token's signature, validity window and issuer and returns
+// its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if errors.Is(err, errUnknownKey) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+// Middleware rejects requests without a valid bearer token with 401 and puts
+// the authenticated principal into the request context for UserID.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := bearerToken(r)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+
+		principal, err := v.authenticate(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			unauthorized(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to verify token", http.StatusServiceUnavailable)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), *principal)))
+	})
+}
+
+func (v *Verifier) authenticate(ctx context.Context, token string) (*Principal, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		if v.apiKeys == nil {
+			return nil, ErrInvalidToken
+		}
+		return v.apiKeys.Authenticate(ctx, token)
+	}
+
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{UserID: claims.Subject}, nil
+}
+
+// RequireScope rejects requests whose principal lacks scope with 403.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !PrincipalFrom(r.Context()).HasScope(scope) {
+				http.Error(w, "Forbidden: API key lacks the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests authenticated with an API key with 403, for
+// routes such as key management that only a signed-in user may use.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !PrincipalFrom(r.Context()).Session() {
+			http.Error(w, "Forbidden: API keys can't access this route", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// signToken builds an RS256 JWT with the header and claims given.
+func signToken(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	server := newJWKSServer(t, "k1")
+	verifier := NewVerifier(NewKeySet(server.URL), "https://clerk.example.com")
+	key := server.keys["k1"]
+	other := newRSAKey(t)
+	now := time.Now().Unix()
+
+	header := func(changes map[string]interface{}) map[string]interface{} {
+		h := map[string]interface{}{"alg": "RS256", "kid": "k1", "typ": "JWT"}
+		for k, v := range changes {
+			h[k] = v
+		}
+		return h
+	}
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "user_1", "iss": "https://clerk.example.com", "exp": now + 60, "nbf": now - 60}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", signToken(t, key, header(nil), claims(nil)), true},
+		{"expired within leeway", signToken(t, key, header(nil), claims(map[string]interface{}{"exp": now - 2})), true},
+		{"expired", signToken(t, key, header(nil), claims(map[string]interface{}{"exp": now - 60})), false},
+		{"missing exp", signToken(t, key, header(nil), claims(map[string]interface{}{"exp": nil})), false},
+		{"not yet valid within leeway", signToken(t, key, header(nil), claims(map[string]interface{}{"nbf": now + 2})), true},
+		{"not yet valid", signToken(t, key, header(nil), claims(map[string]interface{}{"nbf": now + 60})), false},
+		{"issuer mismatch", signToken(t, key, header(nil), claims(map[string]interface{}{"iss": "https://evil.example.com"})), false},
+		{"missing subject", signToken(t, key, header(nil), claims(map[string]interface{}{"sub": nil})), false},
+		{"unknown kid", signToken(t, key, header(map[string]interface{}{"kid": "k9"}), claims(nil)), false},
+		{"wrong key", signToken(t, other, header(nil), claims(nil)), false},
+		// The signature is RS256 either way; only the header is pinned.
+		{"HS256", signToken(t, key, header(map[string]interface{}{"alg": "HS256"}), claims(nil)), false},
+		{"none", signToken(t, key, header(map[string]interface{}{"alg": "none"}), claims(nil)), false},
+		{"malformed", "not.a-token", false},
+	} {
+		got, err := verifier.Verify(context.Background(), tc.token)
+		if tc.valid {
+			if err != nil || got.Subject != "user_1" {
+				t.Errorf("%s: claims %+v, err %v, want user_1", tc.name, got, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTTL is how long fetched keys are trusted before a refresh.
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits refetches triggered by unknown key IDs, so tokens
+	// with made-up kids can't hammer the JWKS endpoint.
+	jwksMinRefresh = 30 * time.Second
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// KeySet caches the RSA signing keys published at a JWKS URL.
+type KeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewKeySet(url string) *KeySet {
+	return &KeySet{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Key returns the key with the given ID, refreshing the cache when it is
+// stale or doesn't know the ID yet, as happens after a key rotation.
+func (k *KeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	age := time.Since(k.fetchedAt)
+	if key, ok := k.keys[kid]; ok && age < jwksTTL {
+		return key, nil
+	}
+	if k.keys == nil || age >= jwksMinRefresh {
+		if err := k.refresh(ctx); err != nil {
+			// Keep serving the keys we have if the endpoint is briefly down.
+			if key, ok := k.keys[kid]; ok {
+				return key, nil
+			}
+			return nil, err
+		}
+	}
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errUnknownKey
+}
+
+func (k *KeySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching JWKS: %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, j := range doc.Keys {
+		if j.Kty != "RSA" || (j.Use != "" && j.Use != "sig") {
+			continue
+		}
+		key, err := j.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key %q: %w", j.Kid, err)
+		}
+		keys[j.Kid] = key
+	}
+
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	return nil
+}
+
+func (j jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes the public halves of its keys and counts fetches.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	down    bool
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		s.keys[kid] = newRSAKey(t)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (s *jwksServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	if s.down {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+
+	keys := []jwk{}
+	for kid, key := range s.keys {
+		keys = append(keys, jwk{
+			Kid: kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (s *jwksServer) set(kid string, key *rsa.PrivateKey, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key != nil {
+		s.keys[kid] = key
+	}
+	s.down = down
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func TestKeySet(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t, "k1")
+	keys := NewKeySet(server.URL)
+
+	key, err := keys.Key(ctx, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.N.Cmp(server.keys["k1"].N) != 0 {
+		t.Fatal("Key returned a different key")
+	}
+	if _, err := keys.Key(ctx, "k1"); err != nil || server.fetchCount() != 1 {
+		t.Fatalf("cached key: err = %v after %d fetches, want 1 fetch", err, server.fetchCount())
+	}
+
+	// Unknown kids only trigger a refetch once jwksMinRefresh has passed.
+	if _, err := keys.Key(ctx, "made-up"); !errors.Is(err, errUnknownKey) {
+		t.Fatalf("unknown kid: err = %v", err)
+	}
+	if server.fetchCount() != 1 {
+		t.Errorf("unknown kid refetched within jwksMinRefresh: %d fetches", server.fetchCount())
+	}
+
+	// A rotated key is picked up by the next refetch.
+	server.set("k2", newRSAKey(t), false)
+	keys.fetchedAt = time.Now().Add(-jwksMinRefresh)
+	if _, err := keys.Key(ctx, "k2"); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+
+	// Stale keys keep working while the endpoint is down, but unknown ones
+	// don't.
+	server.set("", nil, true)
+	keys.fetchedAt = time.Now().Add(-jwksTTL)
+	if _, err := keys.Key(ctx, "k1"); err != nil {
+		t.Errorf("stale key while the JWKS is down: %v", err)
+	}
+	if _, err := keys.Key(ctx, "k3"); err == nil || errors.Is(err, errUnknownKey) {
+		t.Errorf("unknown key while the JWKS is down: err = %v, want the fetch error", err)
+	}
+}
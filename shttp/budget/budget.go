@@ -0,0 +1,21 @@
+package budget
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewBudgetRoutes(mongoClient *mongo.Client) *chi.Mux {
+	repo := NewBudgetRepository(mongoClient)
+	handler := NewBudgetHandler(repo, org.NewAccessServiceFromClient(mongoClient))
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Post("/", handler.CreateBudget)
+	router.Get("/", handler.GetBudgets)
+	router.Delete("/{id}", handler.DeleteBudget)
+
+	return router
+}
@@ -0,0 +1,119 @@
+package budget
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BudgetHandler struct {
+	repo   *BudgetRepository
+	access *org.AccessService
+}
+
+func NewBudgetHandler(repo *BudgetRepository, access *org.AccessService) *BudgetHandler {
+	return &BudgetHandler{repo: repo, access: access}
+}
+
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name       string              `json:"name"`
+		ProjectID  *primitive.ObjectID `json:"project_id"`
+		URLPattern string              `json:"url_pattern"`
+		Limits     simage.Budget       `json:"limits"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Missing name parameter", http.StatusBadRequest)
+		return
+	}
+	if err := req.Limits.Validate(); err != nil {
+		http.Error(w, "Invalid limits: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.UserID(r.Context())
+	if req.ProjectID != nil {
+		if err := h.access.Authorize(r.Context(), userID, *req.ProjectID, org.RoleEditor); err != nil {
+			org.WriteAccessError(w, err, "Failed to check project access")
+			return
+		}
+	}
+
+	budget := Budget{
+		UserID:     userID,
+		ProjectID:  req.ProjectID,
+		Name:       req.Name,
+		URLPattern: req.URLPattern,
+		Limits:     req.Limits,
+		CreatedAt:  time.Now(),
+	}
+
+	id, err := h.repo.Create(r.Context(), &budget)
+	if err != nil {
+		http.Error(w, "Failed to create budget", http.StatusInternalServerError)
+		return
+	}
+	budget.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.access.Filter(r.Context(), auth.UserID(r.Context()), org.RoleViewer)
+	if err != nil {
+		http.Error(w, "Failed to fetch budgets", http.StatusInternalServerError)
+		return
+	}
+
+	budgets, err := h.repo.FindMany(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch budgets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"budgets": budgets,
+	})
+}
+
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.access.Scope(r.Context(), auth.UserID(r.Context()), bson.M{"_id": objectID}, org.RoleEditor)
+	if err != nil {
+		http.Error(w, "Failed to delete budget", http.StatusInternalServerError)
+		return
+	}
+
+	deleted, err := h.repo.Delete(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to delete budget", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package budget
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Budget applies image limits to every scan of a URL matching URLPattern.
+// The pattern is a glob where * matches any run of characters; an empty
+// pattern matches every URL. Project budgets apply to the project's scans,
+// personal ones to the user's scans outside projects.
+type Budget struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     string              `json:"user_id" bson:"user_id"`
+	ProjectID  *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	Name       string              `json:"name" bson:"name"`
+	URLPattern string              `json:"url_pattern" bson:"url_pattern"`
+	Limits     simage.Budget       `json:"limits" bson:"limits"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// Matches reports whether the budget applies to the URL.
+func (b Budget) Matches(url string) bool {
+	if b.URLPattern == "" {
+		return true
+	}
+	parts := strings.Split(b.URLPattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, _ := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", url)
+	return matched
+}
+
+// Evaluate checks the images of a scan against the budget's limits.
+func (b Budget) Evaluate(images []simage.Image) simage.BudgetResult {
+	result := simage.EvaluateBudget(b.Name, b.Limits, images)
+	if !b.ID.IsZero() {
+		result.BudgetID = b.ID.Hex()
+	}
+	return result
+}
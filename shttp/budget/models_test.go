@@ -0,0 +1,49 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBudgetMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, url string
+		want         bool
+	}{
+		{"", "https://example.com/anything", true},
+		{"https://example.com/", "https://example.com/", true},
+		{"https://example.com/", "https://example.com/about", false},
+		{"https://example.com/*", "https://example.com/blog/post", true},
+		{"https://*.example.com/*", "https://shop.example.com/cart", true},
+		{"https://*.example.com/*", "https://example.com/cart", false},
+		{"*/checkout", "https://example.com/shop/checkout", true},
+		// Regex metacharacters in the pattern match literally.
+		{"https://example.com/search?q=*", "https://example.com/search?q=shoes", true},
+		{"https://example.com/search?q=*", "https://example.com/searchq=shoes", false},
+		{"https://example.com/a.b", "https://example.com/axb", false},
+		{"https://example.com/(new)+[x]", "https://example.com/(new)+[x]", true},
+		{"https://example.com/$1|^", "https://example.com/$1|^", true},
+	} {
+		b := Budget{URLPattern: tc.pattern}
+		if got := b.Matches(tc.url); got != tc.want {
+			t.Errorf("%q matching %q = %v, want %v", tc.pattern, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestBudgetEvaluate(t *testing.T) {
+	images := []simage.Image{{Src: "https://a.com/a.jpg", Size: 500}}
+
+	b := Budget{Name: "home", Limits: simage.Budget{MaxTotalBytes: 100}}
+	result := b.Evaluate(images)
+	if result.Name != "home" || result.BudgetID != "" || result.Passed {
+		t.Errorf("unsaved budget result = %+v", result)
+	}
+
+	b.ID = primitive.NewObjectID()
+	if result := b.Evaluate(images); result.BudgetID != b.ID.Hex() {
+		t.Errorf("budget ID = %q, want %q", result.BudgetID, b.ID.Hex())
+	}
+}
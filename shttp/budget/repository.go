@@ -0,0 +1,76 @@
+package budget
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BudgetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBudgetRepository(client *mongo.Client) *BudgetRepository {
+	return &BudgetRepository{
+		collection: client.Database("sharprenderdb").Collection("budgets"),
+	}
+}
+
+func (r *BudgetRepository) Create(ctx context.Context, budget *Budget) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, budget)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *BudgetRepository) FindMany(ctx context.Context, filter interface{}) ([]Budget, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	budgets := []Budget{}
+	if err = cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+
+	return budgets, nil
+}
+
+func (r *BudgetRepository) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// FindMatching returns the budgets whose URL pattern matches url: the
+// project's budgets for project scans, otherwise the user's personal ones.
+func (r *BudgetRepository) FindMatching(ctx context.Context, userID string, projectID *primitive.ObjectID, url string) ([]Budget, error) {
+	filter := bson.M{"user_id": userID, "project_id": nil}
+	if projectID != nil {
+		filter = bson.M{"project_id": *projectID}
+	}
+
+	budgets, err := r.FindMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []Budget
+	for _, b := range budgets {
+		if b.Matches(url) {
+			matching = append(matching, b)
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,28 @@
+package crawl
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewCrawlRoutes(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, quota *usage.UsageService) *chi.Mux {
+	handler := NewCrawlHandler(NewCrawlServiceFromClient(mongoClient, scans, artifacts, quota))
+
+	router := chi.NewRouter()
+	router.With(auth.RequireScope(auth.ScopeScanCreate)).Post("/", handler.StartCrawl)
+	router.With(auth.RequireScope(auth.ScopeScanRead)).Get("/{id}", handler.GetCrawl)
+
+	return router
+}
+
+// NewCrawlServiceFromClient builds the crawl service, which the server also
+// uses to reap crawls lost in a restart.
+func NewCrawlServiceFromClient(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, quota *usage.UsageService) *CrawlService {
+	scanService := scan.NewScanServiceFromClient(mongoClient, scans, artifacts)
+	return NewCrawlService(NewCrawlRepository(mongoClient), scanService, org.NewAccessServiceFromClient(mongoClient), quota)
+}
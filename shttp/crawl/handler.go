@@ -0,0 +1,96 @@
+package crawl
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CrawlHandler struct {
+	service *CrawlService
+}
+
+func NewCrawlHandler(service *CrawlService) *CrawlHandler {
+	return &CrawlHandler{service: service}
+}
+
+func (h *CrawlHandler) StartCrawl(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL       string              `json:"url"`
+		ProjectID *primitive.ObjectID `json:"project_id"`
+		CrawlOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		http.Error(w, "Missing URL parameter", http.StatusBadRequest)
+		return
+	}
+
+	seed, err := url.ParseRequestURI(req.URL)
+	if err != nil || seed.Host == "" {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.CrawlOptions.Validate(); err != nil {
+		http.Error(w, "Invalid crawl options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.UserID(r.Context())
+	if req.ProjectID != nil {
+		if err := h.service.access.Authorize(r.Context(), userID, *req.ProjectID, org.RoleEditor); err != nil {
+			org.WriteAccessError(w, err, "Failed to check project access")
+			return
+		}
+	}
+
+	crawl, err := h.service.StartCrawl(r.Context(), userID, req.ProjectID, req.URL, req.CrawlOptions)
+	var exceeded *usage.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		usage.WriteQuotaError(w, err, time.Now())
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to start crawl", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"crawl_id": crawl.ID.Hex()})
+}
+
+func (h *CrawlHandler) GetCrawl(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid crawl ID", http.StatusBadRequest)
+		return
+	}
+
+	crawl, err := h.service.GetCrawl(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Crawl not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch crawl", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(crawl)
+}
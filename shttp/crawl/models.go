@@ -0,0 +1,85 @@
+package crawl
+
+import (
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+type Crawl struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `json:"user_id" bson:"user_id"`
+	// ProjectID files the crawl and its scans under a project.
+	ProjectID  *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	SeedURL    string              `json:"seed_url" bson:"seed_url"`
+	Options    CrawlOptions        `json:"options" bson:"options"`
+	Status     string              `json:"status" bson:"status"`
+	Pages      []CrawlPage         `json:"pages" bson:"pages"`
+	Report     *SiteReport         `json:"report,omitempty" bson:"report,omitempty"`
+	Error      string              `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	// HeartbeatAt is refreshed while a server runs the crawl, so crawls lost
+	// in a restart can be told from running ones.
+	HeartbeatAt time.Time `json:"-" bson:"heartbeat_at"`
+}
+
+// scannedPages counts the pages that were scanned, and so used quota.
+func (c *Crawl) scannedPages() int {
+	scanned := 0
+	for _, p := range c.Pages {
+		if p.ScanID != nil {
+			scanned++
+		}
+	}
+	return scanned
+}
+
+type CrawlOptions struct {
+	MaxDepth    int              `json:"max_depth" bson:"max_depth"`
+	MaxPages    int              `json:"max_pages" bson:"max_pages"`
+	Concurrency int              `json:"concurrency" bson:"concurrency"`
+	UseSitemap  bool             `json:"use_sitemap" bson:"use_sitemap"`
+	ScanOptions scan.ScanOptions `json:"scan_options" bson:"scan_options"`
+}
+
+type CrawlPage struct {
+	URL    string              `json:"url" bson:"url"`
+	Depth  int                 `json:"depth" bson:"depth"`
+	ScanID *primitive.ObjectID `json:"scan_id,omitempty" bson:"scan_id,omitempty"`
+	Error  string              `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// SiteReport summarizes image weight across all pages of a crawl. Images are
+// deduplicated by URL, so an asset shared by many pages is counted once.
+type SiteReport struct {
+	PageCount       int                     `json:"page_count" bson:"page_count"`
+	ImageCount      int                     `json:"image_count" bson:"image_count"`
+	UniqueImages    int                     `json:"unique_images" bson:"unique_images"`
+	TotalImageBytes int64                   `json:"total_image_bytes" bson:"total_image_bytes"`
+	Pages           []PageWeight            `json:"pages" bson:"pages"`
+	SharedImages    []SharedImage           `json:"shared_images" bson:"shared_images"`
+	Duplicates      []simage.DuplicateGroup `json:"duplicates" bson:"duplicates"`
+	Findings        []simage.Finding        `json:"findings" bson:"findings"`
+}
+
+type PageWeight struct {
+	URL        string             `json:"url" bson:"url"`
+	ScanID     primitive.ObjectID `json:"scan_id" bson:"scan_id"`
+	ImageCount int                `json:"image_count" bson:"image_count"`
+	ImageBytes int64              `json:"image_bytes" bson:"image_bytes"`
+}
+
+type SharedImage struct {
+	Src   string   `json:"src" bson:"src"`
+	Size  int      `json:"size" bson:"size"`
+	Pages []string `json:"pages" bson:"pages"`
+}
@@ -0,0 +1,85 @@
+package crawl
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CrawlRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCrawlRepository(client *mongo.Client) *CrawlRepository {
+	return &CrawlRepository{
+		collection: client.Database("sharprenderdb").Collection("crawls"),
+	}
+}
+
+func (r *CrawlRepository) Create(ctx context.Context, crawl *Crawl) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, crawl)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *CrawlRepository) FindOne(ctx context.Context, filter interface{}) (*Crawl, error) {
+	var crawl Crawl
+	err := r.collection.FindOne(ctx, filter).Decode(&crawl)
+	return &crawl, err
+}
+
+// AddPage records a visited page so progress is visible while the crawl runs.
+func (r *CrawlRepository) AddPage(ctx context.Context, id primitive.ObjectID, page CrawlPage) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$push": bson.M{"pages": page}})
+	return err
+}
+
+// Heartbeat records that a server is still running the crawl.
+func (r *CrawlRepository) Heartbeat(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": StatusRunning}, bson.M{"$set": bson.M{"heartbeat_at": at}})
+	return err
+}
+
+// FindStale returns the running crawls without a heartbeat since before.
+// Crawls stored before heartbeats were recorded go by their creation time.
+func (r *CrawlRepository) FindStale(ctx context.Context, before time.Time) ([]Crawl, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status": StatusRunning,
+		"$or": bson.A{
+			bson.M{"heartbeat_at": bson.M{"$lt": before}},
+			bson.M{"heartbeat_at": bson.M{"$exists": false}, "created_at": bson.M{"$lt": before}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var crawls []Crawl
+	if err := cursor.All(ctx, &crawls); err != nil {
+		return nil, err
+	}
+	return crawls, nil
+}
+
+// Finish stores the final status of a running crawl together with its report.
+// It reports false when the crawl had already finished, so that only one
+// server settles its quota.
+func (r *CrawlRepository) Finish(ctx context.Context, crawl *Crawl) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": crawl.ID, "status": StatusRunning}, bson.M{"$set": bson.M{
+		"status":      crawl.Status,
+		"report":      crawl.Report,
+		"error":       crawl.Error,
+		"finished_at": crawl.FinishedAt,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
@@ -0,0 +1,286 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/scrawl"
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxCrawlDepth       = 5
+	maxCrawlPages       = 200
+	maxCrawlConcurrency = 4
+	crawlTimeout        = 2 * time.Hour
+
+	// A running crawl refreshes its heartbeat every crawlHeartbeat. One
+	// without a heartbeat for crawlStaleAfter was lost with the server that
+	// ran it and is failed.
+	crawlHeartbeat  = time.Minute
+	crawlStaleAfter = 5 * time.Minute
+)
+
+type CrawlService struct {
+	repo        *CrawlRepository
+	scanService *scan.ScanService
+	access      *org.AccessService
+	quota       *usage.UsageService
+}
+
+func NewCrawlService(repo *CrawlRepository, scanService *scan.ScanService, access *org.AccessService, quota *usage.UsageService) *CrawlService {
+	return &CrawlService{repo: repo, scanService: scanService, access: access, quota: quota}
+}
+
+// Validate fills in defaults and rejects limits beyond what a single crawl may use.
+func (o *CrawlOptions) Validate() error {
+	crawler := scrawl.NewCrawler()
+	if o.MaxDepth == 0 {
+		o.MaxDepth = crawler.MaxDepth
+	}
+	if o.MaxPages == 0 {
+		o.MaxPages = crawler.MaxPages
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = crawler.Concurrency
+	}
+
+	if o.MaxDepth < 0 || o.MaxDepth > maxCrawlDepth {
+		return fmt.Errorf("max_depth must be between 0 and %d", maxCrawlDepth)
+	}
+	if o.MaxPages < 1 || o.MaxPages > maxCrawlPages {
+		return fmt.Errorf("max_pages must be between 1 and %d", maxCrawlPages)
+	}
+	if o.Concurrency < 1 || o.Concurrency > maxCrawlConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d", maxCrawlConcurrency)
+	}
+	return o.ScanOptions.Validate()
+}
+
+// StartCrawl reserves max_pages scans of the user's quota, stores a running
+// crawl and processes it in the background. Scans the crawl doesn't run are
+// given back when it ends. A full quota returns a *usage.QuotaExceededError.
+func (s *CrawlService) StartCrawl(ctx context.Context, userID string, projectID *primitive.ObjectID, seedURL string, opts CrawlOptions) (*Crawl, error) {
+	crawl := &Crawl{
+		UserID:    userID,
+		ProjectID: projectID,
+		SeedURL:   seedURL,
+		Options:   opts,
+		Status:    StatusRunning,
+		Pages:     []CrawlPage{},
+		CreatedAt: time.Now(),
+	}
+	crawl.HeartbeatAt = crawl.CreatedAt
+
+	if err := s.quota.Reserve(ctx, userID, crawl.CreatedAt, opts.MaxPages); err != nil {
+		return nil, err
+	}
+	id, err := s.repo.Create(ctx, crawl)
+	if err != nil {
+		s.quota.Release(context.WithoutCancel(ctx), userID, crawl.CreatedAt, opts.MaxPages)
+		return nil, err
+	}
+	crawl.ID = id
+
+	go s.run(crawl)
+
+	return crawl, nil
+}
+
+// GetCrawl returns a crawl the user can see: their personal crawls and those
+// of projects they are a member of.
+func (s *CrawlService) GetCrawl(ctx context.Context, userID string, id primitive.ObjectID) (*Crawl, error) {
+	filter, err := s.access.Scope(ctx, userID, bson.M{"_id": id}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindOne(ctx, filter)
+}
+
+func (s *CrawlService) run(crawl *Crawl) {
+	ctx, cancel := context.WithTimeout(context.Background(), crawlTimeout)
+	defer cancel()
+	go s.heartbeat(ctx, crawl.ID)
+
+	crawler := scrawl.NewCrawler()
+	crawler.MaxDepth = crawl.Options.MaxDepth
+	crawler.MaxPages = crawl.Options.MaxPages
+	crawler.Concurrency = crawl.Options.Concurrency
+	crawler.UseSitemap = crawl.Options.UseSitemap
+
+	var mu sync.Mutex
+	var scans []scan.Scan
+
+	_, err := crawler.Crawl(ctx, crawl.SeedURL, func(ctx context.Context, pageURL string, depth int) ([]string, error) {
+		page := CrawlPage{URL: pageURL, Depth: depth}
+
+		result, err := s.scanService.RunScan(ctx, scan.ScanRequest{
+			UserID:    crawl.UserID,
+			ProjectID: crawl.ProjectID,
+			URL:       pageURL,
+			Options:   crawl.Options.ScanOptions,
+			CrawlID:   &crawl.ID,
+		})
+		if err != nil {
+			page.Error = err.Error()
+		} else {
+			page.ScanID = &result.ID
+			mu.Lock()
+			scans = append(scans, *result)
+			mu.Unlock()
+		}
+
+		if err := s.repo.AddPage(ctx, crawl.ID, page); err != nil {
+			log.Printf("Failed to record crawl page %s: %v", pageURL, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return result.Links, nil
+	})
+
+	finishedAt := time.Now()
+	crawl.FinishedAt = &finishedAt
+	crawl.Report = buildSiteReport(scans)
+	crawl.Status = StatusCompleted
+	if err != nil {
+		crawl.Status = StatusFailed
+		crawl.Error = err.Error()
+	}
+
+	s.finish(context.Background(), crawl, len(scans))
+}
+
+// finish stores the outcome of a crawl and gives back the quota of the pages
+// it didn't scan, unless the crawl had already been finished elsewhere.
+func (s *CrawlService) finish(ctx context.Context, crawl *Crawl, scanned int) {
+	finished, err := s.repo.Finish(ctx, crawl)
+	if err != nil {
+		log.Printf("Failed to finish crawl %s: %v", crawl.ID.Hex(), err)
+		return
+	}
+	if !finished {
+		log.Printf("Crawl %s had already finished", crawl.ID.Hex())
+		return
+	}
+	if unused := crawl.Options.MaxPages - scanned; unused > 0 {
+		s.quota.Release(ctx, crawl.UserID, crawl.CreatedAt, unused)
+	}
+}
+
+// heartbeat refreshes the crawl's heartbeat until ctx is done.
+func (s *CrawlService) heartbeat(ctx context.Context, id primitive.ObjectID) {
+	ticker := time.NewTicker(crawlHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.repo.Heartbeat(ctx, id, now); err != nil {
+				log.Printf("Failed to record heartbeat of crawl %s: %v", id.Hex(), err)
+			}
+		}
+	}
+}
+
+// ReapStale fails crawls whose server stopped while running them, such as in
+// a restart, and gives back their unscanned quota. It checks at start and
+// then every crawlHeartbeat, and blocks until ctx is cancelled.
+func (s *CrawlService) ReapStale(ctx context.Context) {
+	ticker := time.NewTicker(crawlHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		if err := s.failStale(ctx, time.Now()); err != nil {
+			log.Printf("Failed to reap stale crawls: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *CrawlService) failStale(ctx context.Context, now time.Time) error {
+	stale, err := s.repo.FindStale(ctx, now.Add(-crawlStaleAfter))
+	if err != nil {
+		return err
+	}
+
+	for i := range stale {
+		crawl := &stale[i]
+		crawl.Status = StatusFailed
+		crawl.Error = "the server running the crawl stopped"
+		crawl.FinishedAt = &now
+		s.finish(ctx, crawl, crawl.scannedPages())
+	}
+	return nil
+}
+
+// buildSiteReport deduplicates images shared across pages, groups the same
+// picture served under different URLs and ranks pages by the bytes of images
+// they load.
+func buildSiteReport(scans []scan.Scan) *SiteReport {
+	report := &SiteReport{
+		PageCount:    len(scans),
+		Pages:        []PageWeight{},
+		SharedImages: []SharedImage{},
+	}
+
+	imagesBySrc := make(map[string]*SharedImage)
+	var order []string
+	var siteImages []simage.Image
+
+	for _, sc := range scans {
+		weight := PageWeight{URL: sc.URL, ScanID: sc.ID}
+		for _, img := range sc.Images {
+			weight.ImageCount++
+			weight.ImageBytes += int64(img.Size)
+			report.ImageCount++
+
+			shared, ok := imagesBySrc[img.Src]
+			if !ok {
+				shared = &SharedImage{Src: img.Src, Size: img.Size}
+				imagesBySrc[img.Src] = shared
+				order = append(order, img.Src)
+				siteImages = append(siteImages, img)
+				report.TotalImageBytes += int64(img.Size)
+			}
+			if len(shared.Pages) == 0 || shared.Pages[len(shared.Pages)-1] != sc.URL {
+				shared.Pages = append(shared.Pages, sc.URL)
+			}
+		}
+		report.Pages = append(report.Pages, weight)
+	}
+
+	report.UniqueImages = len(imagesBySrc)
+	report.Duplicates = simage.GroupDuplicates(siteImages)
+	report.Findings = simage.AnalyzeFindings(siteImages)
+	for _, src := range order {
+		if shared := imagesBySrc[src]; len(shared.Pages) > 1 {
+			report.SharedImages = append(report.SharedImages, *shared)
+		}
+	}
+
+	sort.SliceStable(report.Pages, func(i, j int) bool {
+		return report.Pages[i].ImageBytes > report.Pages[j].ImageBytes
+	})
+	sort.SliceStable(report.SharedImages, func(i, j int) bool {
+		return len(report.SharedImages[i].Pages) > len(report.SharedImages[j].Pages)
+	})
+
+	return report
+}
@@ -0,0 +1,153 @@
+package shttp
+
+import (
+	"context"
+
+	"github.com/voage/sharprender-api/db"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations are the schema changes applied at startup, oldest first. Append
+// new migrations with the next version; never renumber or edit applied ones.
+var Migrations = []db.Migration{
+	{
+		Version:     1,
+		Description: "index scans and scan images",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			err := db.CreateIndexes(ctx, database, "scans",
+				mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "schedule_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+				mongo.IndexModel{Keys: bson.D{{Key: "crawl_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+			)
+			if err != nil {
+				return err
+			}
+			return db.CreateIndexes(ctx, database, "scan_images",
+				mongo.IndexModel{Keys: bson.D{{Key: "scan_id", Value: 1}, {Key: "index", Value: 1}}, Options: options.Index().SetUnique(true)},
+				mongo.IndexModel{Keys: bson.D{{Key: "scan_id", Value: 1}, {Key: "size", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "scan_id", Value: 1}, {Key: "network.load_time", Value: 1}}},
+			)
+		},
+	},
+	{
+		Version:     2,
+		Description: "move embedded scan images to scan_images and backfill summaries",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return scan.MoveEmbeddedImages(ctx, database.Client())
+		},
+	},
+	{
+		Version:     3,
+		Description: "index crawls, schedules, API keys, usage, share links, organizations and budgets",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			indexes := map[string][]mongo.IndexModel{
+				"crawls": {
+					{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+					{Keys: bson.D{{Key: "status", Value: 1}}},
+				},
+				"schedules": {
+					{Keys: bson.D{{Key: "enabled", Value: 1}, {Key: "next_run_at", Value: 1}}},
+					{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				},
+				"api_keys": {
+					{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+					{Keys: bson.D{{Key: "user_id", Value: 1}}},
+				},
+				"usage": {
+					{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "period", Value: 1}, {Key: "start", Value: 1}}, Options: options.Index().SetUnique(true)},
+				},
+				"share_links": {
+					{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+					{Keys: bson.D{{Key: "scan_id", Value: 1}}},
+				},
+				"organizations": {
+					{Keys: bson.D{{Key: "members.user_id", Value: 1}}},
+				},
+				"projects": {
+					{Keys: bson.D{{Key: "org_id", Value: 1}}},
+				},
+				"budgets": {
+					{Keys: bson.D{{Key: "user_id", Value: 1}}},
+					{Keys: bson.D{{Key: "project_id", Value: 1}}},
+				},
+			}
+			return db.CreateCollectionIndexes(ctx, database, indexes)
+		},
+	},
+	{
+		Version:     4,
+		Description: "stamp schema_version on scans",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Unversioned scans have the version 1 layout, whatever
+			// scan.SchemaVersion has become since.
+			_, err := database.Collection("scans").UpdateMany(ctx,
+				bson.M{"schema_version": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"schema_version": 1}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "expire share links, old usage counters and purge records",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return db.CreateCollectionIndexes(ctx, database, map[string][]mongo.IndexModel{
+				// Links are useless once expired.
+				"share_links": {{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}},
+				// Quotas only read the current day and month.
+				"usage":  {{Keys: bson.D{{Key: "updated_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(400 * 24 * 60 * 60)}},
+				"purges": {{Keys: bson.D{{Key: "purged_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(365 * 24 * 60 * 60)}},
+			})
+		},
+	},
+	{
+		Version:     6,
+		Description: "index webhooks and their deliveries",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			indexes := map[string][]mongo.IndexModel{
+				"webhooks": {
+					{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "project_id", Value: 1}}},
+					{Keys: bson.D{{Key: "project_id", Value: 1}}},
+				},
+				"webhook_deliveries": {
+					// The dispatcher claims due deliveries.
+					{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+					{Keys: bson.D{{Key: "endpoint_id", Value: 1}, {Key: "created_at", Value: -1}}},
+				},
+			}
+			return db.CreateCollectionIndexes(ctx, database, indexes)
+		},
+	},
+	{
+		Version:     7,
+		Description: "index alert rules and alerts",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			indexes := map[string][]mongo.IndexModel{
+				"alert_rules": {
+					{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "url", Value: 1}}},
+				},
+				"alerts": {
+					// Every scheduled scan looks up the open alert of each rule.
+					{Keys: bson.D{{Key: "rule_id", Value: 1}, {Key: "url", Value: 1}, {Key: "resolved_at", Value: 1}}},
+					{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+				},
+			}
+			return db.CreateCollectionIndexes(ctx, database, indexes)
+		},
+	},
+	{
+		Version:     8,
+		Description: "expire webhook deliveries",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Deliveries finish within hours; the log is kept for a month.
+			return db.CreateIndexes(ctx, database, "webhook_deliveries", mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+			})
+		},
+	},
+}
@@ -0,0 +1,162 @@
+package org
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound hides projects and organizations the user isn't a member of.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden means the user is a member but their role is too low.
+	ErrForbidden = errors.New("insufficient role")
+)
+
+// AccessService decides which user-owned and project-owned documents a user
+// may see or change. Documents carry a user_id and, when they belong to a
+// project, a project_id; personal documents have no project_id.
+type AccessService struct {
+	orgs     orgReader
+	projects projectReader
+}
+
+// The parts of OrgRepository and ProjectRepository access checks read.
+type (
+	orgReader interface {
+		FindOne(ctx context.Context, filter interface{}) (*Organization, error)
+		FindByMember(ctx context.Context, userID string) ([]Organization, error)
+	}
+	projectReader interface {
+		FindOne(ctx context.Context, filter interface{}) (*Project, error)
+		FindMany(ctx context.Context, filter interface{}) ([]Project, error)
+	}
+)
+
+func NewAccessService(orgs orgReader, projects projectReader) *AccessService {
+	return &AccessService{orgs: orgs, projects: projects}
+}
+
+func NewAccessServiceFromClient(client *mongo.Client) *AccessService {
+	return NewAccessService(NewOrgRepository(client), NewProjectRepository(client))
+}
+
+// NewPersonalAccessService returns an AccessService for servers running
+// without MongoDB. There are no organizations, so users reach only their
+// personal documents and every project is ErrNotFound.
+func NewPersonalAccessService() *AccessService {
+	return NewAccessService(noOrgs{}, noProjects{})
+}
+
+type noOrgs struct{}
+
+func (noOrgs) FindOne(ctx context.Context, filter interface{}) (*Organization, error) {
+	return nil, mongo.ErrNoDocuments
+}
+
+func (noOrgs) FindByMember(ctx context.Context, userID string) ([]Organization, error) {
+	return nil, nil
+}
+
+type noProjects struct{}
+
+func (noProjects) FindOne(ctx context.Context, filter interface{}) (*Project, error) {
+	return nil, mongo.ErrNoDocuments
+}
+
+func (noProjects) FindMany(ctx context.Context, filter interface{}) ([]Project, error) {
+	return nil, nil
+}
+
+// Authorize checks that the user has at least the required role on the
+// project. It returns ErrNotFound for projects outside the user's
+// organizations and ErrForbidden when the role is too low.
+func (s *AccessService) Authorize(ctx context.Context, userID string, projectID primitive.ObjectID, required Role) error {
+	project, err := s.projects.FindOne(ctx, bson.M{"_id": projectID})
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	org, err := s.orgs.FindOne(ctx, bson.M{"_id": project.OrgID, "members.user_id": userID})
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !org.Role(userID).Allows(required) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ProjectIDs returns the projects on which the user has at least the required role.
+func (s *AccessService) ProjectIDs(ctx context.Context, userID string, required Role) ([]primitive.ObjectID, error) {
+	orgs, err := s.orgs.FindByMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgIDs := []primitive.ObjectID{}
+	for _, o := range orgs {
+		if o.Role(userID).Allows(required) {
+			orgIDs = append(orgIDs, o.ID)
+		}
+	}
+	if len(orgIDs) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+
+	projects, err := s.projects.FindMany(ctx, bson.M{"org_id": bson.M{"$in": orgIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(projects))
+	for i, p := range projects {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+// Filter matches the user's personal documents and those of every project on
+// which the user has at least the required role.
+func (s *AccessService) Filter(ctx context.Context, userID string, required Role) (bson.M, error) {
+	projectIDs, err := s.ProjectIDs(ctx, userID, required)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{"user_id": userID, "project_id": nil},
+		bson.M{"project_id": bson.M{"$in": projectIDs}},
+	}}, nil
+}
+
+// Scope narrows filter to the documents the user may access with the role.
+func (s *AccessService) Scope(ctx context.Context, userID string, filter bson.M, required Role) (bson.M, error) {
+	access, err := s.Filter(ctx, userID, required)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$and": bson.A{filter, access}}, nil
+}
+
+// ScopeProject narrows filter to one project after checking the user's role
+// on it, or to the user's accessible documents when projectID is nil.
+func (s *AccessService) ScopeProject(ctx context.Context, userID string, projectID *primitive.ObjectID, filter bson.M, required Role) (bson.M, error) {
+	if projectID == nil {
+		return s.Scope(ctx, userID, filter, required)
+	}
+	if err := s.Authorize(ctx, userID, *projectID, required); err != nil {
+		return nil, err
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{"project_id": *projectID}}}, nil
+}
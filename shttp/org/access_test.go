@@ -0,0 +1,188 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/squery"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// find returns the documents matching filter, the way Mongo would.
+func find[T any](t *testing.T, docs []T, filter interface{}) []T {
+	query, err := squery.Normalize(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []T
+	for _, d := range docs {
+		doc, err := squery.Normalize(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := squery.Match(doc, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+type fakeOrgs struct {
+	t    *testing.T
+	orgs []Organization
+}
+
+func (f fakeOrgs) FindOne(ctx context.Context, filter interface{}) (*Organization, error) {
+	found := find(f.t, f.orgs, filter)
+	if len(found) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &found[0], nil
+}
+
+func (f fakeOrgs) FindByMember(ctx context.Context, userID string) ([]Organization, error) {
+	return find(f.t, f.orgs, bson.M{"members.user_id": userID}), nil
+}
+
+type fakeProjects struct {
+	t        *testing.T
+	projects []Project
+}
+
+func (f fakeProjects) FindOne(ctx context.Context, filter interface{}) (*Project, error) {
+	found := find(f.t, f.projects, filter)
+	if len(found) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &found[0], nil
+}
+
+func (f fakeProjects) FindMany(ctx context.Context, filter interface{}) ([]Project, error) {
+	return find(f.t, f.projects, filter), nil
+}
+
+// document is a scan, schedule or other document owned by a user and maybe a
+// project.
+type document struct {
+	Name      string              `bson:"name"`
+	UserID    string              `bson:"user_id"`
+	ProjectID *primitive.ObjectID `bson:"project_id,omitempty"`
+}
+
+func TestAccess(t *testing.T) {
+	ctx := context.Background()
+	acme := Organization{ID: primitive.NewObjectID(), Members: []Member{
+		{UserID: "owner", Role: RoleOwner},
+		{UserID: "editor", Role: RoleEditor},
+		{UserID: "viewer", Role: RoleViewer},
+	}}
+	other := Organization{ID: primitive.NewObjectID(), Members: []Member{{UserID: "outsider", Role: RoleOwner}}}
+	site := Project{ID: primitive.NewObjectID(), OrgID: acme.ID}
+	hidden := Project{ID: primitive.NewObjectID(), OrgID: other.ID}
+	access := NewAccessService(fakeOrgs{t, []Organization{acme, other}}, fakeProjects{t, []Project{site, hidden}})
+
+	docs := []document{
+		{Name: "editor's personal", UserID: "editor"},
+		{Name: "viewer's personal", UserID: "viewer"},
+		{Name: "site, by editor", UserID: "editor", ProjectID: &site.ID},
+		{Name: "site, by a former member", UserID: "gone", ProjectID: &site.ID},
+		{Name: "hidden, by editor", UserID: "editor", ProjectID: &hidden.ID},
+	}
+	names := func(found []document) []string {
+		var names []string
+		for _, d := range found {
+			names = append(names, d.Name)
+		}
+		return names
+	}
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, tc := range []struct {
+		user     string
+		required Role
+		want     []string
+	}{
+		{"viewer", RoleViewer, []string{"viewer's personal", "site, by editor", "site, by a former member"}},
+		// Viewers keep their personal documents but no project ones.
+		{"viewer", RoleEditor, []string{"viewer's personal"}},
+		// A document filed under a project follows the project, not its
+		// author.
+		{"editor", RoleEditor, []string{"editor's personal", "site, by editor", "site, by a former member"}},
+		{"outsider", RoleViewer, []string{"hidden, by editor"}},
+		{"stranger", RoleViewer, nil},
+	} {
+		filter, err := access.Filter(ctx, tc.user, tc.required)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(find(t, docs, filter)); !equal(got, tc.want) {
+			t.Errorf("Filter(%s, %s) matches %v, want %v", tc.user, tc.required, got, tc.want)
+		}
+	}
+
+	scoped, err := access.Scope(ctx, "editor", bson.M{"name": bson.M{"$in": bson.A{"hidden, by editor", "site, by editor"}}}, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(find(t, docs, scoped)); !equal(got, []string{"site, by editor"}) {
+		t.Errorf("Scope matches %v, want only the visible document", got)
+	}
+
+	for _, tc := range []struct {
+		user     string
+		project  primitive.ObjectID
+		required Role
+		want     error
+	}{
+		{"owner", site.ID, RoleOwner, nil},
+		{"editor", site.ID, RoleEditor, nil},
+		{"editor", site.ID, RoleOwner, ErrForbidden},
+		{"viewer", site.ID, RoleEditor, ErrForbidden},
+		{"outsider", site.ID, RoleViewer, ErrNotFound},
+		{"editor", primitive.NewObjectID(), RoleViewer, ErrNotFound},
+	} {
+		if err := access.Authorize(ctx, tc.user, tc.project, tc.required); !errors.Is(err, tc.want) {
+			t.Errorf("Authorize(%s, %s) = %v, want %v", tc.user, tc.required, err, tc.want)
+		}
+	}
+
+	if _, err := access.ScopeProject(ctx, "viewer", &site.ID, bson.M{}, RoleEditor); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ScopeProject let a viewer edit: %v", err)
+	}
+	scoped, err = access.ScopeProject(ctx, "viewer", &site.ID, bson.M{}, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(find(t, docs, scoped)); !equal(got, []string{"site, by editor", "site, by a former member"}) {
+		t.Errorf("ScopeProject matches %v, want the project's documents", got)
+	}
+
+	personal := NewPersonalAccessService()
+	filter, err := personal.Filter(ctx, "editor", RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(find(t, docs, filter)); !equal(got, []string{"editor's personal"}) {
+		t.Errorf("personal Filter matches %v, want only personal documents", got)
+	}
+	if err := personal.Authorize(ctx, "editor", site.ID, RoleViewer); !errors.Is(err, ErrNotFound) {
+		t.Errorf("personal Authorize = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,198 @@
+package org
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrgHandler struct {
+	service *OrgService
+	repo    *OrgRepository
+}
+
+func NewOrgHandler(service *OrgService, repo *OrgRepository) *OrgHandler {
+	return &OrgHandler{service: service, repo: repo}
+}
+
+func (h *OrgHandler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Missing name parameter", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.service.CreateOrg(r.Context(), auth.UserID(r.Context()), req.Name)
+	if err != nil {
+		http.Error(w, "Failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+func (h *OrgHandler) GetOrgs(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.repo.FindByMember(r.Context(), auth.UserID(r.Context()))
+	if err != nil {
+		http.Error(w, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"organizations": orgs,
+	})
+}
+
+// SetMember adds a member or changes their role.
+func (h *OrgHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	orgID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var member Member
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if member.UserID == "" {
+		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+		return
+	}
+	if err := member.Role.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.service.SetMember(r.Context(), auth.UserID(r.Context()), orgID, member)
+	if errors.Is(err, ErrLastOwner) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		WriteAccessError(w, err, "Failed to update members")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RemoveMember(r.Context(), auth.UserID(r.Context()), orgID, chi.URLParam(r, "userID"))
+	if errors.Is(err, ErrLastOwner) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		WriteAccessError(w, err, "Failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetRetention overrides how long the organization's scans are kept. DELETE
+// restores the server default.
+func (h *OrgHandler) SetRetention(w http.ResponseWriter, r *http.Request) {
+	orgID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var retention *Retention
+	if r.Method != http.MethodDelete {
+		retention = &Retention{}
+		if err := json.NewDecoder(r.Body).Decode(retention); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := retention.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	org, err := h.service.SetRetention(r.Context(), auth.UserID(r.Context()), orgID, retention)
+	if err != nil {
+		WriteAccessError(w, err, "Failed to update retention")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+func (h *OrgHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	orgID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name string   `json:"name"`
+		URLs []string `json:"urls"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Missing name parameter", http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.service.CreateProject(r.Context(), auth.UserID(r.Context()), orgID, req.Name, req.URLs)
+	if err != nil {
+		WriteAccessError(w, err, "Failed to create project")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(project)
+}
+
+func (h *OrgHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	orgID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return
+	}
+
+	projects, err := h.service.GetProjects(r.Context(), auth.UserID(r.Context()), orgID)
+	if err != nil {
+		WriteAccessError(w, err, "Failed to fetch projects")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"projects": projects,
+	})
+}
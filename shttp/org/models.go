@@ -0,0 +1,96 @@
+package org
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Role is a member's level of access to every project of an organization.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// Allows reports whether the role grants at least the required one.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+func (r Role) Validate() error {
+	if _, ok := roleRank[r]; !ok {
+		return fmt.Errorf("unknown role %q", r)
+	}
+	return nil
+}
+
+// Organization is a team whose members share its projects' scans, budgets and
+// schedules.
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `json:"name" bson:"name"`
+	Members   []Member           `json:"members" bson:"members"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	// Retention overrides the server's default retention for the
+	// organization's project scans.
+	Retention *Retention `json:"retention,omitempty" bson:"retention,omitempty"`
+}
+
+// Retention says how long scans are kept. After DetailDays a scan's images
+// and optimized artifacts are purged, leaving its summary; after SummaryDays
+// the scan is deleted. Zero keeps them forever.
+type Retention struct {
+	DetailDays  int `json:"detail_days" bson:"detail_days"`
+	SummaryDays int `json:"summary_days" bson:"summary_days"`
+}
+
+func (r Retention) Validate() error {
+	if r.DetailDays < 0 || r.SummaryDays < 0 {
+		return fmt.Errorf("retention days must not be negative")
+	}
+	if r.SummaryDays > 0 && (r.DetailDays == 0 || r.DetailDays > r.SummaryDays) {
+		return fmt.Errorf("detail_days must be between 1 and summary_days when scans are deleted")
+	}
+	return nil
+}
+
+type Member struct {
+	UserID string `json:"user_id" bson:"user_id"`
+	Role   Role   `json:"role" bson:"role"`
+}
+
+// Role returns the user's role in the organization, or "" for non-members.
+func (o Organization) Role(userID string) Role {
+	for _, m := range o.Members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
+
+func (o Organization) owners() int {
+	n := 0
+	for _, m := range o.Members {
+		if m.Role == RoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+// Project groups the URLs an organization tracks. Scans, budgets and
+// schedules with its ID belong to it rather than to a single user.
+type Project struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID `json:"org_id" bson:"org_id"`
+	Name      string             `json:"name" bson:"name"`
+	URLs      []string           `json:"urls" bson:"urls"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,26 @@
+package org
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewOrgRoutes(mongoClient *mongo.Client) *chi.Mux {
+	repo := NewOrgRepository(mongoClient)
+	service := NewOrgService(repo, NewProjectRepository(mongoClient))
+	handler := NewOrgHandler(service, repo)
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Post("/", handler.CreateOrg)
+	router.Get("/", handler.GetOrgs)
+	router.Put("/{id}/members", handler.SetMember)
+	router.Delete("/{id}/members/{userID}", handler.RemoveMember)
+	router.Put("/{id}/retention", handler.SetRetention)
+	router.Delete("/{id}/retention", handler.SetRetention)
+	router.Post("/{id}/projects", handler.CreateProject)
+	router.Get("/{id}/projects", handler.GetProjects)
+
+	return router
+}
@@ -0,0 +1,138 @@
+package org
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrgRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrgRepository(client *mongo.Client) *OrgRepository {
+	return &OrgRepository{
+		collection: client.Database("sharprenderdb").Collection("organizations"),
+	}
+}
+
+func (r *OrgRepository) Create(ctx context.Context, org *Organization) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, org)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *OrgRepository) FindOne(ctx context.Context, filter interface{}) (*Organization, error) {
+	var org Organization
+	err := r.collection.FindOne(ctx, filter).Decode(&org)
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// FindByMember returns the organizations the user belongs to.
+func (r *OrgRepository) FindByMember(ctx context.Context, userID string) ([]Organization, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"members.user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []Organization{}
+	if err = cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// FindMany returns the matching organizations, oldest first.
+func (r *OrgRepository) FindMany(ctx context.Context, filter interface{}) ([]Organization, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []Organization{}
+	if err = cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// SetRetention sets the retention of an organization, or removes it for nil
+// so the server default applies again.
+func (r *OrgRepository) SetRetention(ctx context.Context, id primitive.ObjectID, retention *Retention) error {
+	update := bson.M{"$set": bson.M{"retention": retention}}
+	if retention == nil {
+		update = bson.M{"$unset": bson.M{"retention": ""}}
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// SetMembers replaces the member list of an organization.
+func (r *OrgRepository) SetMembers(ctx context.Context, id primitive.ObjectID, members []Member) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"members": members}})
+	return err
+}
+
+type ProjectRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProjectRepository(client *mongo.Client) *ProjectRepository {
+	return &ProjectRepository{
+		collection: client.Database("sharprenderdb").Collection("projects"),
+	}
+}
+
+func (r *ProjectRepository) Create(ctx context.Context, project *Project) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, project)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *ProjectRepository) FindOne(ctx context.Context, filter interface{}) (*Project, error) {
+	var project Project
+	err := r.collection.FindOne(ctx, filter).Decode(&project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+func (r *ProjectRepository) FindMany(ctx context.Context, filter interface{}) ([]Project, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+	if err = cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
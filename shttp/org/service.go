@@ -0,0 +1,181 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrLastOwner = errors.New("an organization needs at least one owner")
+
+type OrgService struct {
+	orgs     *OrgRepository
+	projects *ProjectRepository
+}
+
+func NewOrgService(orgs *OrgRepository, projects *ProjectRepository) *OrgService {
+	return &OrgService{orgs: orgs, projects: projects}
+}
+
+// CreateOrg creates an organization with the user as its only owner.
+func (s *OrgService) CreateOrg(ctx context.Context, userID, name string) (*Organization, error) {
+	org := Organization{
+		Name:      name,
+		Members:   []Member{{UserID: userID, Role: RoleOwner}},
+		CreatedAt: time.Now(),
+	}
+
+	id, err := s.orgs.Create(ctx, &org)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	org.ID = id
+
+	return &org, nil
+}
+
+// member loads an organization the user belongs to with at least the required role.
+func (s *OrgService) member(ctx context.Context, userID string, orgID primitive.ObjectID, required Role) (*Organization, error) {
+	org, err := s.orgs.FindOne(ctx, bson.M{"_id": orgID, "members.user_id": userID})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !org.Role(userID).Allows(required) {
+		return nil, ErrForbidden
+	}
+	return org, nil
+}
+
+// SetMember adds a member or changes their role. Only owners manage members.
+func (s *OrgService) SetMember(ctx context.Context, actorID string, orgID primitive.ObjectID, member Member) (*Organization, error) {
+	if err := member.Role.Validate(); err != nil {
+		return nil, err
+	}
+	org, err := s.member(ctx, actorID, orgID, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for i, m := range org.Members {
+		if m.UserID == member.UserID {
+			org.Members[i].Role = member.Role
+			found = true
+		}
+	}
+	if !found {
+		org.Members = append(org.Members, member)
+	}
+	if org.owners() == 0 {
+		return nil, ErrLastOwner
+	}
+
+	if err := s.orgs.SetMembers(ctx, org.ID, org.Members); err != nil {
+		return nil, fmt.Errorf("failed to update members: %w", err)
+	}
+	return org, nil
+}
+
+// RemoveMember removes a user from the organization. Owners can remove
+// anyone; every member can leave.
+func (s *OrgService) RemoveMember(ctx context.Context, actorID string, orgID primitive.ObjectID, userID string) error {
+	required := RoleOwner
+	if actorID == userID {
+		required = RoleViewer
+	}
+	org, err := s.member(ctx, actorID, orgID, required)
+	if err != nil {
+		return err
+	}
+
+	members := []Member{}
+	for _, m := range org.Members {
+		if m.UserID != userID {
+			members = append(members, m)
+		}
+	}
+	if len(members) == len(org.Members) {
+		return ErrNotFound
+	}
+	org.Members = members
+	if org.owners() == 0 {
+		return ErrLastOwner
+	}
+
+	return s.orgs.SetMembers(ctx, org.ID, org.Members)
+}
+
+// SetRetention overrides the organization's retention, or restores the server
+// default for nil. Only owners may.
+func (s *OrgService) SetRetention(ctx context.Context, userID string, orgID primitive.ObjectID, retention *Retention) (*Organization, error) {
+	if retention != nil {
+		if err := retention.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	org, err := s.member(ctx, userID, orgID, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.orgs.SetRetention(ctx, org.ID, retention); err != nil {
+		return nil, fmt.Errorf("failed to update retention: %w", err)
+	}
+	org.Retention = retention
+	return org, nil
+}
+
+// CreateProject adds a project to the organization; editors and owners may.
+func (s *OrgService) CreateProject(ctx context.Context, userID string, orgID primitive.ObjectID, name string, urls []string) (*Project, error) {
+	if _, err := s.member(ctx, userID, orgID, RoleEditor); err != nil {
+		return nil, err
+	}
+	if urls == nil {
+		urls = []string{}
+	}
+
+	project := Project{
+		OrgID:     orgID,
+		Name:      name,
+		URLs:      urls,
+		CreatedAt: time.Now(),
+	}
+
+	id, err := s.projects.Create(ctx, &project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+	project.ID = id
+
+	return &project, nil
+}
+
+// GetProjects lists the organization's projects for any member.
+func (s *OrgService) GetProjects(ctx context.Context, userID string, orgID primitive.ObjectID) ([]Project, error) {
+	if _, err := s.member(ctx, userID, orgID, RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.projects.FindMany(ctx, bson.M{"org_id": orgID})
+}
+
+// WriteAccessError answers a failed access check with 404 or 403, and any
+// other error with 500 and msg.
+func WriteAccessError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Forbidden: your role doesn't allow this", http.StatusForbidden)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
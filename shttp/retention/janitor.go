@@ -0,0 +1,221 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	sweepInterval = time.Hour
+	batchSize     = 100
+)
+
+// Janitor enforces retention: it strips the images and artifacts of scans
+// older than their detail retention and deletes scans older than their
+// summary retention, recording every purge. Running it on several servers at
+// once is harmless, as each step can be repeated.
+type Janitor struct {
+	scans     scan.ScanStore
+	artifacts sblob.Store
+	shares    shareDeleter
+	orgs      orgFinder
+	projects  projectFinder
+	purges    purgeRecorder
+	defaults  org.Retention
+}
+
+// The janitor's view of the repositories it uses besides the scan store.
+type (
+	shareDeleter interface {
+		DeleteForScan(ctx context.Context, scanID primitive.ObjectID) error
+	}
+	orgFinder interface {
+		FindMany(ctx context.Context, filter interface{}) ([]org.Organization, error)
+	}
+	projectFinder interface {
+		FindMany(ctx context.Context, filter interface{}) ([]org.Project, error)
+	}
+	purgeRecorder interface {
+		Create(ctx context.Context, purge *Purge) error
+	}
+)
+
+func NewJanitor(scans scan.ScanStore, artifacts sblob.Store, shares shareDeleter, orgs orgFinder, projects projectFinder, purges purgeRecorder, defaults org.Retention) *Janitor {
+	return &Janitor{scans: scans, artifacts: artifacts, shares: shares, orgs: orgs, projects: projects, purges: purges, defaults: defaults}
+}
+
+// Run blocks until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.sweep(ctx, time.Now()); err != nil {
+			log.Printf("Retention sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scopedRetention is the retention that applies to the scans matching filter.
+type scopedRetention struct {
+	filter    bson.M
+	retention org.Retention
+}
+
+// policies splits scans by the retention that applies to them: each
+// organization with its own retention covers its projects' scans, and the
+// default covers personal scans and every other project.
+func (j *Janitor) policies(ctx context.Context) ([]scopedRetention, error) {
+	orgs, err := j.orgs.FindMany(ctx, bson.M{"retention": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organizations: %w", err)
+	}
+
+	var policies []scopedRetention
+	overridden := bson.A{}
+	for _, o := range orgs {
+		projects, err := j.projects.FindMany(ctx, bson.M{"org_id": o.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load projects: %w", err)
+		}
+		if len(projects) == 0 {
+			continue
+		}
+
+		ids := make(bson.A, len(projects))
+		for i, p := range projects {
+			ids[i] = p.ID
+		}
+		overridden = append(overridden, ids...)
+		policies = append(policies, scopedRetention{filter: bson.M{"project_id": bson.M{"$in": ids}}, retention: *o.Retention})
+	}
+
+	return append(policies, scopedRetention{
+		filter:    bson.M{"project_id": bson.M{"$nin": overridden}},
+		retention: j.defaults,
+	}), nil
+}
+
+// sweep applies every policy, even after one of them fails, and returns the
+// failures.
+func (j *Janitor) sweep(ctx context.Context, now time.Time) error {
+	policies, err := j.policies(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range policies {
+		if p.retention.SummaryDays > 0 {
+			cutoff := now.AddDate(0, 0, -p.retention.SummaryDays)
+			expired := bson.M{"$and": bson.A{p.filter, bson.M{"created_at": bson.M{"$lt": cutoff}}}}
+			if err := j.purge(ctx, expired, now, PurgeScan, j.deleteScan); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if p.retention.DetailDays > 0 {
+			cutoff := now.AddDate(0, 0, -p.retention.DetailDays)
+			expired := bson.M{"$and": bson.A{p.filter, bson.M{
+				"created_at":       bson.M{"$lt": cutoff},
+				"images_purged_at": bson.M{"$exists": false},
+			}}}
+			if err := j.purge(ctx, expired, now, PurgeImages, j.stripImages); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// purge applies step to the expired scans in batches, oldest first, until
+// none are left. Each step makes its scan stop matching expired. A scan whose
+// step fails is recorded with the error and left out of later batches, so it
+// neither blocks the others nor is retried before the next sweep.
+func (j *Janitor) purge(ctx context.Context, expired bson.M, now time.Time, kind string, step func(context.Context, scan.Scan, time.Time) error) error {
+	oldestFirst := bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+	failed := bson.A{}
+	for {
+		filter := bson.M{"$and": bson.A{expired, bson.M{"_id": bson.M{"$nin": failed}}}}
+		batch, err := j.scans.FindHistory(ctx, filter, bson.M{}, oldestFirst, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to find expired scans: %w", err)
+		}
+		if len(batch) == 0 {
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to purge %d scans", len(failed))
+			}
+			return nil
+		}
+
+		for _, s := range batch {
+			if err := step(ctx, s, now); err != nil {
+				log.Printf("Failed to purge scan %s: %v", s.ID.Hex(), err)
+				failed = append(failed, s.ID)
+				j.record(ctx, kind, s, now, err)
+				continue
+			}
+			j.record(ctx, kind, s, now, nil)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (j *Janitor) stripImages(ctx context.Context, s scan.Scan, now time.Time) error {
+	if err := j.artifacts.DeletePrefix(ctx, scan.ArtifactPrefix(s.ID)); err != nil {
+		return fmt.Errorf("failed to delete artifacts: %w", err)
+	}
+	return j.scans.PurgeImages(ctx, s.ID, now)
+}
+
+func (j *Janitor) deleteScan(ctx context.Context, s scan.Scan, now time.Time) error {
+	if err := j.artifacts.DeletePrefix(ctx, scan.ArtifactPrefix(s.ID)); err != nil {
+		return fmt.Errorf("failed to delete artifacts: %w", err)
+	}
+	if err := j.shares.DeleteForScan(ctx, s.ID); err != nil {
+		return fmt.Errorf("failed to delete share links: %w", err)
+	}
+	_, err := j.scans.Delete(ctx, bson.M{"_id": s.ID})
+	return err
+}
+
+// record logs a purge, or an attempt that failed with purgeErr; failing to
+// record doesn't undo it.
+func (j *Janitor) record(ctx context.Context, kind string, s scan.Scan, now time.Time, purgeErr error) {
+	purge := &Purge{
+		Kind:          kind,
+		ScanID:        s.ID,
+		UserID:        s.UserID,
+		ProjectID:     s.ProjectID,
+		URL:           s.URL,
+		ImageCount:    s.Summary.ImageCount,
+		TotalBytes:    s.Summary.TotalBytes,
+		ScanCreatedAt: s.CreatedAt,
+		PurgedAt:      now,
+	}
+	if purgeErr != nil {
+		purge.Error = purgeErr.Error()
+	}
+	if err := j.purges.Create(ctx, purge); err != nil {
+		log.Printf("Failed to record %s purge of scan %s: %v", kind, s.ID.Hex(), err)
+	}
+}
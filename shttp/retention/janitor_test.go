@@ -0,0 +1,168 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeOrgs []org.Organization
+
+func (f fakeOrgs) FindMany(ctx context.Context, filter interface{}) ([]org.Organization, error) {
+	var orgs []org.Organization
+	for _, o := range f {
+		if o.Retention != nil {
+			orgs = append(orgs, o)
+		}
+	}
+	return orgs, nil
+}
+
+type fakeProjects []org.Project
+
+func (f fakeProjects) FindMany(ctx context.Context, filter interface{}) ([]org.Project, error) {
+	orgID := filter.(bson.M)["org_id"]
+	var projects []org.Project
+	for _, p := range f {
+		if p.OrgID == orgID {
+			projects = append(projects, p)
+		}
+	}
+	return projects, nil
+}
+
+// fakeShares fails to delete the share links of broken.
+type fakeShares struct {
+	broken primitive.ObjectID
+}
+
+func (f fakeShares) DeleteForScan(ctx context.Context, scanID primitive.ObjectID) error {
+	if scanID == f.broken {
+		return errors.New("share links unavailable")
+	}
+	return nil
+}
+
+type fakePurges []Purge
+
+func (f *fakePurges) Create(ctx context.Context, purge *Purge) error {
+	*f = append(*f, *purge)
+	return nil
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	scans, err := scan.OpenBoltScanStore(filepath.Join(t.TempDir(), "scans.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scans.Close()
+	artifacts, err := sblob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strict := org.Organization{ID: primitive.NewObjectID(), Retention: &org.Retention{DetailDays: 7, SummaryDays: 10}}
+	lax := org.Organization{ID: primitive.NewObjectID()}
+	strictProject := org.Project{ID: primitive.NewObjectID(), OrgID: strict.ID}
+	laxProject := org.Project{ID: primitive.NewObjectID(), OrgID: lax.ID}
+
+	newScan := func(project *org.Project, days int) primitive.ObjectID {
+		s := &scan.Scan{ID: primitive.NewObjectID(), UserID: "user", URL: "https://example.com/", CreatedAt: now.AddDate(0, 0, -days)}
+		if project != nil {
+			s.ProjectID = &project.ID
+		}
+		id, err := scans.Create(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := artifacts.Put(ctx, scan.ArtifactPrefix(id)+"page.png", []byte("png")); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	recentPersonal := newScan(nil, 5)
+	oldPersonal := newScan(nil, 40)
+	expiredPersonal := newScan(nil, 400)
+	oldLax := newScan(&laxProject, 40)
+	// Within the default retention, but past the organization's.
+	oldStrict := newScan(&strictProject, 8)
+	expiredStrict := newScan(&strictProject, 12)
+	broken := newScan(&strictProject, 11)
+
+	purges := &fakePurges{}
+	janitor := NewJanitor(scans, artifacts, fakeShares{broken: broken},
+		fakeOrgs{strict, lax}, fakeProjects{strictProject, laxProject}, purges, DefaultRetention)
+
+	policies, err := janitor.policies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 2 || policies[0].retention != *strict.Retention || policies[1].retention != DefaultRetention {
+		t.Fatalf("policies = %+v, want the organization's then the default", policies)
+	}
+	if got := policies[1].filter["project_id"].(bson.M)["$nin"]; len(got.(bson.A)) != 1 {
+		t.Errorf("default policy excludes %v, want the organization's project", got)
+	}
+
+	if err := janitor.sweep(ctx, now); err == nil {
+		t.Error("sweep succeeded despite a failing scan")
+	}
+
+	deleted := []primitive.ObjectID{expiredPersonal, expiredStrict}
+	// broken couldn't be deleted, but still lost its images.
+	stripped := []primitive.ObjectID{oldPersonal, oldLax, oldStrict, broken}
+	kept := []primitive.ObjectID{recentPersonal}
+
+	for _, id := range deleted {
+		if _, err := scans.FindOne(ctx, bson.M{"_id": id}); err != mongo.ErrNoDocuments {
+			t.Errorf("scan %s: err = %v, want it deleted", id.Hex(), err)
+		}
+	}
+	for _, id := range stripped {
+		s, err := scans.FindOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ImagesPurgedAt == nil {
+			t.Errorf("scan %s kept its images", id.Hex())
+		}
+		if _, err := artifacts.Get(ctx, scan.ArtifactPrefix(id)+"page.png"); err == nil {
+			t.Errorf("scan %s kept its artifacts", id.Hex())
+		}
+	}
+	for _, id := range kept {
+		s, err := scans.FindOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			t.Fatalf("scan %s: %v", id.Hex(), err)
+		}
+		if s.ImagesPurgedAt != nil {
+			t.Errorf("scan %s lost its images", id.Hex())
+		}
+	}
+
+	failures := 0
+	for _, p := range *purges {
+		if p.Error != "" {
+			failures++
+			if p.ScanID != broken || p.Kind != PurgeScan {
+				t.Errorf("unexpected failed purge %+v", p)
+			}
+		}
+	}
+	if failures != 1 || len(*purges) != len(deleted)+len(stripped)+1 {
+		t.Errorf("recorded %d purges with %d failures, want %d with 1", len(*purges), failures, len(deleted)+len(stripped)+1)
+	}
+}
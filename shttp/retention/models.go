@@ -0,0 +1,32 @@
+package retention
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// PurgeImages records that a scan's images and artifacts were removed.
+	PurgeImages = "images"
+	// PurgeScan records that a whole scan was deleted.
+	PurgeScan = "scan"
+)
+
+// Purge records one scan the janitor purged, so there's a trace of data that
+// disappeared because of retention rather than a user.
+type Purge struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Kind          string              `json:"kind" bson:"kind"`
+	ScanID        primitive.ObjectID  `json:"scan_id" bson:"scan_id"`
+	UserID        string              `json:"user_id" bson:"user_id"`
+	ProjectID     *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	URL           string              `json:"url" bson:"url"`
+	ImageCount    int                 `json:"image_count" bson:"image_count"`
+	TotalBytes    int64               `json:"total_bytes" bson:"total_bytes"`
+	ScanCreatedAt time.Time           `json:"scan_created_at" bson:"scan_created_at"`
+	PurgedAt      time.Time           `json:"purged_at" bson:"purged_at"`
+	// Error is set when the purge failed; the janitor tries again on its
+	// next sweep.
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
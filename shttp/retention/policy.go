@@ -0,0 +1,40 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/voage/sharprender-api/shttp/org"
+)
+
+// DefaultRetention keeps image detail for 30 days and summaries for a year.
+var DefaultRetention = org.Retention{
+	DetailDays:  30,
+	SummaryDays: 365,
+}
+
+// RetentionFromEnv reads RETENTION_DETAIL_DAYS and RETENTION_SUMMARY_DAYS,
+// falling back to DefaultRetention for unset variables. It applies to
+// personal scans and to organizations without their own retention.
+func RetentionFromEnv() (org.Retention, error) {
+	retention := DefaultRetention
+	for name, target := range map[string]*int{
+		"RETENTION_DETAIL_DAYS":  &retention.DetailDays,
+		"RETENTION_SUMMARY_DAYS": &retention.SummaryDays,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return org.Retention{}, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		*target = n
+	}
+	if err := retention.Validate(); err != nil {
+		return org.Retention{}, err
+	}
+	return retention, nil
+}
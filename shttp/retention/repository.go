@@ -0,0 +1,22 @@
+package retention
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PurgeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPurgeRepository(client *mongo.Client) *PurgeRepository {
+	return &PurgeRepository{
+		collection: client.Database("sharprenderdb").Collection("purges"),
+	}
+}
+
+func (r *PurgeRepository) Create(ctx context.Context, purge *Purge) error {
+	_, err := r.collection.InsertOne(ctx, purge)
+	return err
+}
@@ -0,0 +1,21 @@
+package retention
+
+import (
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewJanitorFromClient builds the background janitor that enforces retention.
+func NewJanitorFromClient(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, defaults org.Retention) *Janitor {
+	return NewJanitor(
+		scans,
+		artifacts,
+		scan.NewShareRepository(mongoClient),
+		org.NewOrgRepository(mongoClient),
+		org.NewProjectRepository(mongoClient),
+		NewPurgeRepository(mongoClient),
+		defaults,
+	)
+}
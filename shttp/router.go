@@ -6,16 +6,70 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/voage/sharprender-api/db"
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/alert"
+	"github.com/voage/sharprender-api/shttp/apikey"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/budget"
+	"github.com/voage/sharprender-api/shttp/crawl"
+	"github.com/voage/sharprender-api/shttp/org"
 	"github.com/voage/sharprender-api/shttp/scan"
+	"github.com/voage/sharprender-api/shttp/schedule"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"github.com/voage/sharprender-api/shttp/webhook"
 )
 
-func NewRouter(mongoClient *db.MongoClient) *chi.Mux {
+func NewRouter(mongoClient *db.MongoClient, scans scan.ScanStore, artifacts sblob.Store, verifier *auth.Verifier, limits usage.Limits, notifiers alert.Notifiers) *chi.Mux {
+	quota := usage.NewUsageServiceFromClient(mongoClient.Client, limits)
+
+	router := newBaseRouter()
+
+	// Share links carry their own token instead of a session, so they are
+	// rate limited per client address.
+	router.With(usage.NewClientRateLimiter(limits.SharedRequestsPerMinute).Middleware).
+		Mount("/shared", scan.NewSharedScanRoutes(mongoClient.Client, scans, artifacts))
+
+	// Everything else acts on a user's data, so it needs a verified session,
+	// and is rate limited per session and API key.
+	router.Group(func(r chi.Router) {
+		r.Use(verifier.Middleware)
+		r.Use(usage.NewRateLimiter(limits.RequestsPerMinute).Middleware)
+		r.Mount("/scan", scan.NewScanRoutes(mongoClient.Client, scans, artifacts, quota))
+		r.Mount("/crawl", crawl.NewCrawlRoutes(mongoClient.Client, scans, artifacts, quota))
+		r.Mount("/schedules", schedule.NewScheduleRoutes(mongoClient.Client, scans, artifacts))
+		r.Mount("/budgets", budget.NewBudgetRoutes(mongoClient.Client))
+		r.Mount("/api-keys", apikey.NewAPIKeyRoutes(mongoClient.Client))
+		r.Mount("/orgs", org.NewOrgRoutes(mongoClient.Client))
+		r.Mount("/usage", usage.NewUsageRoutes(quota))
+		r.Mount("/webhooks", webhook.NewWebhookRoutes(mongoClient.Client))
+		r.Mount("/alerts", alert.NewAlertRoutes(mongoClient.Client, scans, artifacts, notifiers))
+	})
+
+	return router
+}
+
+// NewLocalRouter serves the scan API from a local scan store for running
+// without MongoDB. Only a user's personal scans are available; organizations,
+// schedules, crawls, alerts, budgets, webhooks, API keys, share links and
+// quotas all need Mongo.
+func NewLocalRouter(scans scan.ScanStore, artifacts sblob.Store, verifier *auth.Verifier, limits usage.Limits) *chi.Mux {
+	router := newBaseRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(verifier.Middleware)
+		r.Use(usage.NewRateLimiter(limits.RequestsPerMinute).Middleware)
+		r.Mount("/scan", scan.NewLocalScanRoutes(scans, artifacts))
+	})
+	return router
+}
+
+// newBaseRouter sets up CORS and the health check.
+func newBaseRouter() *chi.Mux {
 	router := chi.NewRouter()
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:8888"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -23,7 +77,5 @@ func NewRouter(mongoClient *db.MongoClient) *chi.Mux {
 	router.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("pong"))
 	})
-	router.Mount("/scan", scan.NewScanRoutes(mongoClient.Client))
-
 	return router
 }
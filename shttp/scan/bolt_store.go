@@ -0,0 +1,437 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/squery"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	scansBucket  = []byte("scans")
+	imagesBucket = []byte("scan_images")
+)
+
+// BoltScanStore keeps scans in a single bbolt file for running without
+// MongoDB. Scans are stored as BSON under their ID and images under the scan
+// ID followed by their index, so a scan's images are read in order with one
+// prefix scan. Queries are evaluated in memory with squery, which is fine for
+// the scan counts of a single user or CI runner.
+type BoltScanStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltScanStore opens or creates the store file at path.
+func OpenBoltScanStore(path string) (*BoltScanStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create scan store directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scan store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{scansBucket, imagesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create scan store buckets: %w", err)
+	}
+
+	return &BoltScanStore{db: db}, nil
+}
+
+func (s *BoltScanStore) Close() error {
+	return s.db.Close()
+}
+
+// storedScan is a scan read from the store, with the decoded document kept
+// for matching and sorting.
+type storedScan struct {
+	scan Scan
+	doc  bson.M
+}
+
+func imageKey(scanID primitive.ObjectID, index int) []byte {
+	key := make([]byte, len(scanID)+4)
+	copy(key, scanID[:])
+	binary.BigEndian.PutUint32(key[len(scanID):], uint32(index))
+	return key
+}
+
+func (s *BoltScanStore) Create(ctx context.Context, scan *Scan) (primitive.ObjectID, error) {
+	doc := *scan
+	if doc.ID.IsZero() {
+		doc.ID = primitive.NewObjectID()
+	}
+	doc.Images = nil
+	doc.SchemaVersion = SchemaVersion
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(scansBucket).Put(doc.ID[:], data); err != nil {
+			return err
+		}
+		images := tx.Bucket(imagesBucket)
+		for i, img := range scan.Images {
+			data, err := bson.Marshal(ScanImage{ID: primitive.NewObjectID(), ScanID: doc.ID, Index: i, Host: hostname(img.Src), Image: img})
+			if err != nil {
+				return err
+			}
+			if err := images.Put(imageKey(doc.ID, i), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return doc.ID, nil
+}
+
+// find returns the matching scans in insertion order, stopping after limit
+// scans unless limit is 0.
+func (s *BoltScanStore) find(tx *bolt.Tx, filter interface{}, limit int) ([]storedScan, error) {
+	query, err := squery.Normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []storedScan
+	c := tx.Bucket(scansBucket).Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		doc, err := squery.Decode(v)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := squery.Match(doc, query)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		var scan Scan
+		if err := bson.Unmarshal(v, &scan); err != nil {
+			return nil, err
+		}
+		found = append(found, storedScan{scan: scan, doc: doc})
+		if limit > 0 && len(found) == limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+func (s *BoltScanStore) findOne(tx *bolt.Tx, filter interface{}) (*Scan, error) {
+	found, err := s.find(tx, filter, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &found[0].scan, nil
+}
+
+// images returns the stored images of a scan in order.
+func (s *BoltScanStore) images(tx *bolt.Tx, scanID primitive.ObjectID) ([]ScanImage, []bson.M, error) {
+	var images []ScanImage
+	var docs []bson.M
+	c := tx.Bucket(imagesBucket).Cursor()
+	for k, v := c.Seek(scanID[:]); k != nil && len(k) > len(scanID) && primitive.ObjectID(k[:len(scanID)]) == scanID; k, v = c.Next() {
+		var img ScanImage
+		if err := bson.Unmarshal(v, &img); err != nil {
+			return nil, nil, err
+		}
+		doc, err := squery.Decode(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		images = append(images, img)
+		docs = append(docs, doc)
+	}
+	return images, docs, nil
+}
+
+// loadImages sets scan.Images to all of the scan's images.
+func (s *BoltScanStore) loadImages(tx *bolt.Tx, scan *Scan) error {
+	images, _, err := s.images(tx, scan.ID)
+	if err != nil {
+		return err
+	}
+	scan.Images = []simage.Image{}
+	for _, img := range images {
+		scan.Images = append(scan.Images, img.Image)
+	}
+	return nil
+}
+
+func (s *BoltScanStore) deleteImages(tx *bolt.Tx, scanID primitive.ObjectID) error {
+	images, _, err := s.images(tx, scanID)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if err := tx.Bucket(imagesBucket).Delete(imageKey(scanID, img.Index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltScanStore) FindOne(ctx context.Context, filter interface{}) (*Scan, error) {
+	var scan *Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		scan, err = s.findOne(tx, filter)
+		return err
+	})
+	return scan, err
+}
+
+func (s *BoltScanStore) FindOneWithImages(ctx context.Context, filter interface{}) (*Scan, error) {
+	var scan *Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		if scan, err = s.findOne(tx, filter); err != nil {
+			return err
+		}
+		return s.loadImages(tx, scan)
+	})
+	return scan, err
+}
+
+// imageMatch is an image matching a filter, with its document for sorting.
+type imageMatch struct {
+	image simage.Image
+	doc   bson.M
+}
+
+// matchImages returns the scan's images matching the filters, in the order
+// they were found.
+func (s *BoltScanStore) matchImages(scan *Scan, filters FilterOptions) ([]imageMatch, error) {
+	query, err := squery.Normalize(filters.query(scan))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []imageMatch
+	err = s.db.View(func(tx *bolt.Tx) error {
+		images, docs, err := s.images(tx, scan.ID)
+		if err != nil {
+			return err
+		}
+		for i, doc := range docs {
+			ok, err := squery.Match(doc, query)
+			if err != nil {
+				return err
+			}
+			if ok {
+				matches = append(matches, imageMatch{image: images[i].Image, doc: doc})
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+func (s *BoltScanStore) FilterImages(ctx context.Context, scan *Scan, filters FilterOptions) error {
+	matches, err := s.matchImages(scan, filters)
+	if err != nil {
+		return err
+	}
+
+	order := filters.sort()
+	sort.SliceStable(matches, func(i, j int) bool {
+		return squery.Less(matches[i].doc, matches[j].doc, order)
+	})
+
+	start := min(filters.Offset, len(matches))
+	end := len(matches)
+	if filters.Limit > 0 {
+		end = min(start+filters.Limit, end)
+	}
+	scan.Images = []simage.Image{}
+	for _, m := range matches[start:end] {
+		scan.Images = append(scan.Images, m.image)
+	}
+	return nil
+}
+
+func (s *BoltScanStore) GetOverview(ctx context.Context, scan *Scan, filters FilterOptions) (*ImageOverview, error) {
+	matches, err := s.matchImages(scan, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &ImageOverview{Formats: map[string]int{}}
+	for _, m := range matches {
+		overview.add(m.image)
+	}
+	return overview, nil
+}
+
+// FindDiffPair reads both scans in one transaction, so a scan deleted in
+// between can't leave the diff half loaded.
+func (s *BoltScanStore) FindDiffPair(ctx context.Context, baseFilter, headFilter interface{}) (*Scan, *Scan, error) {
+	var pair [2]*Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for i, filter := range []interface{}{baseFilter, headFilter} {
+			scan, err := s.findOne(tx, filter)
+			if err != nil {
+				return err
+			}
+			if err := s.loadImages(tx, scan); err != nil {
+				return err
+			}
+			pair[i] = scan
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair[0], pair[1], nil
+}
+
+func (s *BoltScanStore) FindMany(ctx context.Context, filter interface{}) ([]Scan, error) {
+	return s.findSorted(filter, bson.D{{Key: "created_at", Value: 1}}, 0)
+}
+
+// findSorted returns up to limit matching scans in the given order, or every
+// matching scan when limit is 0.
+func (s *BoltScanStore) findSorted(filter interface{}, order bson.D, limit int) ([]Scan, error) {
+	var found []storedScan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = s.find(tx, filter, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return squery.Less(found[i].doc, found[j].doc, order)
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+
+	scans := []Scan{}
+	for _, f := range found {
+		scans = append(scans, f.scan)
+	}
+	return scans, nil
+}
+
+func (s *BoltScanStore) FindHistory(ctx context.Context, filter, after bson.M, sort bson.D, limit int) ([]Scan, error) {
+	return s.findSorted(bson.M{"$and": bson.A{filter, after}}, sort, limit)
+}
+
+func (s *BoltScanStore) GetTrend(ctx context.Context, filter interface{}) ([]TrendPoint, error) {
+	scans, err := s.FindMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []TrendPoint
+	for _, scan := range scans {
+		points = append(points, TrendPoint{
+			ScanID:        scan.ID,
+			CreatedAt:     scan.CreatedAt,
+			TotalBytes:    scan.Summary.TotalBytes,
+			ImageCount:    scan.Summary.ImageCount,
+			FindingsCount: scan.Summary.FindingsCount,
+			Conditions:    scan.Conditions,
+		})
+	}
+	return points, nil
+}
+
+func (s *BoltScanStore) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		scan, err := s.findOne(tx, filter)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(scansBucket).Delete(scan.ID[:]); err != nil {
+			return err
+		}
+		if err := s.deleteImages(tx, scan.ID); err != nil {
+			return err
+		}
+		deleted = 1
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *BoltScanStore) PurgeImages(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		scan, err := s.findOne(tx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if err := s.deleteImages(tx, id); err != nil {
+			return err
+		}
+
+		scan.ImagesPurgedAt = &at
+		data, err := bson.Marshal(scan)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(scansBucket).Put(id[:], data)
+	})
+}
+
+func (s *BoltScanStore) SetArchived(ctx context.Context, filter interface{}, archived bool) (int64, error) {
+	var matched int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		scan, err := s.findOne(tx, filter)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		scan.Archived = archived
+		data, err := bson.Marshal(scan)
+		if err != nil {
+			return err
+		}
+		matched = 1
+		return tx.Bucket(scansBucket).Put(scan.ID[:], data)
+	})
+	return matched, err
+}
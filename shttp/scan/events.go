@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"context"
+	"log"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/webhook"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// previousLookback caps the earlier scans of a URL searched for one run under
+// the same conditions.
+const previousLookback = 20
+
+// ScanEvent is the data of the scan webhook events.
+type ScanEvent struct {
+	ScanID     primitive.ObjectID    `json:"scan_id"`
+	URL        string                `json:"url"`
+	ProjectID  *primitive.ObjectID   `json:"project_id,omitempty"`
+	ScheduleID *primitive.ObjectID   `json:"schedule_id,omitempty"`
+	CrawlID    *primitive.ObjectID   `json:"crawl_id,omitempty"`
+	Summary    ScanSummary           `json:"summary"`
+	Conditions simage.ScanConditions `json:"conditions"`
+	// Budgets are the failed budgets of budget.violated events.
+	Budgets []simage.BudgetResult `json:"budgets,omitempty"`
+	// Previous is the scan a regression.detected event compares against.
+	Previous *PreviousScan `json:"previous,omitempty"`
+}
+
+type PreviousScan struct {
+	ScanID  primitive.ObjectID `json:"scan_id"`
+	Summary ScanSummary        `json:"summary"`
+}
+
+// FailedScanEvent is the data of scan.failed events.
+type FailedScanEvent struct {
+	URL        string              `json:"url"`
+	ProjectID  *primitive.ObjectID `json:"project_id,omitempty"`
+	ScheduleID *primitive.ObjectID `json:"schedule_id,omitempty"`
+	CrawlID    *primitive.ObjectID `json:"crawl_id,omitempty"`
+	Error      string              `json:"error"`
+}
+
+func newScanEvent(scan *Scan) ScanEvent {
+	return ScanEvent{
+		ScanID:     scan.ID,
+		URL:        scan.URL,
+		ProjectID:  scan.ProjectID,
+		ScheduleID: scan.ScheduleID,
+		CrawlID:    scan.CrawlID,
+		Summary:    scan.Summary,
+		Conditions: scan.Conditions,
+	}
+}
+
+// publishFailed raises scan.failed for a scan that couldn't run.
+func (s *ScanService) publishFailed(ctx context.Context, req ScanRequest, scanErr error) {
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Publish(ctx, webhook.EventScanFailed, req.UserID, req.ProjectID, FailedScanEvent{
+		URL:        req.URL,
+		ProjectID:  req.ProjectID,
+		ScheduleID: req.ScheduleID,
+		CrawlID:    req.CrawlID,
+		Error:      scanErr.Error(),
+	})
+}
+
+// publishCompleted raises scan.completed for a stored scan, and
+// budget.violated and regression.detected when they apply.
+func (s *ScanService) publishCompleted(ctx context.Context, scan *Scan) {
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Publish(ctx, webhook.EventScanCompleted, scan.UserID, scan.ProjectID, newScanEvent(scan))
+
+	var failed []simage.BudgetResult
+	for _, b := range scan.Budgets {
+		if !b.Passed {
+			failed = append(failed, b)
+		}
+	}
+	if len(failed) > 0 {
+		event := newScanEvent(scan)
+		event.Budgets = failed
+		s.webhooks.Publish(ctx, webhook.EventBudgetViolated, scan.UserID, scan.ProjectID, event)
+	}
+
+	previous, err := s.PreviousComparable(ctx, scan)
+	if err != nil {
+		log.Printf("Failed to find the scan before %s: %v", scan.ID.Hex(), err)
+		return
+	}
+	if previous == nil {
+		return
+	}
+	event := newScanEvent(scan)
+	event.Previous = &PreviousScan{ScanID: previous.ID, Summary: previous.Summary}
+	s.webhooks.PublishTo(ctx, webhook.EventRegressionDetected, scan.UserID, scan.ProjectID, event, func(e *webhook.Endpoint) bool {
+		return len(Regressions(webhookRegression(e), previous, scan)) > 0
+	})
+}
+
+// webhookRegression is what counts as a regression for an endpoint: image
+// bytes growing past its threshold, or any new finding. Failed budgets have
+// their own event.
+func webhookRegression(e *webhook.Endpoint) RegressionCriteria {
+	return RegressionCriteria{
+		BytesIncreasePercent: e.RegressionThreshold(),
+		FindingSeverity:      simage.SeverityInfo,
+	}
+}
+
+// PreviousComparable returns the latest earlier scan of the same URL, in the
+// same project or personal history, that ran under the same conditions, or
+// nil if there is none.
+func (s *ScanService) PreviousComparable(ctx context.Context, scan *Scan) (*Scan, error) {
+	filter := bson.M{"url": scan.URL, "user_id": scan.UserID, "project_id": nil}
+	if scan.ProjectID != nil {
+		filter = bson.M{"url": scan.URL, "project_id": *scan.ProjectID}
+	}
+	filter["_id"] = bson.M{"$ne": scan.ID}
+	filter["created_at"] = bson.M{"$lte": scan.CreatedAt}
+
+	newestFirst := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	earlier, err := s.repo.FindHistory(ctx, filter, bson.M{}, newestFirst, previousLookback)
+	if err != nil {
+		return nil, err
+	}
+	for i := range earlier {
+		if earlier[i].Conditions.Matches(scan.Conditions) {
+			return &earlier[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,320 @@
+package scan
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// imageSorts maps the sort parameter to the scan_images field it orders by.
+var imageSorts = map[string]string{
+	"size":      "size",
+	"load_time": "network.load_time",
+	"width":     "width",
+	"height":    "height",
+	"format":    "format",
+	"src":       "src",
+}
+
+// FilterOptions selects, orders and pages the images of a scan. Nil and empty
+// fields don't filter; a Limit of 0 returns every remaining image.
+type FilterOptions struct {
+	SizeMin, SizeMax         *int64
+	LoadTimeMin, LoadTimeMax *float64
+	WidthMin, WidthMax       *int64
+	HeightMin, HeightMax     *int64
+	Formats                  []string
+	// Party is "first" or "third", comparing the image host to the page host.
+	Party     string
+	Rules     []string
+	LCP       *bool
+	AboveFold *bool
+
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+// parseFilterOptions reads the image filter parameters:
+//
+//	size_min, size_max             bytes
+//	load_time_min, load_time_max   seconds
+//	width_min, width_max           pixels, likewise height_min and height_max
+//	format                         comma-separated or repeated, e.g. jpeg,png
+//	party                          first or third
+//	rule                           finding rule IDs, comma-separated or repeated
+//	lcp, above_fold                true or false
+//	sort                           size, load_time, width, height, format or src
+//	order                          asc (default) or desc
+//	limit, offset                  pagination of the matching images
+//
+// Any malformed value is an error, which callers answer with 400.
+func parseFilterOptions(r *http.Request) (FilterOptions, error) {
+	params := r.URL.Query()
+	var filters FilterOptions
+	var err error
+
+	for _, p := range []struct {
+		name   string
+		target **int64
+	}{
+		{"size_min", &filters.SizeMin},
+		{"size_max", &filters.SizeMax},
+		{"width_min", &filters.WidthMin},
+		{"width_max", &filters.WidthMax},
+		{"height_min", &filters.HeightMin},
+		{"height_max", &filters.HeightMax},
+	} {
+		if *p.target, err = parseIntParam(params, p.name); err != nil {
+			return FilterOptions{}, err
+		}
+	}
+	if filters.LoadTimeMin, err = parseFloatParam(params, "load_time_min"); err != nil {
+		return FilterOptions{}, err
+	}
+	if filters.LoadTimeMax, err = parseFloatParam(params, "load_time_max"); err != nil {
+		return FilterOptions{}, err
+	}
+
+	filters.Formats = listParam(params, "format")
+	filters.Rules = listParam(params, "rule")
+	for _, rule := range filters.Rules {
+		if !knownRule(rule) {
+			return FilterOptions{}, fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+
+	switch party := params.Get("party"); party {
+	case "", "first", "third":
+		filters.Party = party
+	default:
+		return FilterOptions{}, fmt.Errorf("party must be first or third, got %q", party)
+	}
+
+	if filters.LCP, err = parseBoolParam(params, "lcp"); err != nil {
+		return FilterOptions{}, err
+	}
+	if filters.AboveFold, err = parseBoolParam(params, "above_fold"); err != nil {
+		return FilterOptions{}, err
+	}
+
+	if s := params.Get("sort"); s != "" {
+		if _, ok := imageSorts[s]; !ok {
+			return FilterOptions{}, fmt.Errorf("unknown sort %q", s)
+		}
+		filters.Sort = s
+	}
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		filters.Desc = true
+	default:
+		return FilterOptions{}, fmt.Errorf("order must be asc or desc")
+	}
+
+	limit, err := parseIntParam(params, "limit")
+	if err != nil {
+		return FilterOptions{}, err
+	}
+	if limit != nil {
+		filters.Limit = int(*limit)
+	}
+	offset, err := parseIntParam(params, "offset")
+	if err != nil {
+		return FilterOptions{}, err
+	}
+	if offset != nil {
+		filters.Offset = int(*offset)
+	}
+
+	return filters, nil
+}
+
+func parseIntParam(params url.Values, name string) (*int64, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return &n, nil
+}
+
+func parseFloatParam(params url.Values, name string) (*float64, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &f, nil
+}
+
+func parseBoolParam(params url.Values, name string) (*bool, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &b, nil
+}
+
+// listParam collects comma-separated and repeated values of a parameter.
+func listParam(params url.Values, name string) []string {
+	var values []string
+	for _, v := range params[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// query matches the scan's images in scan_images that pass the filters.
+func (f FilterOptions) query(scan *Scan) bson.M {
+	query := bson.M{"scan_id": scan.ID}
+
+	addRange(query, "size", f.SizeMin, f.SizeMax)
+	addRange(query, "width", f.WidthMin, f.WidthMax)
+	addRange(query, "height", f.HeightMin, f.HeightMax)
+	addRange(query, "network.load_time", f.LoadTimeMin, f.LoadTimeMax)
+
+	if len(f.Formats) > 0 {
+		query["format"] = bson.M{"$in": f.Formats}
+	}
+	if f.LCP != nil {
+		query["lcp"] = *f.LCP
+	}
+	if f.AboveFold != nil {
+		query["above_fold"] = *f.AboveFold
+	}
+
+	if len(f.Rules) > 0 {
+		srcs := []string{}
+		for _, finding := range scan.Findings {
+			if contains(f.Rules, finding.RuleID) {
+				srcs = append(srcs, finding.Images...)
+			}
+		}
+		query["src"] = bson.M{"$in": srcs}
+	}
+
+	switch f.Party {
+	case "first":
+		query["$or"] = firstParty(hostname(scan.URL))
+	case "third":
+		query["$nor"] = firstParty(hostname(scan.URL))
+	}
+
+	return query
+}
+
+// addRange adds inclusive bounds on a field; nil bounds are left open.
+func addRange[T int64 | float64](query bson.M, field string, min, max *T) {
+	bounds := bson.M{}
+	if min != nil {
+		bounds["$gte"] = *min
+	}
+	if max != nil {
+		bounds["$lte"] = *max
+	}
+	if len(bounds) > 0 {
+		query[field] = bounds
+	}
+}
+
+// firstParty matches images served from the page's site: its registrable
+// domain or any subdomain of it, so cdn.example.com, example.com and
+// shop.example.com are all first-party to each other.
+func firstParty(pageHost string) bson.A {
+	site := siteDomain(pageHost)
+	if site == "" {
+		return bson.A{bson.M{"host": bson.M{"$in": bson.A{}}}}
+	}
+	return bson.A{
+		bson.M{"host": site},
+		bson.M{"host": primitive.Regex{Pattern: `\.` + regexp.QuoteMeta(site) + `$`}},
+	}
+}
+
+// secondLevelLabels are the labels registries commonly put under a
+// two-letter country code, as in example.co.uk or example.com.au.
+var secondLevelLabels = map[string]bool{
+	"ac": true, "co": true, "com": true, "edu": true, "gov": true,
+	"net": true, "or": true, "org": true, "ne": true, "go": true,
+}
+
+// siteDomain approximates the registrable domain of a host: its last two
+// labels, or three under a country code's second level (example.co.uk).
+// IP addresses and single-label hosts are returned as they are.
+func siteDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	n := 2
+	if len(labels) > 2 && len(labels[len(labels)-1]) == 2 && secondLevelLabels[labels[len(labels)-2]] {
+		n = 3
+	}
+	if len(labels) <= n {
+		return host
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+// sort orders by the requested field, or in the order the scan found the
+// images, with the index breaking ties.
+func (f FilterOptions) sort() bson.D {
+	direction := 1
+	if f.Desc {
+		direction = -1
+	}
+	if field, ok := imageSorts[f.Sort]; ok {
+		return bson.D{{Key: field, Value: direction}, {Key: "index", Value: 1}}
+	}
+	return bson.D{{Key: "index", Value: 1}}
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func knownRule(id string) bool {
+	for _, rule := range simage.Rules() {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
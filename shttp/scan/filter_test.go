@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/squery"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseFilterOptions(t *testing.T) {
+	for _, tc := range []struct {
+		query   string
+		wantErr bool
+		check   func(FilterOptions) bool
+	}{
+		{"", false, func(f FilterOptions) bool { return f.SizeMin == nil && f.Limit == 0 && f.Sort == "" }},
+		{"size_min=100&size_max=200", false, func(f FilterOptions) bool { return *f.SizeMin == 100 && *f.SizeMax == 200 }},
+		{"load_time_min=0.5", false, func(f FilterOptions) bool { return *f.LoadTimeMin == 0.5 }},
+		{"format=jpeg,png&format=webp", false, func(f FilterOptions) bool { return len(f.Formats) == 3 && f.Formats[2] == "webp" }},
+		{"rule=oversized-image,duplicate-image", false, func(f FilterOptions) bool { return len(f.Rules) == 2 }},
+		{"party=third&lcp=true&above_fold=false", false, func(f FilterOptions) bool {
+			return f.Party == "third" && *f.LCP && !*f.AboveFold
+		}},
+		{"sort=load_time&order=desc&limit=10&offset=20", false, func(f FilterOptions) bool {
+			return f.Sort == "load_time" && f.Desc && f.Limit == 10 && f.Offset == 20
+		}},
+		{"size_min=-1", true, nil},
+		{"width_max=wide", true, nil},
+		{"load_time_max=-0.1", true, nil},
+		{"rule=no-such-rule", true, nil},
+		{"party=second", true, nil},
+		{"lcp=maybe", true, nil},
+		{"sort=alt", true, nil},
+		{"order=up", true, nil},
+		{"limit=-5", true, nil},
+	} {
+		filters, err := parseFilterOptions(httptest.NewRequest("GET", "/scan/1?"+tc.query, nil))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, want error %v", tc.query, err, tc.wantErr)
+			continue
+		}
+		if tc.check != nil && !tc.check(filters) {
+			t.Errorf("%q: unexpected filters %+v", tc.query, filters)
+		}
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	scan := &Scan{
+		ID:  primitive.NewObjectID(),
+		URL: "https://www.example.com/products",
+		Findings: []simage.Finding{
+			{RuleID: simage.RuleOversizedImage, Images: []string{"https://cdn.example.com/hero.jpg"}},
+		},
+	}
+	image := func(src, host string, size int, loadTime float64) ScanImage {
+		img := ScanImage{ScanID: scan.ID, Host: host, Image: simage.Image{Src: src, Size: size, Format: "jpeg"}}
+		img.Network.LoadTime = loadTime
+		return img
+	}
+	hero := image("https://cdn.example.com/hero.jpg", "cdn.example.com", 2<<20, 1.5)
+	logo := image("https://example.com/logo.jpg", "example.com", 10_000, 0.2)
+	shop := image("https://shop.example.com/item.jpg", "shop.example.com", 50_000, 0.4)
+	ad := image("https://ads.tracker.net/pixel.jpg", "ads.tracker.net", 100, 0.1)
+	lookalike := image("https://notexample.com/a.jpg", "notexample.com", 100, 0.1)
+	other := image("https://www.example.com/other.jpg", "example.com", 100, 0.1)
+	other.ScanID = primitive.NewObjectID()
+
+	int64p := func(n int64) *int64 { return &n }
+	float64p := func(f float64) *float64 { return &f }
+
+	for _, tc := range []struct {
+		name    string
+		filters FilterOptions
+		want    []ScanImage
+	}{
+		{"no filters", FilterOptions{}, []ScanImage{hero, logo, shop, ad, lookalike}},
+		{"size range", FilterOptions{SizeMin: int64p(10_000), SizeMax: int64p(50_000)}, []ScanImage{logo, shop}},
+		{"size lower bound is inclusive", FilterOptions{SizeMin: int64p(2 << 20)}, []ScanImage{hero}},
+		{"load time upper bound", FilterOptions{LoadTimeMax: float64p(0.2)}, []ScanImage{logo, ad, lookalike}},
+		{"first party spans the site's subdomains", FilterOptions{Party: "first"}, []ScanImage{hero, logo, shop}},
+		{"third party", FilterOptions{Party: "third"}, []ScanImage{ad, lookalike}},
+		{"rule", FilterOptions{Rules: []string{simage.RuleOversizedImage}}, []ScanImage{hero}},
+		{"rule without findings", FilterOptions{Rules: []string{simage.RuleDuplicateImage}}, nil},
+	} {
+		query, err := squery.Normalize(tc.filters.query(scan))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, img := range []ScanImage{hero, logo, shop, ad, lookalike, other} {
+			doc, err := squery.Normalize(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := squery.Match(doc, query)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if ok {
+				got = append(got, img.Src)
+			}
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: matched %v, want %d images", tc.name, got, len(tc.want))
+			continue
+		}
+		for i, img := range tc.want {
+			if got[i] != img.Src {
+				t.Errorf("%s: matched %v, want %s at %d", tc.name, got, img.Src, i)
+			}
+		}
+	}
+}
+
+func TestSiteDomain(t *testing.T) {
+	for _, tc := range []struct{ host, want string }{
+		{"example.com", "example.com"},
+		{"cdn.example.com", "example.com"},
+		{"a.b.example.com", "example.com"},
+		{"shop.example.co.uk", "example.co.uk"},
+		{"example.co.uk", "example.co.uk"},
+		{"static.example.io", "example.io"},
+		{"localhost", "localhost"},
+		{"192.168.1.10", "192.168.1.10"},
+		{"", ""},
+	} {
+		if got := siteDomain(tc.host); got != tc.want {
+			t.Errorf("siteDomain(%q) = %q, want %q", tc.host, got, tc.want)
+		}
+	}
+}
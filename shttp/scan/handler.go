@@ -1,26 +1,37 @@
 package scan
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"path"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/sblob"
 	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ScanHandler struct {
 	service *ScanService
-	repo    *ScanRepository
+	repo    ScanStore
+	shares  *ShareService
 }
 
-func NewScanHandler(service *ScanService, repo *ScanRepository) *ScanHandler {
-	return &ScanHandler{service: service, repo: repo}
+func NewScanHandler(service *ScanService, repo ScanStore, shares *ShareService) *ScanHandler {
+	return &ScanHandler{service: service, repo: repo, shares: shares}
 }
 
 func (h *ScanHandler) GetScanResults(w http.ResponseWriter, r *http.Request) {
@@ -33,10 +44,18 @@ func (h *ScanHandler) GetScanResults(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query filters
-	filters := parseFilterOptions(r)
+	filters, err := parseFilterOptions(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Fetch results from service
-	result, err := h.service.fetchScanResult(r.Context(), objectID, filters)
+	result, err := h.service.fetchScanResult(r.Context(), auth.UserID(r.Context()), objectID, filters)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch scan results", http.StatusInternalServerError)
 		return
@@ -50,8 +69,9 @@ func (h *ScanHandler) GetScanResults(w http.ResponseWriter, r *http.Request) {
 
 func (h *ScanHandler) ScanURL(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		UserID string `json:"user_id"`
-		URL    string `json:"url"`
+		URL       string              `json:"url"`
+		ProjectID *primitive.ObjectID `json:"project_id"`
+		ScanOptions
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -62,10 +82,6 @@ func (h *ScanHandler) ScanURL(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing URL parameter", http.StatusBadRequest)
 		return
 	}
-	if req.UserID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
-		return
-	}
 
 	_, err := url.ParseRequestURI(req.URL)
 	if err != nil {
@@ -73,63 +89,597 @@ func (h *ScanHandler) ScanURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imageScraper := simage.NewImageScraper()
+	if err := req.ScanOptions.Validate(); err != nil {
+		http.Error(w, "Invalid scan options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	imageScraper.SetNetworkProfile("No Throttling")
+	userID := auth.UserID(r.Context())
+	if req.ProjectID != nil {
+		if err := h.service.access.Authorize(r.Context(), userID, *req.ProjectID, org.RoleEditor); err != nil {
+			org.WriteAccessError(w, err, "Failed to check project access")
+			return
+		}
+	}
 
-	results, metadata, err := imageScraper.ScrapeImages(r.Context(), req.URL)
+	scan, err := h.service.RunScan(r.Context(), ScanRequest{
+		UserID:    userID,
+		ProjectID: req.ProjectID,
+		URL:       req.URL,
+		Options:   req.ScanOptions,
+	})
 	if err != nil {
-		log.Printf("Failed to scrape: %v", err)
-		http.Error(w, "Failed to scrape", http.StatusInternalServerError)
+		log.Printf("Failed to run scan: %v", err)
+		http.Error(w, "Failed to run scan", http.StatusInternalServerError)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scan_id":        scan.ID.Hex(),
+		"budgets_passed": simage.BudgetsPassed(scan.Budgets),
+	})
+}
 
-	resultsWithAI, err := simage.CreateAIRecommendations(ctx, results)
+// RerunScan runs a stored scan again with the same URL and options.
+func (h *ScanHandler) RerunScan(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
 	if err != nil {
-		log.Printf("Failed to get AI recommendations: %v", err)
-		http.Error(w, "Failed to get AI recommendations", http.StatusInternalServerError)
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
 		return
 	}
 
-	scan := Scan{
-		UserID:    req.UserID,
-		URL:       req.URL,
-		Metadata:  metadata,
-		Images:    resultsWithAI,
-		CreatedAt: time.Now(),
+	scan, err := h.service.RerunScan(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to re-run scan %s: %v", objectID.Hex(), err)
+		http.Error(w, "Failed to run scan", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scan_id":          scan.ID.Hex(),
+		"previous_scan_id": objectID.Hex(),
+		"budgets_passed":   simage.BudgetsPassed(scan.Budgets),
+	})
+}
+
+// ArchiveScan hides a scan from the default history; DELETE restores it.
+func (h *ScanHandler) ArchiveScan(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.SetArchived(r.Context(), auth.UserID(r.Context()), objectID, r.Method != http.MethodDelete)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update scan", http.StatusInternalServerError)
+		return
 	}
 
-	id, err := h.repo.Create(context.Background(), &scan)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteScan deletes a scan, its optimized artifacts and its share links.
+func (h *ScanHandler) DeleteScan(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Failed to create scan", http.StatusInternalServerError)
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteScan(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete scan %s: %v", objectID.Hex(), err)
+		http.Error(w, "Failed to delete scan", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetScanDiff reports what changed between the base and head scans.
+func (h *ScanHandler) GetScanDiff(w http.ResponseWriter, r *http.Request) {
+	baseID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("base"))
+	if err != nil {
+		http.Error(w, "Invalid base scan ID", http.StatusBadRequest)
+		return
+	}
+	headID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("head"))
+	if err != nil {
+		http.Error(w, "Invalid head scan ID", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.service.DiffScans(r.Context(), auth.UserID(r.Context()), baseID, headID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrImagesPurged) {
+		http.Error(w, "Scan images were removed by retention", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, ErrIncomparableScans) {
+		http.Error(w, "Scans ran under different network or CPU conditions", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to diff scans", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"scan_id": id.Hex()})
+	json.NewEncoder(w).Encode(diff)
 }
 
-func (h *ScanHandler) GetScanHistory(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "user_id is required", http.StatusBadRequest)
+// thumbnailWidth is the preview width embedded in HTML and PDF reports.
+const thumbnailWidth = 192
+
+type reportWriter func(ctx context.Context, w io.Writer, r sreport.Report) error
+
+// reportFormats maps the report routes to their writer and content type.
+// Formats with thumbnails embed a preview of every image.
+var reportFormats = map[string]struct {
+	contentType string
+	thumbnails  bool
+	write       reportWriter
+}{
+	"junit.xml":    {"application/xml", false, withoutContext(sreport.WriteJUnit)},
+	"report.sarif": {"application/sarif+json", false, withoutContext(sreport.WriteSARIF)},
+	"report.md":    {"text/markdown; charset=utf-8", false, withoutContext(sreport.WriteMarkdown)},
+	"report.html":  {"text/html; charset=utf-8", true, withoutContext(sreport.WriteHTML)},
+	"report.pdf":   {"application/pdf", true, sreport.WritePDF},
+}
+
+func withoutContext(write func(io.Writer, sreport.Report) error) reportWriter {
+	return func(_ context.Context, w io.Writer, r sreport.Report) error {
+		return write(w, r)
+	}
+}
+
+// GetScanReport renders a stored scan in the format named by the last path segment.
+func (h *ScanHandler) GetScanReport(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.service.scope(r.Context(), auth.UserID(r.Context()), bson.M{"_id": objectID}, org.RoleViewer)
+	if err != nil {
+		http.Error(w, "Failed to fetch scan", http.StatusInternalServerError)
+		return
+	}
+
+	scan, err := h.repo.FindOneWithImages(r.Context(), filter)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch scan", http.StatusInternalServerError)
+		return
+	}
+
+	writeReport(w, r, scan)
+}
+
+// writeReport renders the scan in the format named by the last path segment.
+func writeReport(w http.ResponseWriter, r *http.Request, scan *Scan) {
+	name := path.Base(r.URL.Path)
+	if _, ok := reportFormats[name]; !ok {
+		http.Error(w, "Unknown report format", http.StatusNotFound)
+		return
+	}
+
+	// Render fully before writing so a failure can still become a 500.
+	data, err := renderReport(r.Context(), name, scan)
+	if err != nil {
+		log.Printf("Failed to write report: %v", err)
+		http.Error(w, "Failed to render report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", reportFormats[name].contentType)
+	w.Write(data)
+}
+
+// renderReport renders the scan in one of reportFormats.
+func renderReport(ctx context.Context, name string, scan *Scan) ([]byte, error) {
+	format := reportFormats[name]
+	report := scan.Report()
+	if format.thumbnails {
+		report.Thumbnails = simage.Thumbnails(ctx, scan.Images, thumbnailWidth)
+	}
+
+	var buf bytes.Buffer
+	if err := format.write(ctx, &buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportFormats maps export file extensions to their writer and content type.
+var exportFormats = map[string]struct {
+	contentType string
+	newWriter   func(io.Writer) sreport.ImageRowWriter
+}{
+	".csv":   {"text/csv; charset=utf-8", sreport.NewImagesCSVWriter},
+	".jsonl": {"application/x-ndjson", sreport.NewImagesJSONLWriter},
+}
+
+// GetImagesExport exports a scan's images as CSV or JSON Lines, honoring the
+// same filter parameters as GetScanResults.
+func (h *ScanHandler) GetImagesExport(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	filters, err := parseFilterOptions(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.service.ExportImages(r.Context(), auth.UserID(r.Context()), objectID, filters)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err == ErrImagesPurged {
+		http.Error(w, "Scan images were removed by retention", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch scan results", http.StatusInternalServerError)
+		return
+	}
+
+	writeExport(w, r, "scan-"+objectID.Hex(), rows)
+}
+
+// GetHistoryExport exports the images of all scans the user can see, or of
+// the project named by project_id. Optional from/to query parameters take RFC
+// 3339 timestamps.
+func (h *ScanHandler) GetHistoryExport(w http.ResponseWriter, r *http.Request) {
+	projectID, err := parseProjectParam(r)
+	if err != nil {
+		http.Error(w, "Invalid project_id parameter", http.StatusBadRequest)
+		return
+	}
+	from, err := parseTimeParam(r, "from")
+	if err != nil {
+		http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(r, "to")
+	if err != nil {
+		http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+		return
+	}
+
+	filters, err := parseFilterOptions(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ext := path.Ext(r.URL.Path)
+	if _, ok := exportFormats[ext]; !ok {
+		http.Error(w, "Unknown export format", http.StatusNotFound)
+		return
+	}
+
+	// Rows are streamed scan by scan; the response starts with the first
+	// scan, so earlier errors still get a status code of their own.
+	var rw sreport.ImageRowWriter
+	err = h.service.ExportHistory(r.Context(), auth.UserID(r.Context()), projectID, from, to, filters, func(rows []sreport.ImageRow) error {
+		if rw == nil {
+			rw = startExport(w, ext, "scan-history")
+		}
+		return rw.Write(rows)
+	})
+	if err != nil && rw == nil {
+		org.WriteAccessError(w, err, "Failed to fetch scan history")
 		return
 	}
+	if err != nil {
+		log.Printf("Failed to write history export: %v", err)
+		return
+	}
+	if rw == nil {
+		rw = startExport(w, ext, "scan-history")
+	}
+	if err := rw.Flush(); err != nil {
+		log.Printf("Failed to write history export: %v", err)
+	}
+}
+
+// writeExport writes rows in the format named by the request path's extension.
+func writeExport(w http.ResponseWriter, r *http.Request, name string, rows []sreport.ImageRow) {
+	ext := path.Ext(r.URL.Path)
+	if _, ok := exportFormats[ext]; !ok {
+		http.Error(w, "Unknown export format", http.StatusNotFound)
+		return
+	}
+
+	rw := startExport(w, ext, name)
+	if err := rw.Write(rows); err != nil {
+		log.Printf("Failed to write export: %v", err)
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		log.Printf("Failed to write export: %v", err)
+	}
+}
+
+// startExport sets the download headers of a known export extension and
+// returns the writer for its format.
+func startExport(w http.ResponseWriter, ext, name string) sreport.ImageRowWriter {
+	format := exportFormats[ext]
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+ext))
+	return format.newWriter(w)
+}
+
+func parseProjectParam(r *http.Request) (*primitive.ObjectID, error) {
+	value := r.URL.Query().Get("project_id")
+	if value == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetScanArtifact serves an optimized image stored for the scan.
+func (h *ScanHandler) GetScanArtifact(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.service.Artifact(r.Context(), auth.UserID(r.Context()), objectID, chi.URLParam(r, "name"))
+	if errors.Is(err, sblob.ErrNotFound) {
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch artifact", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Write(data)
+}
+
+// GetNetworkProfiles lists the named network profiles a scan can request.
+func (h *ScanHandler) GetNetworkProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"profiles": simage.NetworkProfileNames(),
+	})
+}
+
+// GetScanHistory lists one page of the scans the user can see: their own and
+// those of their projects, or only those of the project named by project_id.
+// Pass next_cursor back as cursor to get the following page.
+func (h *ScanHandler) GetScanHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	filter := bson.M{"user_id": userID}
+	projectID, err := parseProjectParam(r)
+	if err != nil {
+		http.Error(w, "Invalid project_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scans, next, err := h.service.GetHistory(ctx, auth.UserID(ctx), projectID, query)
+	if err != nil {
+		org.WriteAccessError(w, err, "Failed to fetch scan history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"scans":       scans,
+		"next_cursor": next,
+	})
+}
+
+// CreateShare creates a share link for a scan. The response is the only time
+// the token is shown.
+func (h *ScanHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+		Redact    bool       `json:"redact"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-	scans, err := h.repo.GetScansOverview(ctx, filter)
+	link, token, err := h.shares.CreateShare(r.Context(), auth.UserID(r.Context()), objectID, req.ExpiresAt, req.Redact)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to fetch scan history", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"share": link,
+		"token": token,
+		"path":  "/shared/" + token,
+	})
+}
+
+func (h *ScanHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	links, err := h.shares.GetShares(r.Context(), auth.UserID(r.Context()), objectID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch share links", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"scans":   scans,
+		"shares":  links,
 	})
 }
+
+// RevokeShare stops a share link from working. The link stays listed.
+func (h *ScanHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	scanID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+	shareID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "shareID"))
+	if err != nil {
+		http.Error(w, "Invalid share ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.shares.RevokeShare(r.Context(), auth.UserID(r.Context()), scanID, shareID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke share link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveShare loads the active link named by the token in the URL, answering
+// 404 for unknown, expired and revoked tokens.
+func (h *ScanHandler) resolveShare(w http.ResponseWriter, r *http.Request) *ShareLink {
+	link, err := h.shares.Resolve(r.Context(), chi.URLParam(r, "token"))
+	if errors.Is(err, ErrShareNotFound) {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch share link", http.StatusInternalServerError)
+		return nil
+	}
+	return link
+}
+
+// GetSharedResults serves GetScanResults for a share link without authentication.
+func (h *ScanHandler) GetSharedResults(w http.ResponseWriter, r *http.Request) {
+	link := h.resolveShare(w, r)
+	if link == nil {
+		return
+	}
+
+	filters, err := parseFilterOptions(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.shares.SharedResult(r.Context(), link, filters)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch scan results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetSharedReport serves GetScanReport for a share link without
+// authentication. Each report is rendered once per link and then served from
+// the artifact store, so a leaked token can't keep the renderer busy.
+func (h *ScanHandler) GetSharedReport(w http.ResponseWriter, r *http.Request) {
+	link := h.resolveShare(w, r)
+	if link == nil {
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	format, ok := reportFormats[name]
+	if !ok {
+		http.Error(w, "Unknown report format", http.StatusNotFound)
+		return
+	}
+
+	data, err := h.shares.SharedReport(r.Context(), link, name)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to write shared report: %v", err)
+		http.Error(w, "Failed to render report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Write(data)
+}
@@ -0,0 +1,191 @@
+package scan
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// historySorts maps the sort parameter to the field it orders by.
+var historySorts = map[string]string{
+	"created_at":  "created_at",
+	"total_bytes": "summary.total_bytes",
+}
+
+// HistoryQuery is one page request of the scan history.
+type HistoryQuery struct {
+	Sort   string
+	Desc   bool
+	Limit  int
+	Search string
+	// Archived is "exclude" (the default), "include" or "only".
+	Archived string
+	From     *time.Time
+	To       *time.Time
+	Cursor   *historyCursor
+}
+
+// historyCursor points just past the last scan of a page. Sort and Desc are
+// kept so a cursor can't be reused with a different order.
+type historyCursor struct {
+	Sort       string             `json:"s"`
+	Desc       bool               `json:"d"`
+	CreatedAt  time.Time          `json:"c"`
+	TotalBytes int64              `json:"b"`
+	ID         primitive.ObjectID `json:"i"`
+}
+
+// parseHistoryQuery reads sort (created_at or total_bytes), order (asc or
+// desc), limit, q, archived, from, to and cursor. The default is newest
+// first, without archived scans.
+func parseHistoryQuery(r *http.Request) (*HistoryQuery, error) {
+	params := r.URL.Query()
+	q := &HistoryQuery{Sort: "created_at", Desc: true, Limit: defaultHistoryLimit, Search: params.Get("q"), Archived: "exclude"}
+
+	switch archived := params.Get("archived"); archived {
+	case "":
+	case "exclude", "include", "only":
+		q.Archived = archived
+	default:
+		return nil, errors.New("archived must be exclude, include or only")
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		if _, ok := historySorts[sort]; !ok {
+			return nil, fmt.Errorf("unknown sort %q", sort)
+		}
+		q.Sort = sort
+	}
+
+	switch params.Get("order") {
+	case "", "desc":
+	case "asc":
+		q.Desc = false
+	default:
+		return nil, errors.New("order must be asc or desc")
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+		q.Limit = n
+	}
+
+	var err error
+	if q.From, err = parseTimeParam(r, "from"); err != nil {
+		return nil, errors.New("invalid from parameter")
+	}
+	if q.To, err = parseTimeParam(r, "to"); err != nil {
+		return nil, errors.New("invalid to parameter")
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil || c.Sort != q.Sort || c.Desc != q.Desc {
+			return nil, errors.New("invalid cursor")
+		}
+		q.Cursor = c
+	}
+
+	return q, nil
+}
+
+func encodeCursor(c historyCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*historyCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c historyCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// searchFilter matches the search text anywhere in the URL or page title,
+// ignoring case.
+func searchFilter(search string) bson.M {
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+	return bson.M{"$or": bson.A{
+		bson.M{"url": pattern},
+		bson.M{"metadata.title": pattern},
+	}}
+}
+
+// archivedFilter matches scans by their archive flag, or is nil to keep all.
+func (q *HistoryQuery) archivedFilter() bson.M {
+	switch q.Archived {
+	case "include":
+		return nil
+	case "only":
+		return bson.M{"archived": true}
+	}
+	return bson.M{"archived": bson.M{"$ne": true}}
+}
+
+// sort returns the sort stage, with _id breaking ties so pages never overlap.
+func (q *HistoryQuery) sort() bson.D {
+	direction := 1
+	if q.Desc {
+		direction = -1
+	}
+	return bson.D{{Key: historySorts[q.Sort], Value: direction}, {Key: "_id", Value: direction}}
+}
+
+// after matches the scans that come after the cursor in the query's order.
+func (q *HistoryQuery) after() bson.M {
+	if q.Cursor == nil {
+		return bson.M{}
+	}
+
+	op := "$gt"
+	if q.Desc {
+		op = "$lt"
+	}
+	field := historySorts[q.Sort]
+	var value interface{} = q.Cursor.CreatedAt
+	if q.Sort == "total_bytes" {
+		value = q.Cursor.TotalBytes
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: q.Cursor.ID}},
+	}}
+}
+
+// nextCursor points past the last scan of a full page, or is empty when the
+// page is the last one.
+func (q *HistoryQuery) nextCursor(page []Scan, more bool) string {
+	if !more || len(page) == 0 {
+		return ""
+	}
+	last := page[len(page)-1]
+	return encodeCursor(historyCursor{
+		Sort:       q.Sort,
+		Desc:       q.Desc,
+		CreatedAt:  last.CreatedAt,
+		TotalBytes: last.Summary.TotalBytes,
+		ID:         last.ID,
+	})
+}
@@ -0,0 +1,125 @@
+package scan
+
+import (
+	"context"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScanImage stores one image of a scan. Images live in their own collection
+// so large pages don't run into Mongo's 16MB document limit.
+type ScanImage struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	ScanID primitive.ObjectID `bson:"scan_id"`
+	// Index keeps the order in which the scan found the images.
+	Index int `bson:"index"`
+	// Host is the image's host without "www.", for first/third-party filters.
+	Host         string `bson:"host"`
+	simage.Image `bson:",inline"`
+}
+
+type ScanImageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewScanImageRepository(client *mongo.Client) *ScanImageRepository {
+	return &ScanImageRepository{
+		collection: client.Database("sharprenderdb").Collection("scan_images"),
+	}
+}
+
+// CreateMany stores the images of a scan in order.
+func (r *ScanImageRepository) CreateMany(ctx context.Context, scanID primitive.ObjectID, images []simage.Image) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(images))
+	for i, img := range images {
+		docs[i] = ScanImage{ScanID: scanID, Index: i, Host: hostname(img.Src), Image: img}
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// Find returns the matching images in the given order.
+func (r *ScanImageRepository) Find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]simage.Image, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	images := []simage.Image{}
+	for cursor.Next(ctx) {
+		var doc ScanImage
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		images = append(images, doc.Image)
+	}
+	return images, cursor.Err()
+}
+
+// FindForScan returns every image of a scan in the order they were found.
+func (r *ScanImageRepository) FindForScan(ctx context.Context, scanID primitive.ObjectID) ([]simage.Image, error) {
+	return r.Find(ctx, bson.M{"scan_id": scanID}, options.Find().SetSort(bson.M{"index": 1}))
+}
+
+// Filter returns the requested page of a scan's images matching the filters.
+func (r *ScanImageRepository) Filter(ctx context.Context, scan *Scan, filters FilterOptions) ([]simage.Image, error) {
+	opts := options.Find().SetSort(filters.sort()).SetSkip(int64(filters.Offset))
+	if filters.Limit > 0 {
+		opts.SetLimit(int64(filters.Limit))
+	}
+	return r.Find(ctx, filters.query(scan), opts)
+}
+
+// Overview totals the images matching filter, grouped by format in Mongo so
+// that no image is loaded.
+func (r *ScanImageRepository) Overview(ctx context.Context, filter interface{}) (*ImageOverview, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$format",
+			"image_count":     bson.M{"$sum": 1},
+			"total_size":      bson.M{"$sum": "$size"},
+			"total_load_time": bson.M{"$sum": bson.M{"$trunc": "$network.load_time"}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Format        string  `bson:"_id"`
+		ImageCount    int     `bson:"image_count"`
+		TotalSize     int64   `bson:"total_size"`
+		TotalLoadTime float64 `bson:"total_load_time"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	overview := &ImageOverview{Formats: map[string]int{}}
+	for _, g := range groups {
+		overview.ImageCount += g.ImageCount
+		overview.TotalSize += g.TotalSize
+		overview.TotalLoadTime += int64(g.TotalLoadTime)
+		overview.Formats[overviewFormat(g.Format)] += g.ImageCount
+	}
+	return overview, nil
+}
+
+// DeleteForScan removes every image of a scan.
+func (r *ScanImageRepository) DeleteForScan(ctx context.Context, scanID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"scan_id": scanID})
+	return err
+}
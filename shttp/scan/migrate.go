@@ -0,0 +1,72 @@
+package scan
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrateBatchSize bounds how many legacy scans are loaded at once.
+const migrateBatchSize = 50
+
+// MoveEmbeddedImages moves the images of scans stored before scan_images
+// existed out of their scan documents, filling in missing summaries on the
+// way. Scans already moved are skipped, so it can be run again.
+func MoveEmbeddedImages(ctx context.Context, client *mongo.Client) error {
+	repo := NewScanRepository(client)
+
+	moved := 0
+	for {
+		n, err := repo.migrateEmbeddedImages(ctx)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		moved += n
+	}
+	if moved > 0 {
+		log.Printf("Moved the images of %d scans to scan_images", moved)
+	}
+	return nil
+}
+
+// migrateEmbeddedImages migrates one batch of scans that still embed their
+// images and returns how many it migrated. A scan interrupted half way is
+// migrated again from scratch, as its images are only unset at the end.
+func (r *ScanRepository) migrateEmbeddedImages(ctx context.Context) (int, error) {
+	opts := options.Find().SetLimit(migrateBatchSize)
+	cursor, err := r.collection.Find(ctx, bson.M{"images": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var scans []Scan
+	if err := cursor.All(ctx, &scans); err != nil {
+		return 0, err
+	}
+
+	for _, scan := range scans {
+		if err := r.images.DeleteForScan(ctx, scan.ID); err != nil {
+			return 0, err
+		}
+		if err := r.images.CreateMany(ctx, scan.ID, scan.Images); err != nil {
+			return 0, err
+		}
+
+		update := bson.M{"$unset": bson.M{"images": ""}}
+		if scan.Summary == (ScanSummary{}) {
+			update["$set"] = bson.M{"summary": Summarize(scan.Images, scan.Findings)}
+		}
+		if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": scan.ID}, update); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(scans), nil
+}
@@ -1,30 +1,246 @@
 package scan
 
 import (
+	"strings"
 	"time"
 
 	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// SchemaVersion is the current layout of stored scans. Stores stamp it on
+// every scan they create; migrations bring older scans up to it.
+//
+//	1: images live in scan_images and summary is always set
+const SchemaVersion = 1
+
 type Scan struct {
-	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
-	ScanID    string                 `json:"scan_id" bson:"scan_id"`
-	UserID    string                 `json:"user_id" bson:"user_id"`
-	URL       string                 `json:"url" bson:"url"`
-	Metadata  simage.WebsiteMetadata `json:"metadata" bson:"metadata"`
-	Images    []simage.Image         `json:"images" bson:"images"`
-	CreatedAt time.Time              `json:"created_at" bson:"created_at"`
+	ID         primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	ScanID     string                  `json:"scan_id" bson:"scan_id"`
+	UserID     string                  `json:"user_id" bson:"user_id"`
+	ProjectID  *primitive.ObjectID     `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	URL        string                  `json:"url" bson:"url"`
+	Metadata   simage.WebsiteMetadata  `json:"metadata" bson:"metadata"`
+	Images     []simage.Image          `json:"images" bson:"images,omitempty"`
+	Findings   []simage.Finding        `json:"findings" bson:"findings"`
+	Duplicates []simage.DuplicateGroup `json:"duplicates" bson:"duplicates"`
+	Budgets    []simage.BudgetResult   `json:"budgets" bson:"budgets"`
+	Conditions simage.ScanConditions   `json:"conditions" bson:"conditions"`
+	Options    *ScanOptions            `json:"options,omitempty" bson:"options,omitempty"`
+	Summary    ScanSummary             `json:"summary" bson:"summary"`
+	Archived   bool                    `json:"archived" bson:"archived,omitempty"`
+	CrawlID    *primitive.ObjectID     `json:"crawl_id,omitempty" bson:"crawl_id,omitempty"`
+	ScheduleID *primitive.ObjectID     `json:"schedule_id,omitempty" bson:"schedule_id,omitempty"`
+	// PreviousScanID is the scan this one re-ran.
+	PreviousScanID *primitive.ObjectID `json:"previous_scan_id,omitempty" bson:"previous_scan_id,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	// ImagesPurgedAt is set once retention removed the scan's images and
+	// artifacts; the summary, findings and budgets remain.
+	ImagesPurgedAt *time.Time `json:"images_purged_at,omitempty" bson:"images_purged_at,omitempty"`
+	// SchemaVersion is the layout the scan was stored with; see SchemaVersion.
+	SchemaVersion int `json:"schema_version" bson:"schema_version"`
+
+	// Links found on the page; only kept in memory for crawls.
+	Links []string `json:"-" bson:"-"`
+}
+
+// ScanSummary holds the headline numbers of a scan, computed when it is stored
+// so history listings don't need the image array.
+type ScanSummary struct {
+	ImageCount    int   `json:"image_count" bson:"image_count"`
+	TotalBytes    int64 `json:"total_bytes" bson:"total_bytes"`
+	FindingsCount int   `json:"findings_count" bson:"findings_count"`
+}
+
+func Summarize(images []simage.Image, findings []simage.Finding) ScanSummary {
+	summary := ScanSummary{ImageCount: len(images), FindingsCount: len(findings)}
+	for _, img := range images {
+		summary.TotalBytes += int64(img.Size)
+	}
+	return summary
+}
+
+// rerunOptions returns the options the scan ran with. Scans stored before
+// options were kept get them rebuilt from their recorded conditions.
+func (s Scan) rerunOptions() ScanOptions {
+	if s.Options != nil {
+		return *s.Options
+	}
+
+	opts := ScanOptions{NetworkProfile: s.Conditions.Profile}
+	if s.Conditions.Profile == "Custom" {
+		opts = ScanOptions{Network: &CustomNetwork{
+			DownloadKbps: bytesToKbps(s.Conditions.Network.Download),
+			UploadKbps:   bytesToKbps(s.Conditions.Network.Upload),
+			LatencyMs:    s.Conditions.Network.Latency,
+		}}
+	}
+	if s.Conditions.CPUSlowdown > 1 {
+		opts.CPUSlowdown = s.Conditions.CPUSlowdown
+	}
+	return opts
+}
+
+// Report converts the scan into the input of the sreport writers.
+func (s Scan) Report() sreport.Report {
+	return sreport.Report{
+		URL:        s.URL,
+		Metadata:   s.Metadata,
+		Conditions: s.Conditions,
+		Images:     s.Images,
+		Findings:   s.Findings,
+		Duplicates: s.Duplicates,
+		Budgets:    s.Budgets,
+		Passed:     simage.BudgetsPassed(s.Budgets),
+		CreatedAt:  s.CreatedAt,
+
+		ImagesPurgedAt: s.ImagesPurgedAt,
+	}
+}
+
+// sensitiveResponseHeaders are dropped from redacted images; the rest stay, as
+// they explain caching and encoding.
+var sensitiveResponseHeaders = map[string]bool{
+	"set-cookie":         true,
+	"set-cookie2":        true,
+	"authorization":      true,
+	"www-authenticate":   true,
+	"proxy-authenticate": true,
+}
+
+// redactImages blanks the request headers, remote addresses and cookie or
+// auth response headers of images before they are shown through a share link.
+func redactImages(images []simage.Image) {
+	for i := range images {
+		images[i].Network.RequestHeaders = nil
+		images[i].Network.RemoteIPAddress = ""
+		images[i].Network.RemotePort = 0
+
+		headers := map[string]string{}
+		for name, value := range images[i].Network.ResponseHeaders {
+			if !sensitiveResponseHeaders[strings.ToLower(name)] {
+				headers[name] = value
+			}
+		}
+		images[i].Network.ResponseHeaders = headers
+	}
+}
+
+func (s Scan) imageRows() []sreport.ImageRow {
+	return sreport.ImageRows(s.ID.Hex(), s.URL, s.CreatedAt, s.Images, s.Findings)
 }
 
-type FilterOptions struct {
-	Size     *int64
-	ImgType  *string
-	LoadTime *int64
-	HostType *string
+// ShareLink grants unauthenticated read access to one scan until it expires
+// or is revoked. Only the SHA-256 hash of the token is stored.
+type ShareLink struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ScanID    primitive.ObjectID `json:"scan_id" bson:"scan_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Hash      string             `json:"-" bson:"hash"`
+	Redact    bool               `json:"redact" bson:"redact"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Active reports whether the link may still be used at the given time.
+func (l ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// ScanRequest describes a scan to run through ScanService.RunScan.
+type ScanRequest struct {
+	UserID     string
+	ProjectID  *primitive.ObjectID
+	URL        string
+	Options    ScanOptions
+	CrawlID    *primitive.ObjectID
+	ScheduleID *primitive.ObjectID
+	// PreviousScanID links a re-run to the scan it repeats.
+	PreviousScanID *primitive.ObjectID
+}
+
+// ScanOptions are the caller-supplied settings for a scan. Network takes
+// precedence over NetworkProfile when both are set.
+type ScanOptions struct {
+	NetworkProfile string         `json:"network_profile,omitempty" bson:"network_profile,omitempty"`
+	Network        *CustomNetwork `json:"network,omitempty" bson:"network,omitempty"`
+	CPUSlowdown    float64        `json:"cpu_slowdown,omitempty" bson:"cpu_slowdown,omitempty"`
+	// Optimize re-encodes every image to measure its savings and keeps the
+	// smaller encodings as artifacts. It multiplies the scan's CPU time, so
+	// it is off unless asked for.
+	Optimize bool `json:"optimize,omitempty" bson:"optimize,omitempty"`
+}
+
+// CustomNetwork describes network throttling in the units DevTools uses.
+type CustomNetwork struct {
+	DownloadKbps float64 `json:"download_kbps" bson:"download_kbps"`
+	UploadKbps   float64 `json:"upload_kbps" bson:"upload_kbps"`
+	LatencyMs    float64 `json:"latency_ms" bson:"latency_ms"`
 }
 
 type ScanResult struct {
-	Images       []simage.Image         `json:"images"`
-	Aggregations map[string]interface{} `json:"aggregations"`
+	Images []simage.Image `json:"images"`
+	// MatchedImages counts the images passing the filters before pagination.
+	MatchedImages int                     `json:"matched_images"`
+	Aggregations  map[string]interface{}  `json:"aggregations"`
+	Findings      []simage.Finding        `json:"findings"`
+	Duplicates    []simage.DuplicateGroup `json:"duplicates"`
+	Budgets       []simage.BudgetResult   `json:"budgets"`
+	Conditions    simage.ScanConditions   `json:"conditions"`
+}
+
+// ImageOverview totals the images of a scan that match a filter. Load times
+// are summed in whole units, dropping each image's fraction, as the dashboard
+// has always shown them.
+type ImageOverview struct {
+	ImageCount    int            `bson:"image_count"`
+	TotalSize     int64          `bson:"total_size"`
+	TotalLoadTime int64          `bson:"total_load_time"`
+	Formats       map[string]int `bson:"formats"`
+}
+
+// aggregations is the overview in the shape GET /scan/{id} returns.
+func (o *ImageOverview) aggregations() map[string]interface{} {
+	var avgSize, avgLoadTime float64
+	if o.ImageCount > 0 {
+		avgSize = float64(o.TotalSize) / float64(o.ImageCount)
+		avgLoadTime = float64(o.TotalLoadTime) / float64(o.ImageCount)
+	}
+
+	return map[string]interface{}{
+		"avgSize":            avgSize,
+		"totalSize":          o.TotalSize,
+		"avgLoadTime":        avgLoadTime,
+		"totalLoadTime":      o.TotalLoadTime,
+		"imageCount":         o.ImageCount,
+		"formatDistribution": o.Formats,
+	}
+}
+
+// add counts one image into the overview.
+func (o *ImageOverview) add(img simage.Image) {
+	o.ImageCount++
+	o.TotalSize += int64(img.Size)
+	o.TotalLoadTime += int64(img.Network.LoadTime)
+	o.Formats[overviewFormat(img.Format)]++
+}
+
+// overviewFormat groups images without a known format under "unknown".
+func overviewFormat(format string) string {
+	if format == "" {
+		return "unknown"
+	}
+	return format
+}
+
+// TrendPoint holds the headline numbers of one scan in a URL's history.
+type TrendPoint struct {
+	ScanID        primitive.ObjectID    `json:"scan_id" bson:"_id"`
+	CreatedAt     time.Time             `json:"created_at" bson:"created_at"`
+	TotalBytes    int64                 `json:"total_bytes" bson:"total_bytes"`
+	ImageCount    int                   `json:"image_count" bson:"image_count"`
+	FindingsCount int                   `json:"findings_count" bson:"findings_count"`
+	Conditions    simage.ScanConditions `json:"conditions" bson:"conditions"`
 }
@@ -0,0 +1,93 @@
+package scan
+
+import (
+	"fmt"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
+)
+
+// Regression kinds.
+const (
+	RegressionBytes   = "bytes"
+	RegressionFinding = "finding"
+	RegressionBudget  = "budget"
+)
+
+// RegressionCriteria says which changes make a scan worse than its baseline.
+// Alert rules and regression.detected webhooks both judge scans with it.
+type RegressionCriteria struct {
+	// BytesIncreasePercent counts total image bytes growing by more than this
+	// share of the baseline; 0 ignores bytes.
+	BytesIncreasePercent float64
+	// FindingSeverity counts new findings of at least this severity; empty
+	// ignores findings.
+	FindingSeverity string
+	// BudgetViolations counts failed budgets.
+	BudgetViolations bool
+}
+
+// Regression is one way a scan got worse. Key identifies the same regression
+// across scans.
+type Regression struct {
+	Kind    string `json:"kind" bson:"kind"`
+	Key     string `json:"key" bson:"key"`
+	Message string `json:"message" bson:"message"`
+}
+
+// Regressions lists the ways current is worse than baseline under the
+// criteria. Without a baseline only failed budgets count, as there is nothing
+// to grow from.
+func Regressions(criteria RegressionCriteria, baseline, current *Scan) []Regression {
+	var regressions []Regression
+
+	if criteria.BytesIncreasePercent > 0 && baseline != nil && baseline.Summary.TotalBytes > 0 {
+		before, after := baseline.Summary.TotalBytes, current.Summary.TotalBytes
+		growth := float64(after-before) / float64(before) * 100
+		if growth > criteria.BytesIncreasePercent {
+			regressions = append(regressions, Regression{
+				Kind:    RegressionBytes,
+				Key:     RegressionBytes,
+				Message: fmt.Sprintf("Total image bytes grew %.1f%%, from %s to %s", growth, sreport.FormatBytes(before), sreport.FormatBytes(after)),
+			})
+		}
+	}
+
+	if criteria.FindingSeverity != "" && baseline != nil {
+		known := map[string]bool{}
+		for _, f := range baseline.Findings {
+			known[findingKey(f)] = true
+		}
+		for _, f := range current.Findings {
+			key := findingKey(f)
+			if known[key] || simage.SeverityRank(f.Severity) < simage.SeverityRank(criteria.FindingSeverity) {
+				continue
+			}
+			regressions = append(regressions, Regression{
+				Kind:    RegressionFinding,
+				Key:     key,
+				Message: fmt.Sprintf("New %s finding %s: %s", f.Severity, f.RuleID, f.Message),
+			})
+		}
+	}
+
+	if criteria.BudgetViolations {
+		for _, b := range current.Budgets {
+			if b.Passed {
+				continue
+			}
+			regressions = append(regressions, Regression{
+				Kind:    RegressionBudget,
+				Key:     RegressionBudget + ":" + b.Name,
+				Message: fmt.Sprintf("Budget %q failed", b.Name),
+			})
+		}
+	}
+
+	return regressions
+}
+
+// findingKey identifies a finding regression across scans.
+func findingKey(f simage.Finding) string {
+	return RegressionFinding + ":" + simage.FindingKey(f)
+}
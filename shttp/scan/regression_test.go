@@ -0,0 +1,57 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/shttp/webhook"
+)
+
+func TestWebhookRegression(t *testing.T) {
+	baseline := &Scan{Summary: ScanSummary{TotalBytes: 1000}}
+	grown := &Scan{Summary: ScanSummary{TotalBytes: 1150}}
+	flagged := &Scan{
+		Summary:  ScanSummary{TotalBytes: 1000},
+		Findings: []simage.Finding{{RuleID: simage.RuleNearDuplicateImage, Severity: simage.SeverityInfo, Images: []string{"a.png"}}},
+		Budgets:  []simage.BudgetResult{{Name: "hero", Passed: false}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		endpoint webhook.Endpoint
+		current  *Scan
+		want     []string
+	}{
+		{"default threshold", webhook.Endpoint{}, grown, []string{RegressionBytes}},
+		{"higher threshold", webhook.Endpoint{RegressionPercent: 20}, grown, nil},
+		// Failed budgets have their own event.
+		{"new finding", webhook.Endpoint{RegressionPercent: 20}, flagged, []string{RegressionFinding}},
+	} {
+		var kinds []string
+		for _, r := range Regressions(webhookRegression(&tc.endpoint), baseline, tc.current) {
+			kinds = append(kinds, r.Kind)
+		}
+		if len(kinds) != len(tc.want) || (len(kinds) > 0 && kinds[0] != tc.want[0]) {
+			t.Errorf("%s: regressions = %v, want %v", tc.name, kinds, tc.want)
+		}
+	}
+}
+
+func TestRegressionsMatchKnownFindings(t *testing.T) {
+	criteria := RegressionCriteria{FindingSeverity: simage.SeverityInfo}
+	baseline := &Scan{Findings: []simage.Finding{
+		{RuleID: simage.RuleDuplicateImage, Severity: simage.SeverityWarning, Images: []string{"https://example.com/a.png?w=200", "https://example.com/b.png"}},
+	}}
+	// The same group, listed in another order and resized differently.
+	current := &Scan{Findings: []simage.Finding{
+		{RuleID: simage.RuleDuplicateImage, Severity: simage.SeverityWarning, Images: []string{"https://example.com/b.png", "https://example.com/a.png?w=400"}},
+	}}
+
+	if got := Regressions(criteria, baseline, current); len(got) != 0 {
+		t.Errorf("known finding reported as %+v", got)
+	}
+	diff := simage.DiffScans(nil, nil, baseline.Findings, current.Findings)
+	if len(diff.NewFindings) != 0 {
+		t.Errorf("diff reports %+v as new", diff.NewFindings)
+	}
+}
@@ -2,6 +2,7 @@ package scan
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,33 +10,98 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ScanRepository stores scans in the scans collection and their images in
+// scan_images. Scans are read without images unless a method says otherwise.
 type ScanRepository struct {
 	collection *mongo.Collection
+	images     *ScanImageRepository
 }
 
 func NewScanRepository(client *mongo.Client) *ScanRepository {
 	return &ScanRepository{
 		collection: client.Database("sharprenderdb").Collection("scans"),
+		images:     NewScanImageRepository(client),
 	}
 }
 
+// withoutImages leaves out images still embedded in scans stored before they
+// moved to scan_images.
+var withoutImages = bson.M{"images": 0}
+
 func (r *ScanRepository) FindOne(ctx context.Context, filter interface{}) (*Scan, error) {
 	var scan Scan
-	err := r.collection.FindOne(ctx, filter).Decode(&scan)
+	err := r.collection.FindOne(ctx, filter, options.FindOne().SetProjection(withoutImages)).Decode(&scan)
 	return &scan, err
 }
 
+// FindOneWithImages loads a scan with all of its images.
+func (r *ScanRepository) FindOneWithImages(ctx context.Context, filter interface{}) (*Scan, error) {
+	scan, err := r.FindOne(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	scan.Images, err = r.images.FindForScan(ctx, scan.ID)
+	if err != nil {
+		return nil, err
+	}
+	return scan, nil
+}
+
+// FilterImages loads the page of the scan's images selected by the filters.
+func (r *ScanRepository) FilterImages(ctx context.Context, scan *Scan, filters FilterOptions) error {
+	page, err := r.images.Filter(ctx, scan, filters)
+	if err != nil {
+		return err
+	}
+	scan.Images = page
+	return nil
+}
+
+// GetOverview totals the scan's images matching the filters in Mongo.
+func (r *ScanRepository) GetOverview(ctx context.Context, scan *Scan, filters FilterOptions) (*ImageOverview, error) {
+	return r.images.Overview(ctx, filters.query(scan))
+}
+
+// FindDiffPair loads the two scans of a diff with all their images.
+func (r *ScanRepository) FindDiffPair(ctx context.Context, baseFilter, headFilter interface{}) (*Scan, *Scan, error) {
+	base, err := r.FindOneWithImages(ctx, baseFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := r.FindOneWithImages(ctx, headFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, head, nil
+}
+
+// Create stores the scan and then its images. If the images can't be stored
+// the scan is removed again.
 func (r *ScanRepository) Create(ctx context.Context, scan *Scan) (primitive.ObjectID, error) {
-	result, err := r.collection.InsertOne(ctx, scan)
+	doc := *scan
+	doc.Images = nil
+	doc.SchemaVersion = SchemaVersion
+	result, err := r.collection.InsertOne(ctx, doc)
 	if err != nil {
 		return primitive.ObjectID{}, err
 	}
+	id := result.InsertedID.(primitive.ObjectID)
+
+	if err := r.images.CreateMany(ctx, id, scan.Images); err != nil {
+		r.collection.DeleteOne(ctx, bson.M{"_id": id})
+		r.images.DeleteForScan(ctx, id)
+		return primitive.ObjectID{}, err
+	}
 
-	return result.InsertedID.(primitive.ObjectID), nil
+	return id, nil
 }
 
+// FindMany returns every matching scan, oldest first.
 func (r *ScanRepository) FindMany(ctx context.Context, filter interface{}) ([]Scan, error) {
-	cursor, err := r.collection.Find(ctx, filter)
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetProjection(withoutImages)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -49,58 +115,86 @@ func (r *ScanRepository) FindMany(ctx context.Context, filter interface{}) ([]Sc
 	return scans, nil
 }
 
-// FindWithFilter fetches a scan document and filters its images using aggregation
-func (r *ScanRepository) FindWithFilter(ctx context.Context, scanFilter, imageFilter bson.M) (*Scan, error) {
-	// MongoDB aggregation pipeline
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: scanFilter}},  // Match scan document by ID
-		{{Key: "$unwind", Value: "$images"}},  // Unwind the images array
-		{{Key: "$match", Value: imageFilter}}, // Apply image-level filters
-		{
-			{Key: "$group", Value: bson.M{ // Regroup results into a single document
-				"_id":       "$_id",
-				"URL":       bson.M{"$first": "$URL"},
-				"images":    bson.M{"$push": "$images"},
-				"createdAt": bson.M{"$first": "$createdAt"},
-			}},
-		},
-	}
-
-	// Execute aggregation
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
+// Delete removes the matching scan and its images. It returns the number of
+// scans deleted.
+func (r *ScanRepository) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	var deleted Scan
+	err := r.collection.FindOneAndDelete(ctx, filter, options.FindOneAndDelete().SetProjection(bson.M{"_id": 1})).Decode(&deleted)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	return 1, r.images.DeleteForScan(ctx, deleted.ID)
+}
 
-	// Decode the result into a Scan struct
-	var results []Scan
-	if err := cursor.All(ctx, &results); err != nil {
-		return nil, err
+func (r *ScanRepository) PurgeImages(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	if err := r.images.DeleteForScan(ctx, id); err != nil {
+		return err
 	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"images_purged_at": at}})
+	return err
+}
 
-	// Return the first (and only) result or a "not found" error
-	if len(results) == 0 {
-		return nil, mongo.ErrNoDocuments
+// SetArchived sets the archive flag of the matching scan and returns the
+// number of scans matched.
+func (r *ScanRepository) SetArchived(ctx context.Context, filter interface{}, archived bool) (int64, error) {
+	update := bson.M{"$set": bson.M{"archived": true}}
+	if !archived {
+		update = bson.M{"$unset": bson.M{"archived": ""}}
 	}
 
-	return &results[0], nil
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
 }
 
-func (r *ScanRepository) GetScansOverview(ctx context.Context, filter interface{}) ([]Scan, error) {
-	opts := options.Find().SetProjection(bson.M{
-		"images": 0,
-	})
+// FindHistory returns up to limit scans matching filter and then after, in
+// the given order, without their images.
+func (r *ScanRepository) FindHistory(ctx context.Context, filter, after bson.M, sort bson.D, limit int) ([]Scan, error) {
+	opts := options.Find().SetSort(sort).SetLimit(int64(limit)).SetProjection(withoutImages)
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Find(ctx, bson.M{"$and": bson.A{filter, after}}, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var scans []Scan
-	if err = cursor.All(ctx, &scans); err != nil {
+	scans := []Scan{}
+	if err := cursor.All(ctx, &scans); err != nil {
 		return nil, err
 	}
 
 	return scans, nil
 }
+
+// GetTrend returns the stored summary of every matching scan, oldest first.
+func (r *ScanRepository) GetTrend(ctx context.Context, filter interface{}) ([]TrendPoint, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$project", Value: bson.M{
+			"created_at":     1,
+			"conditions":     1,
+			"total_bytes":    "$summary.total_bytes",
+			"image_count":    "$summary.image_count",
+			"findings_count": "$summary.findings_count",
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var points []TrendPoint
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
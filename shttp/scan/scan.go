@@ -1,19 +1,93 @@
 package scan
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/budget"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/usage"
+	"github.com/voage/sharprender-api/shttp/webhook"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewScanRoutes(mongoClient *mongo.Client) *chi.Mux {
-	repo := NewScanRepository(mongoClient)
-	service := NewScanService(repo)
-	handler := NewScanHandler(service, repo)
+func NewScanRoutes(mongoClient *mongo.Client, repo ScanStore, artifacts sblob.Store, quota *usage.UsageService) *chi.Mux {
+	shares := NewShareRepository(mongoClient)
+	service := NewScanService(repo, budget.NewBudgetRepository(mongoClient), artifacts, shares, org.NewAccessServiceFromClient(mongoClient), webhook.NewWebhookServiceFromClient(mongoClient))
+	handler := NewScanHandler(service, repo, NewShareService(shares, service))
+
+	router := scanRoutes(handler, quota.RequireQuota)
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireSession)
+		r.Post("/{id}/shares", handler.CreateShare)
+		r.Get("/{id}/shares", handler.GetShares)
+		r.Delete("/{id}/shares/{shareID}", handler.RevokeShare)
+	})
+
+	return router
+}
+
+// NewLocalScanRoutes serves a user's personal scans from repo on servers
+// running without MongoDB, so without projects, budgets, share links,
+// webhooks or quotas.
+func NewLocalScanRoutes(repo ScanStore, artifacts sblob.Store) *chi.Mux {
+	service := NewScanService(repo, nil, artifacts, nil, org.NewPersonalAccessService(), nil)
+	return scanRoutes(NewScanHandler(service, repo, nil), func(next http.Handler) http.Handler {
+		return next
+	})
+}
+
+// scanRoutes routes the scan endpoints shared by every server. Starting a
+// scan goes through quota.
+func scanRoutes(handler *ScanHandler, quota func(http.Handler) http.Handler) *chi.Mux {
+	router := chi.NewRouter()
+	router.With(auth.RequireScope(auth.ScopeScanCreate), quota).Post("/", handler.ScanURL)
+	router.With(auth.RequireScope(auth.ScopeScanCreate), quota).Post("/{id}/rerun", handler.RerunScan)
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeScanWrite))
+		r.Delete("/{id}", handler.DeleteScan)
+		r.Put("/{id}/archive", handler.ArchiveScan)
+		r.Delete("/{id}/archive", handler.ArchiveScan)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeScanRead))
+		r.Get("/{id}", handler.GetScanResults)
+		r.Get("/history", handler.GetScanHistory)
+		r.Get("/history/images.csv", handler.GetHistoryExport)
+		r.Get("/history/images.jsonl", handler.GetHistoryExport)
+		r.Get("/profiles", handler.GetNetworkProfiles)
+		r.Get("/diff", handler.GetScanDiff)
+		r.Get("/{id}/junit.xml", handler.GetScanReport)
+		r.Get("/{id}/report.sarif", handler.GetScanReport)
+		r.Get("/{id}/report.md", handler.GetScanReport)
+		r.Get("/{id}/report.html", handler.GetScanReport)
+		r.Get("/{id}/report.pdf", handler.GetScanReport)
+		r.Get("/{id}/images.csv", handler.GetImagesExport)
+		r.Get("/{id}/images.jsonl", handler.GetImagesExport)
+		r.Get("/{id}/artifacts/{name}", handler.GetScanArtifact)
+	})
+	return router
+}
+
+// NewSharedScanRoutes serves scans through share link tokens. It is mounted
+// outside the authenticated routes.
+func NewSharedScanRoutes(mongoClient *mongo.Client, repo ScanStore, artifacts sblob.Store) *chi.Mux {
+	shares := NewShareRepository(mongoClient)
+	service := NewScanService(repo, budget.NewBudgetRepository(mongoClient), artifacts, shares, org.NewAccessServiceFromClient(mongoClient), webhook.NewWebhookServiceFromClient(mongoClient))
+	handler := NewScanHandler(service, repo, NewShareService(shares, service))
 
 	router := chi.NewRouter()
-	router.Get("/{id}", handler.GetScanResults)
-	router.Post("/", handler.ScanURL)
-	router.Get("/history", handler.GetScanHistory)
+	router.Get("/{token}", handler.GetSharedResults)
+	for name := range reportFormats {
+		router.Get("/{token}/"+name, handler.GetSharedReport)
+	}
 
 	return router
 }
+
+// NewScanServiceFromClient builds the scan service other packages run scans through.
+func NewScanServiceFromClient(mongoClient *mongo.Client, scans ScanStore, artifacts sblob.Store) *ScanService {
+	return NewScanService(scans, budget.NewBudgetRepository(mongoClient), artifacts, NewShareRepository(mongoClient), org.NewAccessServiceFromClient(mongoClient), webhook.NewWebhookServiceFromClient(mongoClient))
+}
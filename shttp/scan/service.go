@@ -2,122 +2,441 @@ package scan
 
 import (
 	"context"
-	"net/http"
-	"strconv"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/voage/sharprender-api/internal/sblob"
 	"github.com/voage/sharprender-api/internal/simage"
+	"github.com/voage/sharprender-api/internal/sreport"
+	"github.com/voage/sharprender-api/shttp/budget"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/webhook"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ScanService struct {
-	repo *ScanRepository
+	repo      ScanStore
+	budgets   *budget.BudgetRepository
+	artifacts sblob.Store
+	shares    *ShareRepository
+	access    *org.AccessService
+	webhooks  *webhook.WebhookService
 }
 
-func NewScanService(repo *ScanRepository) *ScanService {
-	return &ScanService{repo: repo}
+// NewScanService builds the scan service. Budgets, shares and webhooks may be
+// nil on servers running without MongoDB; scans then skip budget checks and
+// events.
+func NewScanService(repo ScanStore, budgets *budget.BudgetRepository, artifacts sblob.Store, shares *ShareRepository, access *org.AccessService, webhooks *webhook.WebhookService) *ScanService {
+	return &ScanService{repo: repo, budgets: budgets, artifacts: artifacts, shares: shares, access: access, webhooks: webhooks}
 }
 
-// Parse the URL and store the query params in a FilterOptions struct
-func parseFilterOptions(r *http.Request) FilterOptions {
+// scope narrows a scan filter to the scans the user may access with the role:
+// their personal scans and those of their projects.
+func (s *ScanService) scope(ctx context.Context, userID string, filter bson.M, required org.Role) (bson.M, error) {
+	return s.access.Scope(ctx, userID, filter, required)
+}
+
+// Validate checks the options without starting a browser.
+func (o ScanOptions) Validate() error {
+	return o.apply(simage.NewImageScraper())
+}
 
-	var filters FilterOptions
+// Conditions resolves the emulation a scan with these options would run under.
+func (o ScanOptions) Conditions() (simage.ScanConditions, error) {
+	scraper := simage.NewImageScraper()
+	if err := o.apply(scraper); err != nil {
+		return simage.ScanConditions{}, err
+	}
+	return scraper.Conditions(), nil
+}
 
-	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
-		size, err := strconv.ParseInt(sizeStr, 10, 64)
+// apply configures the scraper's network and CPU emulation from the options.
+func (o ScanOptions) apply(scraper *simage.ImageScraper) error {
+	switch {
+	case o.Network != nil:
+		err := scraper.SetCustomNetwork(simage.NetworkProfile{
+			Download: kbpsToBytes(o.Network.DownloadKbps),
+			Upload:   kbpsToBytes(o.Network.UploadKbps),
+			Latency:  o.Network.LatencyMs,
+		})
 		if err != nil {
-			return filters
+			return fmt.Errorf("invalid network: %w", err)
+		}
+	case o.NetworkProfile != "":
+		if err := scraper.SetNetworkProfile(o.NetworkProfile); err != nil {
+			return err
+		}
+	default:
+		if err := scraper.SetNetworkProfile("No Throttling"); err != nil {
+			return err
 		}
-		filters.Size = &size
 	}
 
-	if imageType := r.URL.Query().Get("type"); imageType != "" {
-		filters.ImgType = &imageType
+	if o.CPUSlowdown != 0 {
+		if err := scraper.SetCPUSlowdown(o.CPUSlowdown); err != nil {
+			return err
+		}
 	}
 
-	if loadTimeStr := r.URL.Query().Get("loadTime"); loadTimeStr != "" {
-		loadTime, err := strconv.ParseInt(loadTimeStr, 10, 64)
-		if err != nil {
-			return filters
-		}
-		filters.LoadTime = &loadTime
+	return nil
+}
+
+// kbpsToBytes converts kilobits per second to the bytes per second CDP expects,
+// keeping -1 as "no throttling".
+func kbpsToBytes(kbps float64) float64 {
+	if kbps < 0 {
+		return -1
+	}
+	return kbps * 1000 / 8
+}
+
+// bytesToKbps undoes kbpsToBytes.
+func bytesToKbps(bytes float64) float64 {
+	if bytes < 0 {
+		return -1
+	}
+	return bytes * 8 / 1000
+}
+
+// RunScan scrapes the requested URL under its options, adds AI recommendations
+// and, when the options ask for them, optimized encodings, and stores the
+// resulting scan. Optimized images are kept as artifacts under scans/<id>/.
+// The outcome is published to the owner's webhooks.
+func (s *ScanService) RunScan(ctx context.Context, req ScanRequest) (*Scan, error) {
+	imageScraper := simage.NewImageScraper()
+	if err := req.Options.apply(imageScraper); err != nil {
+		return nil, err
 	}
 
-	if hostType := r.URL.Query().Get("hostType"); hostType != "" {
-		filters.HostType = &hostType
+	id := primitive.NewObjectID()
+	opts := simage.AnalyzeOptions{AIRecommendations: true}
+	if req.Options.Optimize {
+		opts.Optimize = &simage.DefaultOptimizeOptions
+		opts.Artifacts = s.artifacts
+		opts.ArtifactPrefix = ArtifactPrefix(id)
+	}
+	analysis, err := imageScraper.Analyze(ctx, req.URL, opts)
+	if err != nil {
+		s.publishFailed(context.Background(), req, err)
+		return nil, err
 	}
 
-	return filters
+	scan := Scan{
+		ID:         id,
+		UserID:     req.UserID,
+		ProjectID:  req.ProjectID,
+		URL:        req.URL,
+		Metadata:   analysis.Metadata,
+		Images:     analysis.Images,
+		Findings:   analysis.Findings,
+		Duplicates: analysis.Duplicates,
+		Budgets:    s.evaluateBudgets(ctx, req, analysis.Images),
+		Conditions: analysis.Conditions,
+		Options:    &req.Options,
+		Summary:    Summarize(analysis.Images, analysis.Findings),
+		CrawlID:    req.CrawlID,
+		ScheduleID: req.ScheduleID,
+		CreatedAt:  time.Now(),
+		Links:      analysis.Links,
+
+		PreviousScanID: req.PreviousScanID,
+	}
+
+	if _, err := s.repo.Create(context.Background(), &scan); err != nil {
+		return nil, fmt.Errorf("failed to create scan: %w", err)
+	}
+	s.publishCompleted(context.Background(), &scan)
+
+	return &scan, nil
 }
 
-// buildMongoFilterQuery builds a MongoDB query for filtering images
-func buildMongoFilterQuery(filters FilterOptions) bson.M {
-	query := bson.M{}
+// Artifact returns an optimized image stored for one of the user's scans.
+func (s *ScanService) Artifact(ctx context.Context, userID string, scanID primitive.ObjectID, name string) ([]byte, error) {
+	filter, err := s.scope(ctx, userID, bson.M{"_id": scanID}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.FindOne(ctx, filter); err == mongo.ErrNoDocuments {
+		return nil, sblob.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.artifacts.Get(ctx, ArtifactPrefix(scanID)+name)
+}
 
-	if filters.Size != nil {
-		query["images.size"] = bson.M{"$gt": *filters.Size}
+// RerunScan runs a scan the user may edit again with the same URL, project
+// and options, linking the new scan to it.
+func (s *ScanService) RerunScan(ctx context.Context, userID string, id primitive.ObjectID) (*Scan, error) {
+	filter, err := s.scope(ctx, userID, bson.M{"_id": id}, org.RoleEditor)
+	if err != nil {
+		return nil, err
 	}
-	if filters.ImgType != nil {
-		query["images.format"] = *filters.ImgType
+	previous, err := s.repo.FindOne(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	if filters.LoadTime != nil {
-		query["images.network.loadTime"] = bson.M{"$gt": *filters.LoadTime}
+
+	return s.RunScan(ctx, ScanRequest{
+		UserID:         userID,
+		ProjectID:      previous.ProjectID,
+		URL:            previous.URL,
+		Options:        previous.rerunOptions(),
+		PreviousScanID: &previous.ID,
+	})
+}
+
+// SetArchived archives or restores a scan the user may edit. Archived scans
+// are left out of the history unless asked for.
+func (s *ScanService) SetArchived(ctx context.Context, userID string, id primitive.ObjectID, archived bool) error {
+	filter, err := s.scope(ctx, userID, bson.M{"_id": id}, org.RoleEditor)
+	if err != nil {
+		return err
 	}
-	if filters.HostType != nil {
-		if *filters.HostType == "first-party" {
-			query["images.network.initiatorURL"] = bson.M{"$eq": "images.network.documentURL"}
-		} else if *filters.HostType == "third-party" {
-			query["images.network.initiatorURL"] = bson.M{"$ne": "images.network.documentURL"}
+
+	matched, err := s.repo.SetArchived(ctx, filter, archived)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteScan deletes a scan the user may edit along with its artifacts and
+// share links. The scan goes last, so after a failure it can still be found
+// and deleting it again finishes the job.
+func (s *ScanService) DeleteScan(ctx context.Context, userID string, id primitive.ObjectID) error {
+	filter, err := s.scope(ctx, userID, bson.M{"_id": id}, org.RoleEditor)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.FindOne(ctx, filter); err != nil {
+		return err
+	}
+
+	if err := s.artifacts.DeletePrefix(ctx, ArtifactPrefix(id)); err != nil {
+		return fmt.Errorf("failed to delete artifacts: %w", err)
+	}
+	if s.shares != nil {
+		if err := s.shares.DeleteForScan(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete share links: %w", err)
 		}
 	}
 
-	return query
+	deleted, err := s.repo.Delete(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
 
-// calculateAggregations calculates metrics for all images
-func calculateAggregations(images []simage.Image) map[string]interface{} {
-	var totalSize, totalLoadTime int64
-	var avgSize, avgLoadTime float64
-	var formatDistribution map[string]int = make(map[string]int)
+// ArtifactPrefix is the blob key prefix of a scan's optimized images.
+func ArtifactPrefix(scanID primitive.ObjectID) string {
+	return "scans/" + scanID.Hex() + "/"
+}
 
-	for _, img := range images {
-		totalSize += int64(img.Size)
-		totalLoadTime += int64(img.Network.LoadTime)
-		format := img.Format
-		if format == "" {
-			format = "unknown" // Categorize empty formats as "unknown" for now; fix later.
-		}
-		formatDistribution[format]++
+// evaluateBudgets checks the images against every budget matching the URL. A
+// failure to load budgets is logged rather than failing the scan.
+func (s *ScanService) evaluateBudgets(ctx context.Context, req ScanRequest, images []simage.Image) []simage.BudgetResult {
+	results := []simage.BudgetResult{}
+	if s.budgets == nil {
+		return results
 	}
 
-	count := len(images)
-	if count > 0 {
-		avgSize = float64(totalSize) / float64(count)
-		avgLoadTime = float64(totalLoadTime) / float64(count)
+	budgets, err := s.budgets.FindMatching(ctx, req.UserID, req.ProjectID, req.URL)
+	if err != nil {
+		log.Printf("Warning: failed to load budgets for %s: %v", req.URL, err)
+		return results
 	}
 
-	return map[string]interface{}{
-		"avgSize":            avgSize,
-		"totalSize":          totalSize,
-		"avgLoadTime":        avgLoadTime,
-		"totalLoadTime":      totalLoadTime,
-		"imageCount":         count,
-		"formatDistribution": formatDistribution,
+	for _, b := range budgets {
+		results = append(results, b.Evaluate(images))
 	}
+	return results
 }
 
-func (s *ScanService) fetchScanResult(ctx context.Context, id primitive.ObjectID, filters FilterOptions) (*ScanResult, error) {
-	imageFilter := buildMongoFilterQuery(filters)
+func (s *ScanService) fetchScanResult(ctx context.Context, userID string, id primitive.ObjectID, filters FilterOptions) (*ScanResult, error) {
+	scanFilter, err := s.scope(ctx, userID, bson.M{"_id": id}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
 
-	scan, err := s.repo.FindWithFilter(ctx, bson.M{"_id": id}, imageFilter)
+	scan, err := s.repo.FindOne(ctx, scanFilter)
 	if err != nil {
 		return nil, err
 	}
+	return s.filteredResult(ctx, scan, filters)
+}
 
-	aggregations := calculateAggregations(scan.Images)
+// filteredResult loads the page of the scan's images selected by the filters
+// and summarizes every matching image, not only the returned page.
+func (s *ScanService) filteredResult(ctx context.Context, scan *Scan, filters FilterOptions) (*ScanResult, error) {
+	if err := s.repo.FilterImages(ctx, scan, filters); err != nil {
+		return nil, err
+	}
+	overview, err := s.repo.GetOverview(ctx, scan, filters)
+	if err != nil {
+		return nil, err
+	}
 
 	return &ScanResult{
-		Images:       scan.Images,
-		Aggregations: aggregations,
+		Images:        scan.Images,
+		MatchedImages: overview.ImageCount,
+		Aggregations:  overview.aggregations(),
+		Findings:      scan.Findings,
+		Duplicates:    scan.Duplicates,
+		Budgets:       scan.Budgets,
+		Conditions:    scan.Conditions,
 	}, nil
 }
+
+// ExportImages flattens the images of one of the user's scans for CSV or JSON
+// Lines export, filtered like GetScanResults.
+func (s *ScanService) ExportImages(ctx context.Context, userID string, id primitive.ObjectID, filters FilterOptions) ([]sreport.ImageRow, error) {
+	scanFilter, err := s.scope(ctx, userID, bson.M{"_id": id}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := s.repo.FindOne(ctx, scanFilter)
+	if err != nil {
+		return nil, err
+	}
+	if scan.ImagesPurgedAt != nil {
+		return nil, ErrImagesPurged
+	}
+	if err := s.repo.FilterImages(ctx, scan, filters); err != nil {
+		return nil, err
+	}
+	return scan.imageRows(), nil
+}
+
+// ExportHistory flattens the filtered images of every scan the user can see,
+// or of one project, run between from and to, either of which may be nil for
+// an open range. Rows are handed to emit one scan at a time, so only one
+// scan's images are held in memory; access errors are returned before emit
+// is first called.
+func (s *ScanService) ExportHistory(ctx context.Context, userID string, projectID *primitive.ObjectID, from, to *time.Time, filters FilterOptions, emit func([]sreport.ImageRow) error) error {
+	filter, err := s.HistoryFilter(ctx, userID, projectID, from, to)
+	if err != nil {
+		return err
+	}
+
+	scans, err := s.repo.FindMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, scan := range scans {
+		if err := s.repo.FilterImages(ctx, &scan, filters); err != nil {
+			return err
+		}
+		if err := emit(scan.imageRows()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTrend returns the headline numbers of every scan matching the filter,
+// oldest first, keeping only scans run under the given conditions.
+func (s *ScanService) GetTrend(ctx context.Context, filter bson.M, conditions simage.ScanConditions) ([]TrendPoint, error) {
+	points, err := s.repo.GetTrend(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	comparable := []TrendPoint{}
+	for _, p := range points {
+		if p.Conditions.Matches(conditions) {
+			comparable = append(comparable, p)
+		}
+	}
+	return comparable, nil
+}
+
+// ErrIncomparableScans is returned when two scans ran under different network
+// or CPU emulation and their numbers can't be compared.
+var ErrIncomparableScans = errors.New("scans ran under different conditions")
+
+// ErrImagesPurged means retention removed the images a request needs.
+var ErrImagesPurged = errors.New("scan images were removed by retention")
+
+// HistoryFilter matches the scans the user can see, or those of one project,
+// created between from and to.
+func (s *ScanService) HistoryFilter(ctx context.Context, userID string, projectID *primitive.ObjectID, from, to *time.Time) (bson.M, error) {
+	filter := bson.M{}
+	createdAt := bson.M{}
+	if from != nil {
+		createdAt["$gte"] = *from
+	}
+	if to != nil {
+		createdAt["$lte"] = *to
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return s.access.ScopeProject(ctx, userID, projectID, filter, org.RoleViewer)
+}
+
+// GetHistory returns one page of the scans the user can see, or those of one
+// project, and the cursor of the next page.
+func (s *ScanService) GetHistory(ctx context.Context, userID string, projectID *primitive.ObjectID, q *HistoryQuery) ([]Scan, string, error) {
+	filter, err := s.HistoryFilter(ctx, userID, projectID, q.From, q.To)
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Search != "" {
+		filter = bson.M{"$and": bson.A{filter, searchFilter(q.Search)}}
+	}
+	if archived := q.archivedFilter(); archived != nil {
+		filter = bson.M{"$and": bson.A{filter, archived}}
+	}
+
+	scans, err := s.repo.FindHistory(ctx, filter, q.after(), q.sort(), q.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	more := len(scans) > q.Limit
+	if more {
+		scans = scans[:q.Limit]
+	}
+	return scans, q.nextCursor(scans, more), nil
+}
+
+// DiffScans compares two stored scans the user can see image by image.
+func (s *ScanService) DiffScans(ctx context.Context, userID string, baseID, headID primitive.ObjectID) (*simage.ScanDiff, error) {
+	baseFilter, err := s.scope(ctx, userID, bson.M{"_id": baseID}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	headFilter, err := s.scope(ctx, userID, bson.M{"_id": headID}, org.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	base, head, err := s.repo.FindDiffPair(ctx, baseFilter, headFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.ImagesPurgedAt != nil || head.ImagesPurgedAt != nil {
+		return nil, ErrImagesPurged
+	}
+	if !base.Conditions.Matches(head.Conditions) {
+		return nil, ErrIncomparableScans
+	}
+
+	diff := simage.DiffScans(base.Images, head.Images, base.Findings, head.Findings)
+	return &diff, nil
+}
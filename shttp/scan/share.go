@@ -0,0 +1,261 @@
+package scan
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	shareTokenBytes = 32
+	// defaultShareTTL and maxShareTTL bound how long a share link stays valid.
+	defaultShareTTL = 7 * 24 * time.Hour
+	maxShareTTL     = 90 * 24 * time.Hour
+)
+
+// ErrShareNotFound covers unknown, expired and revoked share tokens alike.
+var ErrShareNotFound = errors.New("share link not found")
+
+type ShareRepository struct {
+	collection *mongo.Collection
+}
+
+func NewShareRepository(client *mongo.Client) *ShareRepository {
+	return &ShareRepository{
+		collection: client.Database("sharprenderdb").Collection("share_links"),
+	}
+}
+
+func (r *ShareRepository) Create(ctx context.Context, link *ShareLink) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, link)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *ShareRepository) FindOne(ctx context.Context, filter interface{}) (*ShareLink, error) {
+	var link ShareLink
+	err := r.collection.FindOne(ctx, filter).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (r *ShareRepository) FindMany(ctx context.Context, filter interface{}) ([]ShareLink, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []ShareLink{}
+	if err = cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Revoke marks a link of the scan as revoked. It returns the number of links
+// changed, which is 0 for unknown or already revoked links.
+func (r *ShareRepository) Revoke(ctx context.Context, id, scanID primitive.ObjectID, now time.Time) (int64, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "scan_id": scanID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteForScan removes every link of a deleted scan.
+func (r *ShareRepository) DeleteForScan(ctx context.Context, scanID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"scan_id": scanID})
+	return err
+}
+
+// ShareService manages share links and resolves their tokens to scans.
+type ShareService struct {
+	repo  *ShareRepository
+	scans *ScanService
+}
+
+func NewShareService(repo *ShareRepository, scans *ScanService) *ShareService {
+	return &ShareService{repo: repo, scans: scans}
+}
+
+// authorizeScan checks that the user may reach the scan with the role.
+// Publishing a scan needs editor rights on its project; listing links needs viewer.
+func (s *ShareService) authorizeScan(ctx context.Context, userID string, scanID primitive.ObjectID, required org.Role) error {
+	filter, err := s.scans.scope(ctx, userID, bson.M{"_id": scanID}, required)
+	if err != nil {
+		return err
+	}
+	_, err = s.scans.repo.FindOne(ctx, filter)
+	return err
+}
+
+// CreateShare creates a link to the scan and returns it with its token, which
+// is not stored and can't be shown again. A nil expiresAt means the default TTL.
+func (s *ShareService) CreateShare(ctx context.Context, userID string, scanID primitive.ObjectID, expiresAt *time.Time, redact bool) (*ShareLink, string, error) {
+	now := time.Now()
+	expires := now.Add(defaultShareTTL)
+	if expiresAt != nil {
+		expires = *expiresAt
+	}
+	if !expires.After(now) {
+		return nil, "", errors.New("expires_at must be in the future")
+	}
+	if expires.Sub(now) > maxShareTTL {
+		return nil, "", fmt.Errorf("share links may be valid for at most %s", maxShareTTL)
+	}
+
+	if err := s.authorizeScan(ctx, userID, scanID, org.RoleEditor); err != nil {
+		return nil, "", err
+	}
+
+	raw := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	link := ShareLink{
+		ScanID:    scanID,
+		UserID:    userID,
+		Hash:      hashShareToken(token),
+		Redact:    redact,
+		ExpiresAt: expires,
+		CreatedAt: now,
+	}
+
+	id, err := s.repo.Create(ctx, &link)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create share link: %w", err)
+	}
+	link.ID = id
+
+	return &link, token, nil
+}
+
+// GetShares lists the links of a scan the user can see.
+func (s *ShareService) GetShares(ctx context.Context, userID string, scanID primitive.ObjectID) ([]ShareLink, error) {
+	if err := s.authorizeScan(ctx, userID, scanID, org.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.FindMany(ctx, bson.M{"scan_id": scanID})
+}
+
+// RevokeShare revokes a link of a scan the user may publish.
+func (s *ShareService) RevokeShare(ctx context.Context, userID string, scanID, shareID primitive.ObjectID) error {
+	if err := s.authorizeScan(ctx, userID, scanID, org.RoleEditor); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.Revoke(ctx, shareID, scanID, time.Now())
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	link := ShareLink{ID: shareID, ScanID: scanID}
+	if err := s.scans.artifacts.DeletePrefix(ctx, sharePrefix(&link)); err != nil {
+		log.Printf("Failed to delete reports of share link %s: %v", shareID.Hex(), err)
+	}
+	return nil
+}
+
+// Resolve returns the active link for a token.
+func (s *ShareService) Resolve(ctx context.Context, token string) (*ShareLink, error) {
+	link, err := s.repo.FindOne(ctx, bson.M{"hash": hashShareToken(token)})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !link.Active(time.Now()) {
+		return nil, ErrShareNotFound
+	}
+	return link, nil
+}
+
+// SharedResult returns the results of the scan behind a link with the image
+// filters applied, redacted if the link asks for it.
+func (s *ShareService) SharedResult(ctx context.Context, link *ShareLink, filters FilterOptions) (*ScanResult, error) {
+	scan, err := s.scans.repo.FindOne(ctx, bson.M{"_id": link.ScanID})
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.scans.filteredResult(ctx, scan, filters)
+	if err != nil {
+		return nil, err
+	}
+	if link.Redact {
+		redactImages(result.Images)
+	}
+	return result, nil
+}
+
+// SharedReport returns a report of the scan behind a link, rendering it on
+// first use and caching it with the scan's artifacts, so it is removed with
+// them.
+func (s *ShareService) SharedReport(ctx context.Context, link *ShareLink, name string) ([]byte, error) {
+	key := sharePrefix(link) + name
+	if data, err := s.scans.artifacts.Get(ctx, key); err == nil {
+		return data, nil
+	} else if !errors.Is(err, sblob.ErrNotFound) {
+		return nil, err
+	}
+
+	scan, err := s.scans.repo.FindOneWithImages(ctx, bson.M{"_id": link.ScanID})
+	if err != nil {
+		return nil, err
+	}
+	if link.Redact {
+		redactImages(scan.Images)
+	}
+
+	data, err := renderReport(ctx, name, scan)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.scans.artifacts.Put(ctx, key, data); err != nil {
+		log.Printf("Failed to cache shared report %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// sharePrefix holds the reports rendered for a link.
+func sharePrefix(link *ShareLink) string {
+	return ArtifactPrefix(link.ScanID) + "shares/" + link.ID.Hex() + "/"
+}
+
+// hashShareToken hashes the whole token. Tokens are 256 random bits, so a
+// plain SHA-256 is enough.
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultBoltPath = "data/scans.db"
+
+// ScanStore keeps scans and their images. Filters are Mongo query documents;
+// stores without Mongo evaluate the same documents themselves. Lookups that
+// find nothing return mongo.ErrNoDocuments whatever the backend.
+type ScanStore interface {
+	// Create stores a scan with its images and returns its ID.
+	Create(ctx context.Context, scan *Scan) (primitive.ObjectID, error)
+	// FindOne returns the first matching scan without its images.
+	FindOne(ctx context.Context, filter interface{}) (*Scan, error)
+	// FindOneWithImages returns the first matching scan with all its images.
+	FindOneWithImages(ctx context.Context, filter interface{}) (*Scan, error)
+	// FilterImages sets scan.Images to the page of its images selected by
+	// the filters.
+	FilterImages(ctx context.Context, scan *Scan, filters FilterOptions) error
+	// GetOverview totals every image of the scan matching the filters,
+	// ignoring their offset and limit.
+	GetOverview(ctx context.Context, scan *Scan, filters FilterOptions) (*ImageOverview, error)
+	// FindDiffPair returns the scans matching the base and head filters with
+	// all their images, or mongo.ErrNoDocuments if either is missing.
+	FindDiffPair(ctx context.Context, baseFilter, headFilter interface{}) (base, head *Scan, err error)
+	// FindMany returns every matching scan without images, oldest first.
+	FindMany(ctx context.Context, filter interface{}) ([]Scan, error)
+	// FindHistory returns up to limit scans matching filter and after, in
+	// the given order, without images.
+	FindHistory(ctx context.Context, filter, after bson.M, sort bson.D, limit int) ([]Scan, error)
+	// GetTrend returns the summary of every matching scan, oldest first.
+	GetTrend(ctx context.Context, filter interface{}) ([]TrendPoint, error)
+	// Delete removes the first matching scan and its images and returns the
+	// number of scans removed.
+	Delete(ctx context.Context, filter interface{}) (int64, error)
+	// PurgeImages removes a scan's images and marks it with ImagesPurgedAt.
+	PurgeImages(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// SetArchived sets the archive flag of the first matching scan and
+	// returns the number of scans matched.
+	SetArchived(ctx context.Context, filter interface{}, archived bool) (int64, error)
+}
+
+var (
+	_ ScanStore = (*ScanRepository)(nil)
+	_ ScanStore = (*BoltScanStore)(nil)
+)
+
+// NewStoreFromEnv opens the scan store named by SCAN_STORE: "mongo" (the
+// default) keeps scans in MongoDB, "bolt" in a local file at SCAN_STORE_PATH,
+// defaulting to ./data/scans.db.
+func NewStoreFromEnv(client *mongo.Client) (ScanStore, error) {
+	switch backend := os.Getenv("SCAN_STORE"); backend {
+	case "", "mongo":
+		return NewScanRepository(client), nil
+	case "bolt":
+		path := os.Getenv("SCAN_STORE_PATH")
+		if path == "" {
+			path = defaultBoltPath
+		}
+		return OpenBoltScanStore(path)
+	default:
+		return nil, fmt.Errorf("unknown SCAN_STORE %q, want mongo or bolt", backend)
+	}
+}
@@ -0,0 +1,308 @@
+package scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/simage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBoltScanStore(t *testing.T) {
+	store, err := OpenBoltScanStore(filepath.Join(t.TempDir(), "scans.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	testScanStore(t, store)
+}
+
+// TestMongoScanStore runs against the database at MONGO_TEST_URI. Its scans
+// belong to a fresh user and are deleted afterwards. CI must provide a
+// database, so there a missing URI fails rather than skips.
+func TestMongoScanStore(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" && os.Getenv("CI") != "" {
+		t.Fatal("MONGO_TEST_URI must be set in CI")
+	}
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	testScanStore(t, NewScanRepository(client))
+}
+
+// testScanStore is the behavior every ScanStore must share.
+func testScanStore(t *testing.T, store ScanStore) {
+	ctx := context.Background()
+	user := "conformance-" + primitive.NewObjectID().Hex()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	newScan := func(url string, age time.Duration, images ...simage.Image) *Scan {
+		return &Scan{
+			ID:        primitive.NewObjectID(),
+			UserID:    user,
+			URL:       url,
+			Images:    images,
+			Summary:   Summarize(images, nil),
+			CreatedAt: now.Add(-age),
+		}
+	}
+	image := func(src, format string, size int, loadTime float64) simage.Image {
+		return simage.Image{Src: src, Format: format, Size: size, Network: simage.NetworkInfo{LoadTime: loadTime}}
+	}
+
+	small := newScan("https://example.com/", 3*time.Hour,
+		image("https://example.com/a.jpg", "jpeg", 300, 0.3),
+		image("https://cdn.example.com/b.png", "png", 100, 0.1),
+		image("https://tracker.net/c.gif", "gif", 200, 0.2),
+	)
+	large := newScan("https://example.com/blog", 2*time.Hour,
+		image("https://example.com/hero.jpg", "jpeg", 5000, 1.5),
+	)
+	empty := newScan("https://other.org/", time.Hour)
+	scans := []*Scan{small, large, empty}
+
+	for _, s := range scans {
+		id, err := store.Create(ctx, s)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if id != s.ID {
+			t.Fatalf("Create returned %s, want %s", id.Hex(), s.ID.Hex())
+		}
+	}
+	defer func() {
+		for _, s := range scans {
+			store.Delete(ctx, bson.M{"_id": s.ID})
+		}
+	}()
+
+	t.Run("FindOne", func(t *testing.T) {
+		got, err := store.FindOne(ctx, bson.M{"_id": small.ID, "user_id": user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.URL != small.URL || len(got.Images) != 0 || !got.CreatedAt.Equal(small.CreatedAt) {
+			t.Errorf("FindOne = %s with %d images at %s", got.URL, len(got.Images), got.CreatedAt)
+		}
+		if got.SchemaVersion != SchemaVersion {
+			t.Errorf("schema_version = %d, want %d", got.SchemaVersion, SchemaVersion)
+		}
+
+		_, err = store.FindOne(ctx, bson.M{"_id": primitive.NewObjectID()})
+		if err != mongo.ErrNoDocuments {
+			t.Errorf("FindOne of an unknown scan: err = %v, want ErrNoDocuments", err)
+		}
+	})
+
+	t.Run("FindOneWithImages", func(t *testing.T) {
+		got, err := store.FindOneWithImages(ctx, bson.M{"_id": small.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Images) != 3 || got.Images[0].Src != small.Images[0].Src || got.Images[2].Src != small.Images[2].Src {
+			t.Errorf("FindOneWithImages images = %v, want the 3 images in order", got.Images)
+		}
+	})
+
+	t.Run("FilterImages", func(t *testing.T) {
+		sizeMin := int64(150)
+		tests := []struct {
+			name    string
+			filters FilterOptions
+			page    []string
+			matched int
+		}{
+			{"all", FilterOptions{}, []string{"a.jpg", "b.png", "c.gif"}, 3},
+			{"size", FilterOptions{SizeMin: &sizeMin, Sort: "size"}, []string{"c.gif", "a.jpg"}, 2},
+			{"format", FilterOptions{Formats: []string{"png", "gif"}}, []string{"b.png", "c.gif"}, 2},
+			{"first party", FilterOptions{Party: "first"}, []string{"a.jpg", "b.png"}, 2},
+			{"third party", FilterOptions{Party: "third"}, []string{"c.gif"}, 1},
+			{"page", FilterOptions{Sort: "load_time", Desc: true, Limit: 1, Offset: 1}, []string{"c.gif"}, 3},
+		}
+		for _, tt := range tests {
+			scan, err := store.FindOne(ctx, bson.M{"_id": small.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.FilterImages(ctx, scan, tt.filters); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			overview, err := store.GetOverview(ctx, scan, tt.filters)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if overview.ImageCount != tt.matched {
+				t.Errorf("%s: %d matched, want %d", tt.name, overview.ImageCount, tt.matched)
+			}
+			if len(scan.Images) != len(tt.page) {
+				t.Errorf("%s: page has %d images, want %v", tt.name, len(scan.Images), tt.page)
+				continue
+			}
+			for i, img := range scan.Images {
+				if filepath.Base(img.Src) != tt.page[i] {
+					t.Errorf("%s: image %d is %s, want %s", tt.name, i, img.Src, tt.page[i])
+				}
+			}
+		}
+	})
+
+	t.Run("GetOverview", func(t *testing.T) {
+		overview, err := store.GetOverview(ctx, small, FilterOptions{Party: "first"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if overview.ImageCount != 2 || overview.TotalSize != 400 || overview.Formats["jpeg"] != 1 || overview.Formats["png"] != 1 {
+			t.Errorf("first party overview = %+v", overview)
+		}
+
+		overview, err = store.GetOverview(ctx, large, FilterOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Load times are summed in whole units.
+		if overview.TotalLoadTime != 1 {
+			t.Errorf("total load time = %d, want 1", overview.TotalLoadTime)
+		}
+
+		overview, err = store.GetOverview(ctx, empty, FilterOptions{})
+		if err != nil || overview.ImageCount != 0 || len(overview.Formats) != 0 {
+			t.Errorf("empty scan overview = %+v, %v", overview, err)
+		}
+	})
+
+	t.Run("FindDiffPair", func(t *testing.T) {
+		base, head, err := store.FindDiffPair(ctx, bson.M{"_id": small.ID, "user_id": user}, bson.M{"_id": large.ID, "user_id": user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base.ID != small.ID || len(base.Images) != 3 || head.ID != large.ID || len(head.Images) != 1 {
+			t.Errorf("FindDiffPair = %s with %d images, %s with %d", base.URL, len(base.Images), head.URL, len(head.Images))
+		}
+
+		_, _, err = store.FindDiffPair(ctx, bson.M{"_id": small.ID}, bson.M{"_id": primitive.NewObjectID()})
+		if err != mongo.ErrNoDocuments {
+			t.Errorf("FindDiffPair with a missing head: err = %v, want ErrNoDocuments", err)
+		}
+	})
+
+	t.Run("FindHistory", func(t *testing.T) {
+		q := &HistoryQuery{Sort: "total_bytes", Desc: true, Limit: 2}
+		page, err := store.FindHistory(ctx, bson.M{"user_id": user}, q.after(), q.sort(), q.Limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || page[0].ID != large.ID || page[1].ID != small.ID {
+			t.Fatalf("first page = %v, want large and small", scanURLs(page))
+		}
+
+		q.Cursor, _ = decodeCursor(q.nextCursor(page, true))
+		page, err = store.FindHistory(ctx, bson.M{"user_id": user}, q.after(), q.sort(), q.Limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != empty.ID {
+			t.Errorf("second page = %v, want empty", scanURLs(page))
+		}
+
+		filter := bson.M{"$and": bson.A{
+			bson.M{"user_id": user, "project_id": nil, "created_at": bson.M{"$gte": now.Add(-150 * time.Minute)}},
+			searchFilter("EXAMPLE"),
+		}}
+		page, err = store.FindHistory(ctx, filter, bson.M{}, bson.D{{Key: "created_at", Value: 1}}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != large.ID {
+			t.Errorf("search and date range = %v, want large", scanURLs(page))
+		}
+	})
+
+	t.Run("SetArchived", func(t *testing.T) {
+		matched, err := store.SetArchived(ctx, bson.M{"_id": empty.ID}, true)
+		if err != nil || matched != 1 {
+			t.Fatalf("SetArchived = %d, %v", matched, err)
+		}
+		archived := (&HistoryQuery{Archived: "only"}).archivedFilter()
+		page, err := store.FindHistory(ctx, bson.M{"$and": bson.A{bson.M{"user_id": user}, archived}}, bson.M{}, bson.D{{Key: "_id", Value: 1}}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != empty.ID || !page[0].Archived {
+			t.Errorf("archived scans = %v, want empty", scanURLs(page))
+		}
+
+		if _, err := store.SetArchived(ctx, bson.M{"_id": empty.ID}, false); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.FindOne(ctx, bson.M{"_id": empty.ID, "archived": bson.M{"$ne": true}})
+		if err != nil || got.Archived {
+			t.Errorf("unarchived scan: %v, %v", got, err)
+		}
+	})
+
+	t.Run("GetTrend", func(t *testing.T) {
+		points, err := store.GetTrend(ctx, bson.M{"user_id": user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 3 || points[0].ScanID != small.ID || points[0].TotalBytes != 600 || points[0].ImageCount != 3 {
+			t.Errorf("GetTrend = %+v", points)
+		}
+	})
+
+	t.Run("PurgeImages", func(t *testing.T) {
+		if err := store.PurgeImages(ctx, large.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.FindOneWithImages(ctx, bson.M{"_id": large.ID, "images_purged_at": bson.M{"$exists": true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Images) != 0 || got.ImagesPurgedAt == nil || !got.ImagesPurgedAt.Equal(now) || got.Summary.ImageCount != 1 {
+			t.Errorf("purged scan has %d images, purged at %v, summary %+v", len(got.Images), got.ImagesPurgedAt, got.Summary)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		deleted, err := store.Delete(ctx, bson.M{"_id": small.ID, "user_id": user})
+		if err != nil || deleted != 1 {
+			t.Fatalf("Delete = %d, %v", deleted, err)
+		}
+		deleted, err = store.Delete(ctx, bson.M{"_id": small.ID})
+		if err != nil || deleted != 0 {
+			t.Errorf("second Delete = %d, %v, want 0", deleted, err)
+		}
+		if _, err := store.FindOne(ctx, bson.M{"_id": small.ID}); err != mongo.ErrNoDocuments {
+			t.Errorf("FindOne after Delete: err = %v", err)
+		}
+
+		overview, err := store.GetOverview(ctx, &Scan{ID: small.ID}, FilterOptions{})
+		if err != nil || overview.ImageCount != 0 {
+			t.Errorf("images after Delete = %+v, %v, want none", overview, err)
+		}
+	})
+}
+
+func scanURLs(scans []Scan) []string {
+	urls := []string{}
+	for _, s := range scans {
+		urls = append(urls, s.URL)
+	}
+	return urls
+}
@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ScheduleHandler struct {
+	service *ScheduleService
+	repo    *ScheduleRepository
+	access  *org.AccessService
+}
+
+func NewScheduleHandler(service *ScheduleService, repo *ScheduleRepository, access *org.AccessService) *ScheduleHandler {
+	return &ScheduleHandler{service: service, repo: repo, access: access}
+}
+
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL       string              `json:"url"`
+		Cron      string              `json:"cron"`
+		ProjectID *primitive.ObjectID `json:"project_id"`
+		scan.ScanOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		http.Error(w, "Missing URL parameter", http.StatusBadRequest)
+		return
+	}
+	if req.Cron == "" {
+		http.Error(w, "Missing cron parameter", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := url.ParseRequestURI(req.URL); err != nil {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.ScanOptions.Validate(); err != nil {
+		http.Error(w, "Invalid scan options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.UserID(r.Context())
+	if req.ProjectID != nil {
+		if err := h.access.Authorize(r.Context(), userID, *req.ProjectID, org.RoleEditor); err != nil {
+			org.WriteAccessError(w, err, "Failed to check project access")
+			return
+		}
+	}
+
+	schedule, err := h.service.CreateSchedule(r.Context(), userID, req.ProjectID, req.URL, req.Cron, req.ScanOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (h *ScheduleHandler) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.access.Filter(r.Context(), auth.UserID(r.Context()), org.RoleViewer)
+	if err != nil {
+		http.Error(w, "Failed to fetch schedules", http.StatusInternalServerError)
+		return
+	}
+
+	schedules, err := h.repo.FindMany(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch schedules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"schedules": schedules,
+	})
+}
+
+func (h *ScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.access.Scope(r.Context(), auth.UserID(r.Context()), bson.M{"_id": objectID}, org.RoleEditor)
+	if err != nil {
+		http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+		return
+	}
+
+	deleted, err := h.repo.Delete(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTrend returns total bytes, image count and findings per scan of the
+// schedule's URL. Optional from/to query parameters take RFC 3339 timestamps.
+func (h *ScheduleHandler) GetTrend(w http.ResponseWriter, r *http.Request) {
+	objectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseTimeParam(r, "from")
+	if err != nil {
+		http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(r, "to")
+	if err != nil {
+		http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.access.Scope(r.Context(), auth.UserID(r.Context()), bson.M{"_id": objectID}, org.RoleViewer)
+	if err != nil {
+		http.Error(w, "Failed to fetch schedule", http.StatusInternalServerError)
+		return
+	}
+
+	schedule, err := h.repo.FindOne(r.Context(), filter)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch schedule", http.StatusInternalServerError)
+		return
+	}
+
+	points, err := h.service.GetTrend(r.Context(), schedule, from, to)
+	if err != nil {
+		http.Error(w, "Failed to fetch trend", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":    schedule.URL,
+		"points": points,
+	})
+}
+
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,25 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schedule re-scans a URL on a cron expression. NextRunAt is advanced when an
+// instance claims a run, so the stored state is all a restarted server needs.
+type Schedule struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     string              `json:"user_id" bson:"user_id"`
+	ProjectID  *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	URL        string              `json:"url" bson:"url"`
+	Cron       string              `json:"cron" bson:"cron"`
+	Options    scan.ScanOptions    `json:"options" bson:"options"`
+	Enabled    bool                `json:"enabled" bson:"enabled"`
+	NextRunAt  time.Time           `json:"next_run_at" bson:"next_run_at"`
+	LastRunAt  *time.Time          `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastScanID *primitive.ObjectID `json:"last_scan_id,omitempty" bson:"last_scan_id,omitempty"`
+	LastError  string              `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
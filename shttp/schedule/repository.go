@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScheduleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewScheduleRepository(client *mongo.Client) *ScheduleRepository {
+	return &ScheduleRepository{
+		collection: client.Database("sharprenderdb").Collection("schedules"),
+	}
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, schedule *Schedule) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, schedule)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *ScheduleRepository) FindOne(ctx context.Context, filter interface{}) (*Schedule, error) {
+	var schedule Schedule
+	err := r.collection.FindOne(ctx, filter).Decode(&schedule)
+	return &schedule, err
+}
+
+func (r *ScheduleRepository) FindMany(ctx context.Context, filter interface{}) ([]Schedule, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schedules := []Schedule{}
+	if err = cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (r *ScheduleRepository) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// FindDue returns enabled schedules whose next run is at or before now.
+func (r *ScheduleRepository) FindDue(ctx context.Context, now time.Time, limit int64) ([]Schedule, error) {
+	opts := options.Find().SetSort(bson.M{"next_run_at": 1}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"enabled":     true,
+		"next_run_at": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []Schedule
+	if err = cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// Claim moves a due schedule to its next run time, but only if no other
+// instance has done so since it was read. Exactly one caller wins each run.
+func (r *ScheduleRepository) Claim(ctx context.Context, schedule *Schedule, next time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"_id":         schedule.ID,
+		"enabled":     true,
+		"next_run_at": schedule.NextRunAt,
+	}, bson.M{"$set": bson.M{"next_run_at": next}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Disable turns off a due schedule that can't run, recording why, unless
+// another instance has claimed it since it was read.
+func (r *ScheduleRepository) Disable(ctx context.Context, schedule *Schedule, reason error) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"_id":         schedule.ID,
+		"enabled":     true,
+		"next_run_at": schedule.NextRunAt,
+	}, bson.M{"$set": bson.M{"enabled": false, "last_error": reason.Error()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordRun stores the outcome of a scheduled scan.
+func (r *ScheduleRepository) RecordRun(ctx context.Context, id primitive.ObjectID, ranAt time.Time, scanID *primitive.ObjectID, runErr error) error {
+	set := bson.M{"last_run_at": ranAt, "last_error": ""}
+	if runErr != nil {
+		set["last_error"] = runErr.Error()
+	}
+	if scanID != nil {
+		set["last_scan_id"] = scanID
+	}
+
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": set})
+	return err
+}
@@ -0,0 +1,34 @@
+package schedule
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/internal/sblob"
+	"github.com/voage/sharprender-api/shttp/alert"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewScheduleRoutes(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store) *chi.Mux {
+	repo := NewScheduleRepository(mongoClient)
+	access := org.NewAccessServiceFromClient(mongoClient)
+	service := NewScheduleService(repo, scan.NewScanServiceFromClient(mongoClient, scans, artifacts), access)
+	handler := NewScheduleHandler(service, repo, access)
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Post("/", handler.CreateSchedule)
+	router.Get("/", handler.GetSchedules)
+	router.Delete("/{id}", handler.DeleteSchedule)
+	router.Get("/{id}/trend", handler.GetTrend)
+
+	return router
+}
+
+// NewSchedulerFromClient builds the background scheduler that runs due schedules.
+func NewSchedulerFromClient(mongoClient *mongo.Client, scans scan.ScanStore, artifacts sblob.Store, notifiers alert.Notifiers) *Scheduler {
+	repo := NewScheduleRepository(mongoClient)
+	service := NewScheduleService(repo, scan.NewScanServiceFromClient(mongoClient, scans, artifacts), org.NewAccessServiceFromClient(mongoClient))
+	return NewScheduler(repo, service, alert.NewAlertServiceFromClient(mongoClient, scans, artifacts, notifiers))
+}
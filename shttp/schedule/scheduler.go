@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/alert"
+)
+
+const (
+	pollInterval = time.Minute
+	batchSize    = 10
+)
+
+// Scheduler polls for due schedules and runs them through the scan pipeline.
+// Several servers can run a Scheduler against the same database; each run is
+// claimed by exactly one of them. Every scan it stores is checked against the
+// project's alert rules.
+type Scheduler struct {
+	repo    *ScheduleRepository
+	service *ScheduleService
+	alerts  *alert.AlertService
+}
+
+func NewScheduler(repo *ScheduleRepository, service *ScheduleService, alerts *alert.AlertService) *Scheduler {
+	return &Scheduler{repo: repo, service: service, alerts: alerts}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	due, err := s.repo.FindDue(ctx, time.Now(), batchSize)
+	if err != nil {
+		log.Printf("Failed to fetch due schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range due {
+		result, err := s.service.runDue(ctx, schedule)
+		if err != nil {
+			log.Printf("Scheduled scan of %s failed: %v", schedule.URL, err)
+			continue
+		}
+		if result != nil {
+			log.Printf("Scheduled scan of %s stored as %s", schedule.URL, result.ID.Hex())
+			s.alerts.Evaluate(ctx, result)
+		}
+	}
+}
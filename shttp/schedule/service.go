@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/voage/sharprender-api/shttp/org"
+	"github.com/voage/sharprender-api/shttp/scan"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// minInterval keeps a schedule from launching a browser and AI calls more than
+// once an hour.
+const minInterval = time.Hour
+
+type ScheduleService struct {
+	repo        *ScheduleRepository
+	scanService *scan.ScanService
+	access      *org.AccessService
+}
+
+func NewScheduleService(repo *ScheduleRepository, scanService *scan.ScanService, access *org.AccessService) *ScheduleService {
+	return &ScheduleService{repo: repo, scanService: scanService, access: access}
+}
+
+// minIntervalRuns is how many upcoming runs parseCron checks, so expressions
+// with irregular gaps such as "0,5 * * * *" are caught wherever they start.
+const minIntervalRuns = 100
+
+// parseCron accepts standard five-field expressions and descriptors such as
+// @daily. It is only for new schedules: stored schedules are parsed with
+// cron.ParseStandard, as the interval check depends on when it runs.
+func parseCron(spec string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	run := sched.Next(time.Now())
+	for i := 0; i < minIntervalRuns; i++ {
+		next := sched.Next(run)
+		if next.Sub(run) < minInterval {
+			return nil, fmt.Errorf("schedules may run at most once every %s", minInterval)
+		}
+		run = next
+	}
+	return sched, nil
+}
+
+func (s *ScheduleService) CreateSchedule(ctx context.Context, userID string, projectID *primitive.ObjectID, targetURL, spec string, opts scan.ScanOptions) (*Schedule, error) {
+	sched, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	schedule := &Schedule{
+		UserID:    userID,
+		ProjectID: projectID,
+		URL:       targetURL,
+		Cron:      spec,
+		Options:   opts,
+		Enabled:   true,
+		NextRunAt: sched.Next(now),
+		CreatedAt: now,
+	}
+
+	id, err := s.repo.Create(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	schedule.ID = id
+
+	return schedule, nil
+}
+
+// GetTrend returns the history of scans for the schedule's URL that ran under
+// the schedule's conditions, so manual scans with other profiles stay out.
+// Project schedules include every scan of the URL in the project.
+func (s *ScheduleService) GetTrend(ctx context.Context, schedule *Schedule, from, to *time.Time) ([]scan.TrendPoint, error) {
+	conditions, err := schedule.Options.Conditions()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"user_id": schedule.UserID, "project_id": nil, "url": schedule.URL}
+	if schedule.ProjectID != nil {
+		filter = bson.M{"project_id": *schedule.ProjectID, "url": schedule.URL}
+	}
+	createdAt := bson.M{}
+	if from != nil {
+		createdAt["$gte"] = *from
+	}
+	if to != nil {
+		createdAt["$lte"] = *to
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return s.scanService.GetTrend(ctx, filter, conditions)
+}
+
+// runDue claims and runs one due schedule. It returns the scan it produced, or
+// nil when another instance claimed the run first.
+func (s *ScheduleService) runDue(ctx context.Context, schedule Schedule) (*scan.Scan, error) {
+	now := time.Now()
+	sched, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		// Leaving it enabled would return it from every FindDue.
+		if _, disableErr := s.repo.Disable(ctx, &schedule, fmt.Errorf("invalid cron expression: %w", err)); disableErr != nil {
+			return nil, disableErr
+		}
+		return nil, err
+	}
+
+	// A project schedule runs as its owner, who must still be able to scan
+	// the project.
+	if schedule.ProjectID != nil {
+		err := s.access.Authorize(ctx, schedule.UserID, *schedule.ProjectID, org.RoleEditor)
+		if errors.Is(err, org.ErrForbidden) || errors.Is(err, org.ErrNotFound) {
+			if _, disableErr := s.repo.Disable(ctx, &schedule, errors.New("owner can no longer edit the project")); disableErr != nil {
+				return nil, disableErr
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	claimed, err := s.repo.Claim(ctx, &schedule, sched.Next(now))
+	if err != nil || !claimed {
+		return nil, err
+	}
+
+	result, runErr := s.scanService.RunScan(ctx, scan.ScanRequest{
+		UserID:     schedule.UserID,
+		ProjectID:  schedule.ProjectID,
+		URL:        schedule.URL,
+		Options:    schedule.Options,
+		ScheduleID: &schedule.ID,
+	})
+	var scanID *primitive.ObjectID
+	if runErr == nil {
+		scanID = &result.ID
+	}
+
+	if err := s.repo.RecordRun(ctx, schedule.ID, now, scanID, runErr); err != nil {
+		return result, err
+	}
+	return result, runErr
+}
@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/auth"
+)
+
+type UsageHandler struct {
+	service *UsageService
+}
+
+func NewUsageHandler(service *UsageService) *UsageHandler {
+	return &UsageHandler{service: service}
+}
+
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.service.GetUsage(r.Context(), auth.UserID(r.Context()), time.Now())
+	if err != nil {
+		http.Error(w, "Failed to fetch usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// RequireQuota counts the request as one scan, or answers 429 with
+// Retry-After set to the quota's reset when the user has none left. The scan
+// is given back when the handler doesn't answer with a 2xx status, so invalid
+// or failed requests don't use up the quota.
+func (s *UsageService) RequireQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		userID := auth.UserID(r.Context())
+		if err := s.Reserve(r.Context(), userID, now, 1); err != nil {
+			WriteQuotaError(w, err, now)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status < 200 || recorder.status > 299 {
+			s.Release(context.WithoutCancel(r.Context()), userID, now, 1)
+		}
+	})
+}
+
+// WriteQuotaError answers a failed Reserve made at now.
+func WriteQuotaError(w http.ResponseWriter, err error, now time.Time) {
+	var exceeded *QuotaExceededError
+	if errors.As(err, &exceeded) {
+		tooManyRequests(w, exceeded.ResetsAt.Sub(now), "Scan quota exceeded: "+exceeded.Error())
+		return
+	}
+	http.Error(w, "Failed to check scan quota", http.StatusInternalServerError)
+}
+
+// statusRecorder remembers the status a handler answered with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
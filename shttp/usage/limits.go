@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Limits configures rate limiting and scan quotas. Zero disables a limit.
+type Limits struct {
+	// RequestsPerMinute applies to every authenticated request, separately for
+	// each user session and each API key.
+	RequestsPerMinute int
+	// SharedRequestsPerMinute applies to unauthenticated share link requests,
+	// per client address.
+	SharedRequestsPerMinute int
+	// DailyScans and MonthlyScans cap the scans and crawls a user starts per
+	// UTC calendar day and month, shared by their session and API keys.
+	DailyScans   int
+	MonthlyScans int
+}
+
+var DefaultLimits = Limits{
+	RequestsPerMinute:       120,
+	SharedRequestsPerMinute: 30,
+	DailyScans:              50,
+	MonthlyScans:            500,
+}
+
+// LimitsFromEnv reads RATE_LIMIT_PER_MINUTE, SHARE_RATE_LIMIT_PER_MINUTE,
+// SCAN_QUOTA_DAILY and SCAN_QUOTA_MONTHLY, falling back to DefaultLimits for unset variables.
+func LimitsFromEnv() (Limits, error) {
+	limits := DefaultLimits
+	for name, target := range map[string]*int{
+		"RATE_LIMIT_PER_MINUTE":       &limits.RequestsPerMinute,
+		"SHARE_RATE_LIMIT_PER_MINUTE": &limits.SharedRequestsPerMinute,
+		"SCAN_QUOTA_DAILY":            &limits.DailyScans,
+		"SCAN_QUOTA_MONTHLY":          &limits.MonthlyScans,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Limits{}, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		*target = n
+	}
+	return limits, nil
+}
@@ -0,0 +1,48 @@
+package usage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// Counter holds the number of scans a user started in one calendar day or
+// month (UTC). Start is the period's date, e.g. "2026-10-19" or "2026-10".
+type Counter struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Period    string             `json:"period" bson:"period"`
+	Start     string             `json:"start" bson:"start"`
+	Scans     int                `json:"scans" bson:"scans"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Quota is the usage of one period as shown by GET /usage. A Limit of 0 means
+// unlimited.
+type Quota struct {
+	Used     int       `json:"used"`
+	Limit    int       `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+type Usage struct {
+	Daily             Quota `json:"daily"`
+	Monthly           Quota `json:"monthly"`
+	RequestsPerMinute int   `json:"requests_per_minute"`
+}
+
+// period returns the key and end of the day or month containing now.
+func period(name string, now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if name == PeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
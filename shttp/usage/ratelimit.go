@@ -0,0 +1,114 @@
+package usage
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/auth"
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long a principal's bucket is kept after its last request.
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter keeps an in-memory token bucket per user session and per API
+// key, or per client address, refilled at perMinute and allowing a minute's
+// worth at once.
+type RateLimiter struct {
+	perMinute int
+	key       func(*http.Request) string
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return newRateLimiter(perMinute, func(r *http.Request) string {
+		return principalKey(auth.PrincipalFrom(r.Context()))
+	})
+}
+
+// NewClientRateLimiter limits unauthenticated requests per client address.
+// It uses the connection's address, so a proxy in front of the API should
+// set RemoteAddr from a trusted header.
+func NewClientRateLimiter(perMinute int) *RateLimiter {
+	return newRateLimiter(perMinute, clientKey)
+}
+
+func newRateLimiter(perMinute int, key func(*http.Request) string) *RateLimiter {
+	return &RateLimiter{perMinute: perMinute, key: key, buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// principalKey separates API keys from each other and from the session of
+// the user they belong to.
+func principalKey(p auth.Principal) string {
+	if !p.Session() {
+		return "key:" + p.APIKeyID
+	}
+	return "user:" + p.UserID
+}
+
+func (l *RateLimiter) limiter(key string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleTimeout {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > idleTimeout {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(float64(l.perMinute)/60), l.perMinute)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// Middleware answers 429 with Retry-After once the caller's bucket is empty.
+// Limiters from NewRateLimiter must run after auth.Verifier.Middleware.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.perMinute <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		reservation := l.limiter(l.key(r), now).ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			tooManyRequests(w, delay, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
@@ -0,0 +1,49 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UsageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUsageRepository(client *mongo.Client) *UsageRepository {
+	return &UsageRepository{
+		collection: client.Database("sharprenderdb").Collection("usage"),
+	}
+}
+
+// Add changes a counter by delta, creating it if needed, and returns the new count.
+func (r *UsageRepository) Add(ctx context.Context, userID, period, start string, delta int) (int, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter Counter
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": userID, "period": period, "start": start},
+		bson.M{"$inc": bson.M{"scans": delta}, "$set": bson.M{"updated_at": time.Now()}},
+		opts,
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Scans, nil
+}
+
+// Get returns the count of a period, which is 0 before the first scan.
+func (r *UsageRepository) Get(ctx context.Context, userID, period, start string) (int, error) {
+	var counter Counter
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID, "period": period, "start": start}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Scans, nil
+}
@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// QuotaExceededError tells the caller when the exhausted quota resets.
+type QuotaExceededError struct {
+	Period   string
+	Limit    int
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s scan quota of %d reached", e.Period, e.Limit)
+}
+
+type UsageService struct {
+	repo   counters
+	limits Limits
+}
+
+// counters is the service's view of UsageRepository.
+type counters interface {
+	Add(ctx context.Context, userID, period, start string, delta int) (int, error)
+	Get(ctx context.Context, userID, period, start string) (int, error)
+}
+
+func NewUsageService(repo counters, limits Limits) *UsageService {
+	return &UsageService{repo: repo, limits: limits}
+}
+
+// Reserve counts scans against the user's daily and monthly quotas. When a
+// quota hasn't that many scans left nothing is counted and a
+// *QuotaExceededError is returned.
+func (s *UsageService) Reserve(ctx context.Context, userID string, now time.Time, scans int) error {
+	reserved := []string{}
+	for _, p := range []struct {
+		name  string
+		limit int
+	}{
+		{PeriodDay, s.limits.DailyScans},
+		{PeriodMonth, s.limits.MonthlyScans},
+	} {
+		start, end := period(p.name, now)
+		used, err := s.repo.Add(ctx, userID, p.name, start, scans)
+		if err != nil {
+			s.release(ctx, userID, now, scans, reserved)
+			return err
+		}
+		reserved = append(reserved, p.name)
+
+		// Counting first and undoing on overflow keeps concurrent requests
+		// from both taking the last scan.
+		if p.limit > 0 && used > p.limit {
+			s.release(ctx, userID, now, scans, reserved)
+			return &QuotaExceededError{Period: p.name, Limit: p.limit, ResetsAt: end}
+		}
+	}
+	return nil
+}
+
+// Release gives back scans reserved at now for a request that didn't start
+// them.
+func (s *UsageService) Release(ctx context.Context, userID string, now time.Time, scans int) {
+	s.release(ctx, userID, now, scans, []string{PeriodDay, PeriodMonth})
+}
+
+func (s *UsageService) release(ctx context.Context, userID string, now time.Time, scans int, periods []string) {
+	for _, name := range periods {
+		start, _ := period(name, now)
+		if _, err := s.repo.Add(ctx, userID, name, start, -scans); err != nil {
+			log.Printf("Warning: failed to release %s quota of %s: %v", name, userID, err)
+		}
+	}
+}
+
+// GetUsage reports the user's scans in the current day and month.
+func (s *UsageService) GetUsage(ctx context.Context, userID string, now time.Time) (*Usage, error) {
+	quota := func(name string, limit int) (Quota, error) {
+		start, end := period(name, now)
+		used, err := s.repo.Get(ctx, userID, name, start)
+		return Quota{Used: used, Limit: limit, ResetsAt: end}, err
+	}
+
+	daily, err := quota(PeriodDay, s.limits.DailyScans)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := quota(PeriodMonth, s.limits.MonthlyScans)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{
+		Daily:             daily,
+		Monthly:           monthly,
+		RequestsPerMinute: s.limits.RequestsPerMinute,
+	}, nil
+}
@@ -0,0 +1,19 @@
+package usage
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewUsageRoutes(service *UsageService) *chi.Mux {
+	handler := NewUsageHandler(service)
+
+	router := chi.NewRouter()
+	router.Get("/", handler.GetUsage)
+
+	return router
+}
+
+func NewUsageServiceFromClient(mongoClient *mongo.Client, limits Limits) *UsageService {
+	return NewUsageService(NewUsageRepository(mongoClient), limits)
+}
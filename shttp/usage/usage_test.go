@@ -0,0 +1,145 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/auth"
+)
+
+// memoryCounters is an in-memory UsageRepository.
+type memoryCounters map[string]int
+
+func (m memoryCounters) Add(ctx context.Context, userID, period, start string, delta int) (int, error) {
+	key := userID + "/" + period + "/" + start
+	m[key] += delta
+	return m[key], nil
+}
+
+func (m memoryCounters) Get(ctx context.Context, userID, period, start string) (int, error) {
+	return m[userID+"/"+period+"/"+start], nil
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	service := NewUsageService(memoryCounters{}, Limits{DailyScans: 5, MonthlyScans: 8})
+
+	if err := service.Reserve(ctx, "ann", now, 3); err != nil {
+		t.Fatal(err)
+	}
+	var exceeded *QuotaExceededError
+	if err := service.Reserve(ctx, "ann", now, 3); !errors.As(err, &exceeded) || exceeded.Period != PeriodDay {
+		t.Fatalf("err = %v, want the daily quota exceeded", err)
+	}
+	if !exceeded.ResetsAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ResetsAt = %v, want midnight", exceeded.ResetsAt)
+	}
+	if err := service.Reserve(ctx, "bob", now, 5); err != nil {
+		t.Errorf("quotas aren't per user: %v", err)
+	}
+
+	if err := service.Reserve(ctx, "ann", now, 2); err != nil {
+		t.Fatal(err)
+	}
+	// The day before has its own daily quota but shares the month's.
+	if err := service.Reserve(ctx, "ann", now.AddDate(0, 0, -1), 4); !errors.As(err, &exceeded) || exceeded.Period != PeriodMonth {
+		t.Errorf("err = %v, want the monthly quota exceeded", err)
+	}
+
+	usage, err := service.GetUsage(ctx, "ann", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Daily.Used != 5 || usage.Monthly.Used != 5 {
+		t.Errorf("usage = %+v, want failed reservations left out", usage)
+	}
+
+	service.Release(ctx, "ann", now, 2)
+	if usage, _ := service.GetUsage(ctx, "ann", now); usage.Daily.Used != 3 || usage.Monthly.Used != 3 {
+		t.Errorf("usage after release = %+v", usage)
+	}
+}
+
+func TestRequireQuota(t *testing.T) {
+	service := NewUsageService(memoryCounters{}, Limits{DailyScans: 2})
+	status := http.StatusBadRequest
+	handler := service.RequireQuota(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/scan", nil)
+		req = req.WithContext(auth.WithUserID(req.Context(), "ann"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := serve(); w.Code != http.StatusBadRequest {
+			t.Fatalf("rejected request %d: %d", i, w.Code)
+		}
+	}
+
+	status = http.StatusAccepted
+	serve()
+	serve()
+	w := serve()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("third scan: %d, Retry-After %q, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewClientRateLimiter(2)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(addr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/shared/abc", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if got := serve("192.0.2.1:1234"); got != want {
+			t.Fatalf("status = %d, want %d", got, want)
+		}
+	}
+	// Another port of the same address shares its bucket; another address
+	// has its own.
+	if got := serve("192.0.2.1:5678"); got != http.StatusTooManyRequests {
+		t.Errorf("same address: status = %d, want 429", got)
+	}
+	if got := serve("192.0.2.2:1234"); got != http.StatusOK {
+		t.Errorf("other address: status = %d, want 200", got)
+	}
+}
+
+func TestPrincipalRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(p auth.Principal) int {
+		req := httptest.NewRequest(http.MethodGet, "/scan", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	session := auth.Principal{UserID: "ann"}
+	key := auth.Principal{UserID: "ann", APIKeyID: "k1"}
+	if serve(session) != http.StatusOK || serve(key) != http.StatusOK {
+		t.Fatal("first requests were limited")
+	}
+	if got := serve(session); got != http.StatusTooManyRequests {
+		t.Errorf("session: status = %d, want 429", got)
+	}
+	if got := serve(key); got != http.StatusTooManyRequests {
+		t.Errorf("API key: status = %d, want 429", got)
+	}
+}
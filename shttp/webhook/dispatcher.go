@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/voage/sharprender-api/internal/soutbound"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	dispatchInterval = 5 * time.Second
+	// claimLease is how long a claimed delivery is hidden from other
+	// dispatchers; it outlasts requestTimeout.
+	claimLease     = time.Minute
+	requestTimeout = 10 * time.Second
+	retryBase      = 30 * time.Second
+	maxAttempts    = 8
+)
+
+// Dispatcher sends pending deliveries, retrying failures with exponential
+// backoff until maxAttempts. Several servers can run a Dispatcher against the
+// same database; each attempt is claimed by one of them. Endpoints on
+// non-public addresses are refused and redirects aren't followed, so
+// deliveries can't probe internal services.
+type Dispatcher struct {
+	endpoints  *EndpointRepository
+	deliveries *DeliveryRepository
+	client     *http.Client
+}
+
+func NewDispatcher(endpoints *EndpointRepository, deliveries *DeliveryRepository) *Dispatcher {
+	return &Dispatcher{
+		endpoints:  endpoints,
+		deliveries: deliveries,
+		client:     soutbound.NewClient(requestTimeout),
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := d.deliveries.ClaimDue(ctx, time.Now(), claimLease)
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to claim webhook delivery: %v", err)
+			return
+		}
+		d.dispatch(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, delivery *Delivery) {
+	now := time.Now()
+	endpoint, err := d.endpoints.FindOne(ctx, bson.M{"_id": delivery.EndpointID})
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Failed to load webhook %s: %v", delivery.EndpointID.Hex(), err)
+			return
+		}
+		attempt := Attempt{At: now, Error: "webhook was deleted"}
+		if err := d.deliveries.RecordAttempt(ctx, delivery.ID, attempt, StatusFailed, nil); err != nil {
+			log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+		}
+		return
+	}
+
+	attempt := d.send(ctx, endpoint, delivery, now)
+
+	status, next := StatusSucceeded, (*time.Time)(nil)
+	if attempt.Error != "" {
+		status = StatusFailed
+		if attempts := len(delivery.Attempts) + 1; attempts < maxAttempts {
+			status = StatusPending
+			retryAt := now.Add(retryBase << (attempts - 1))
+			next = &retryAt
+		}
+	}
+
+	if err := d.deliveries.RecordAttempt(ctx, delivery.ID, attempt, status, next); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+// send makes one signed request of a delivery. Any 2xx response counts as
+// received.
+func (d *Dispatcher) send(ctx context.Context, endpoint *Endpoint, delivery *Delivery, now time.Time) Attempt {
+	attempt := Attempt{At: now}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Sharprender-Webhooks/1.0")
+	req.Header.Set("X-Sharprender-Event", delivery.Event)
+	req.Header.Set("X-Sharprender-Delivery", delivery.ID.Hex())
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, now, body))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = time.Since(now).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSendSignsDelivery(t *testing.T) {
+	const secret = "whsec_test"
+
+	received := make(chan error, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := Verify(secret, r.Header.Get(SignatureHeader), body, 5*time.Minute, time.Now())
+		if r.Header.Get("X-Sharprender-Event") != EventPing {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		received <- err
+	}))
+	defer receiver.Close()
+
+	_, body, err := newPayload(EventPing, map[string]string{"hello": "world"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &Endpoint{URL: receiver.URL, Secret: secret}
+	delivery := &Delivery{ID: primitive.NewObjectID(), Event: EventPing, Payload: body}
+
+	d := &Dispatcher{client: receiver.Client()}
+	attempt := d.send(context.Background(), endpoint, delivery, time.Now())
+	if attempt.Error != "" || attempt.StatusCode != http.StatusOK {
+		t.Fatalf("attempt = %+v, want a 200", attempt)
+	}
+	if err := <-received; err != nil {
+		t.Fatalf("receiver rejected the signature: %v", err)
+	}
+
+	endpoint.Secret = "whsec_other"
+	d.send(context.Background(), endpoint, delivery, time.Now())
+	if err := <-received; err != ErrInvalidSignature {
+		t.Fatalf("wrong secret: got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSendRecordsFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	d := &Dispatcher{client: receiver.Client()}
+	attempt := d.send(context.Background(), &Endpoint{URL: receiver.URL}, &Delivery{Payload: "{}"}, time.Now())
+	if attempt.StatusCode != http.StatusServiceUnavailable || attempt.Error == "" {
+		t.Fatalf("attempt = %+v, want a failed 503", attempt)
+	}
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types endpoints can subscribe to.
+const (
+	EventScanCompleted      = "scan.completed"
+	EventScanFailed         = "scan.failed"
+	EventBudgetViolated     = "budget.violated"
+	EventRegressionDetected = "regression.detected"
+	// EventPing is only sent on request, to check an endpoint.
+	EventPing = "ping"
+)
+
+var Events = []string{EventScanCompleted, EventScanFailed, EventBudgetViolated, EventRegressionDetected}
+
+func validateEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+	for _, e := range events {
+		known := false
+		for _, k := range Events {
+			if e == k {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+// Endpoint receives the events of a user's personal scans, or of a project's
+// scans when ProjectID is set.
+type Endpoint struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    string              `json:"user_id" bson:"user_id"`
+	ProjectID *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	URL       string              `json:"url" bson:"url"`
+	Events    []string            `json:"events" bson:"events"`
+	// RegressionPercent is the growth in total image bytes that raises
+	// regression.detected; 0 means DefaultRegressionPercent.
+	RegressionPercent float64 `json:"regression_percent,omitempty" bson:"regression_percent,omitempty"`
+	// Secret signs deliveries. It is only shown when the endpoint is created.
+	Secret    string    `json:"-" bson:"secret"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// DefaultRegressionPercent is the byte growth that counts as a regression for
+// endpoints that don't set their own.
+const DefaultRegressionPercent = 10
+
+// RegressionThreshold is the byte growth, in percent, that counts as a
+// regression for the endpoint.
+func (e *Endpoint) RegressionThreshold() float64 {
+	if e.RegressionPercent > 0 {
+		return e.RegressionPercent
+	}
+	return DefaultRegressionPercent
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Delivery is one event sent, or still to be sent, to an endpoint. Payload is
+// the exact body, so retries and replays are signed over the same bytes.
+type Delivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EndpointID primitive.ObjectID `json:"endpoint_id" bson:"endpoint_id"`
+	EventID    string             `json:"event_id" bson:"event_id"`
+	Event      string             `json:"event" bson:"event"`
+	Payload    string             `json:"payload" bson:"payload"`
+	Status     string             `json:"status" bson:"status"`
+	Attempts   []Attempt          `json:"attempts" bson:"attempts"`
+	// NextAttemptAt is when a pending delivery is due.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	// ReplayOf is the delivery this one replays.
+	ReplayOf  *primitive.ObjectID `json:"replay_of,omitempty" bson:"replay_of,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// Attempt is one request of a delivery. StatusCode is 0 when no response
+// arrived.
+type Attempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code" bson:"status_code"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// payload is the JSON body of every delivery.
+type payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func newPayload(event string, data interface{}, now time.Time) (string, string, error) {
+	id := primitive.NewObjectID().Hex()
+	body, err := json.Marshal(payload{ID: id, Type: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return "", "", err
+	}
+	return id, string(body), nil
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EndpointRepository struct {
+	collection *mongo.Collection
+}
+
+func NewEndpointRepository(client *mongo.Client) *EndpointRepository {
+	return &EndpointRepository{
+		collection: client.Database("sharprenderdb").Collection("webhooks"),
+	}
+}
+
+func (r *EndpointRepository) Create(ctx context.Context, endpoint *Endpoint) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, endpoint)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *EndpointRepository) FindOne(ctx context.Context, filter interface{}) (*Endpoint, error) {
+	var endpoint Endpoint
+	err := r.collection.FindOne(ctx, filter).Decode(&endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+func (r *EndpointRepository) FindMany(ctx context.Context, filter interface{}) ([]Endpoint, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	endpoints := []Endpoint{}
+	if err = cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (r *EndpointRepository) Delete(ctx context.Context, filter interface{}) (int64, error) {
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+type DeliveryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDeliveryRepository(client *mongo.Client) *DeliveryRepository {
+	return &DeliveryRepository{
+		collection: client.Database("sharprenderdb").Collection("webhook_deliveries"),
+	}
+}
+
+func (r *DeliveryRepository) Create(ctx context.Context, delivery *Delivery) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, delivery)
+	if err != nil {
+		return primitive.ObjectID{}, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *DeliveryRepository) FindOne(ctx context.Context, filter interface{}) (*Delivery, error) {
+	var delivery Delivery
+	err := r.collection.FindOne(ctx, filter).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// FindMany returns up to limit matching deliveries, newest first.
+func (r *DeliveryRepository) FindMany(ctx context.Context, filter interface{}, limit int64) ([]Delivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []Delivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDue returns a pending delivery that is due and pushes its next attempt
+// back by lease, so other dispatchers skip it while it is being sent. It
+// returns mongo.ErrNoDocuments when nothing is due.
+func (r *DeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*Delivery, error) {
+	var delivery Delivery
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"status": StatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// RecordAttempt appends an attempt and sets the outcome. A nil next leaves
+// the delivery without a due time, as it is finished.
+func (r *DeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt Attempt, status string, next *time.Time) error {
+	update := bson.M{
+		"$push": bson.M{"attempts": attempt},
+		"$set":  bson.M{"status": status},
+	}
+	if next != nil {
+		update["$set"].(bson.M)["next_attempt_at"] = *next
+	} else {
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DeleteForEndpoint removes the delivery log of a deleted endpoint.
+func (r *DeliveryRepository) DeleteForEndpoint(ctx context.Context, endpointID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"endpoint_id": endpointID})
+	return err
+}
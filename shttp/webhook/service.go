@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	secretBytes  = 32
+	secretPrefix = "whsec_"
+	// deliveryLogLimit caps the deliveries listed for an endpoint.
+	deliveryLogLimit = 100
+)
+
+// WebhookService manages endpoints and queues deliveries for the Dispatcher.
+// Managing a project's endpoints needs editor rights on the project.
+type WebhookService struct {
+	endpoints  *EndpointRepository
+	deliveries *DeliveryRepository
+	access     *org.AccessService
+}
+
+func NewWebhookService(endpoints *EndpointRepository, deliveries *DeliveryRepository, access *org.AccessService) *WebhookService {
+	return &WebhookService{endpoints: endpoints, deliveries: deliveries, access: access}
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing
+// secret, which can't be shown again. The URL and events are validated by
+// the caller.
+func (s *WebhookService) CreateEndpoint(ctx context.Context, userID string, projectID *primitive.ObjectID, rawURL string, events []string, regressionPercent float64) (*Endpoint, string, error) {
+	if projectID != nil {
+		if err := s.access.Authorize(ctx, userID, *projectID, org.RoleEditor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	raw := make([]byte, secretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	endpoint := Endpoint{
+		UserID:    userID,
+		ProjectID: projectID,
+		URL:       rawURL,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+
+		RegressionPercent: regressionPercent,
+	}
+
+	id, err := s.endpoints.Create(ctx, &endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook: %w", err)
+	}
+	endpoint.ID = id
+
+	return &endpoint, secret, nil
+}
+
+// GetEndpoints lists the user's personal endpoints and those of the projects
+// they can edit.
+func (s *WebhookService) GetEndpoints(ctx context.Context, userID string) ([]Endpoint, error) {
+	filter, err := s.access.Filter(ctx, userID, org.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	return s.endpoints.FindMany(ctx, filter)
+}
+
+// endpoint loads an endpoint the user may manage, or mongo.ErrNoDocuments.
+func (s *WebhookService) endpoint(ctx context.Context, userID string, id primitive.ObjectID) (*Endpoint, error) {
+	filter, err := s.access.Scope(ctx, userID, bson.M{"_id": id}, org.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	return s.endpoints.FindOne(ctx, filter)
+}
+
+// DeleteEndpoint removes an endpoint with its delivery log.
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, userID string, id primitive.ObjectID) error {
+	endpoint, err := s.endpoint(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.endpoints.Delete(ctx, bson.M{"_id": endpoint.ID}); err != nil {
+		return err
+	}
+	return s.deliveries.DeleteForEndpoint(ctx, endpoint.ID)
+}
+
+// GetDeliveries returns the most recent deliveries to an endpoint.
+func (s *WebhookService) GetDeliveries(ctx context.Context, userID string, endpointID primitive.ObjectID) ([]Delivery, error) {
+	endpoint, err := s.endpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliveries.FindMany(ctx, bson.M{"endpoint_id": endpoint.ID}, deliveryLogLimit)
+}
+
+// Replay queues a new delivery with the same payload as an earlier one.
+func (s *WebhookService) Replay(ctx context.Context, userID string, endpointID, deliveryID primitive.ObjectID) (*Delivery, error) {
+	endpoint, err := s.endpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.deliveries.FindOne(ctx, bson.M{"_id": deliveryID, "endpoint_id": endpoint.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queue(ctx, endpoint.ID, original.EventID, original.Event, original.Payload, &original.ID)
+}
+
+// Ping queues a ping event to check an endpoint.
+func (s *WebhookService) Ping(ctx context.Context, userID string, endpointID primitive.ObjectID) (*Delivery, error) {
+	endpoint, err := s.endpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID, body, err := newPayload(EventPing, map[string]string{"endpoint_id": endpoint.ID.Hex()}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.queue(ctx, endpoint.ID, eventID, EventPing, body, nil)
+}
+
+func (s *WebhookService) queue(ctx context.Context, endpointID primitive.ObjectID, eventID, event, body string, replayOf *primitive.ObjectID) (*Delivery, error) {
+	now := time.Now()
+	delivery := Delivery{
+		EndpointID:    endpointID,
+		EventID:       eventID,
+		Event:         event,
+		Payload:       body,
+		Status:        StatusPending,
+		Attempts:      []Attempt{},
+		NextAttemptAt: &now,
+		ReplayOf:      replayOf,
+		CreatedAt:     now,
+	}
+
+	id, err := s.deliveries.Create(ctx, &delivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue delivery: %w", err)
+	}
+	delivery.ID = id
+
+	return &delivery, nil
+}
+
+// Publish queues an event for every endpoint subscribed to it: the owner's
+// personal endpoints for personal scans, the project's endpoints for project
+// scans, skipping those registered by users who lost editor rights. Failures
+// are logged rather than returned so events never fail the work that raised
+// them.
+func (s *WebhookService) Publish(ctx context.Context, event, userID string, projectID *primitive.ObjectID, data interface{}) {
+	s.PublishTo(ctx, event, userID, projectID, data, nil)
+}
+
+// PublishTo is Publish limited to the subscribed endpoints accept returns
+// true for, for events whose trigger depends on endpoint settings. A nil
+// accept takes every endpoint.
+func (s *WebhookService) PublishTo(ctx context.Context, event, userID string, projectID *primitive.ObjectID, data interface{}, accept func(*Endpoint) bool) {
+	filter := bson.M{"user_id": userID, "project_id": nil, "events": event}
+	if projectID != nil {
+		filter = bson.M{"project_id": *projectID, "events": event}
+	}
+
+	found, err := s.endpoints.FindMany(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to find webhooks for %s: %v", event, err)
+		return
+	}
+	var endpoints []Endpoint
+	for i := range found {
+		if accept == nil || accept(&found[i]) {
+			endpoints = append(endpoints, found[i])
+		}
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	eventID, body, err := newPayload(event, data, time.Now())
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event, err)
+		return
+	}
+	for _, e := range endpoints {
+		// Project endpoints only receive events while whoever registered them
+		// can still edit the project.
+		if projectID != nil {
+			if err := s.access.Authorize(ctx, e.UserID, *projectID, org.RoleEditor); err != nil {
+				if !errors.Is(err, org.ErrForbidden) && !errors.Is(err, org.ErrNotFound) {
+					log.Printf("Failed to check access of webhook %s: %v", e.ID.Hex(), err)
+				}
+				continue
+			}
+		}
+		if _, err := s.queue(ctx, e.ID, eventID, event, body, nil); err != nil {
+			log.Printf("Failed to queue %s for webhook %s: %v", event, e.ID.Hex(), err)
+		}
+	}
+}
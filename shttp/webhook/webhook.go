@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/voage/sharprender-api/shttp/auth"
+	"github.com/voage/sharprender-api/shttp/org"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewWebhookRoutes(mongoClient *mongo.Client) *chi.Mux {
+	handler := NewWebhookHandler(NewWebhookServiceFromClient(mongoClient))
+
+	router := chi.NewRouter()
+	router.Use(auth.RequireSession)
+	router.Post("/", handler.CreateWebhook)
+	router.Get("/", handler.GetWebhooks)
+	router.Delete("/{id}", handler.DeleteWebhook)
+	router.Get("/{id}/deliveries", handler.GetDeliveries)
+	router.Post("/{id}/deliveries/{deliveryID}/replay", handler.ReplayDelivery)
+	router.Post("/{id}/ping", handler.PingWebhook)
+
+	return router
+}
+
+// NewWebhookServiceFromClient builds the service other packages publish
+// events through.
+func NewWebhookServiceFromClient(mongoClient *mongo.Client) *WebhookService {
+	return NewWebhookService(
+		NewEndpointRepository(mongoClient),
+		NewDeliveryRepository(mongoClient),
+		org.NewAccessServiceFromClient(mongoClient),
+	)
+}
+
+// NewDispatcherFromClient builds the background dispatcher that sends
+// deliveries.
+func NewDispatcherFromClient(mongoClient *mongo.Client) *Dispatcher {
+	return NewDispatcher(NewEndpointRepository(mongoClient), NewDeliveryRepository(mongoClient))
+}
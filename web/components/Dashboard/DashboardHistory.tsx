@@ -1,5 +1,5 @@
 import useScanHistory from "@/hooks/dashboard/useScanHistory";
-import { useEffect } from "react";
+import { useEffect, useState } from "react";
 import DashboardEmptyState from "./DashboardEmptyState";
 import { useUser } from "@clerk/nextjs";
 import DashboardHistoryCard from "./DashboardHistoryCard";
@@ -14,14 +14,15 @@ import {
 import { ChevronDownIcon } from "lucide-react";
 
 const DashboardHistory = () => {
-  const { scans, getScanHistory } = useScanHistory();
+  const { scans, getScanHistory, loadMore, hasMore } = useScanHistory();
   const { isLoaded } = useUser();
+  const [order, setOrder] = useState<"asc" | "desc">("desc");
 
   useEffect(() => {
     if (isLoaded) {
-      getScanHistory();
+      getScanHistory({ order });
     }
-  }, [getScanHistory, isLoaded]);
+  }, [getScanHistory, isLoaded, order]);
 
   if (scans.length === 0) {
     return <DashboardEmptyState />;
@@ -75,7 +76,12 @@ const DashboardHistory = () => {
               </ListBox>
             </Popover>
           </Select>
-          <Select defaultSelectedKey="recent">
+          <Select
+            selectedKey={order === "desc" ? "recent" : "oldest"}
+            onSelectionChange={(key) =>
+              setOrder(key === "oldest" ? "asc" : "desc")
+            }
+          >
             <Button className="flex items-center justify-between w-[160px] px-4 py-2 border border-gray-200 rounded-lg text-sm text-gray-600 bg-white hover:border-gray-300 focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent">
               <SelectValue />
               <ChevronDownIcon className="w-4 h-4 ml-2" />
@@ -108,6 +114,14 @@ const DashboardHistory = () => {
           />
         ))}
       </div>
+      {hasMore && (
+        <button
+          className="self-center px-4 py-2 border border-gray-200 rounded-lg text-sm text-gray-600 bg-white hover:border-gray-300"
+          onClick={() => loadMore({ order })}
+        >
+          Load more
+        </button>
+      )}
     </section>
   );
 };
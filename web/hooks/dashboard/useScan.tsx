@@ -1,28 +1,29 @@
 import { useState } from "react";
 import { ScanResult } from "@/types/scan";
 import { fetcher } from "@/lib/fetcher";
-import { useUser } from "@clerk/nextjs";
+import { useAuth } from "@clerk/nextjs";
 
 const useScan = () => {
   const [scan, setScan] = useState<ScanResult | null>(null);
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
-  const { user } = useUser();
+  const { isSignedIn, getToken } = useAuth();
 
   const createScan = async (url: string): Promise<string> => {
-    if (!user || !user.id) {
-      throw new Error("User is not logged in or user ID is missing.");
+    if (!isSignedIn) {
+      throw new Error("User is not logged in.");
     }
 
     setIsLoading(true);
     try {
-      const response = await fetcher<{ scan_id: string }>(`/scan`, {
-        method: "POST",
-        data: {
-          user_id: user.id.toString(),
-          url,
+      const response = await fetcher<{ scan_id: string }>(
+        `/scan`,
+        {
+          method: "POST",
+          data: { url },
         },
-      });
+        await getToken()
+      );
       return response.scan_id;
     } catch (err) {
       const errorMessage = err instanceof Error ? err.message : String(err);
@@ -36,7 +37,11 @@ const useScan = () => {
   const getScanResults = async (scanId: string): Promise<void> => {
     setIsLoading(true);
     try {
-      const response = await fetcher<ScanResult>(`/scan/${scanId}`);
+      const response = await fetcher<ScanResult>(
+        `/scan/${scanId}`,
+        undefined,
+        await getToken()
+      );
       setScan(response);
     } catch (err) {
       const errorMessage = err instanceof Error ? err.message : String(err);
@@ -1,20 +1,47 @@
 import { fetcher } from "@/lib/fetcher";
-import { Scan } from "@/types/scan";
-import { useUser } from "@clerk/nextjs";
+import { Scan, ScanHistoryPage, ScanHistoryQuery } from "@/types/scan";
+import { useAuth } from "@clerk/nextjs";
 import { useState, useCallback } from "react";
 
 const useScanHistory = () => {
   const [scans, setScans] = useState<Scan[]>([]);
-  const { user } = useUser();
+  const [nextCursor, setNextCursor] = useState("");
+  const { getToken } = useAuth();
 
-  const getScanHistory = useCallback(async () => {
-    const response = await fetcher<{ scans: Scan[] }>(
-      `/scan/history?user_id=${user?.id}`
-    );
-    setScans(response.scans);
-  }, [user?.id]);
+  const fetchPage = useCallback(
+    async (query: ScanHistoryQuery, cursor?: string) => {
+      return fetcher<ScanHistoryPage>(
+        `/scan/history`,
+        { params: { ...query, cursor: cursor || undefined } },
+        await getToken()
+      );
+    },
+    [getToken]
+  );
 
-  return { scans, getScanHistory };
+  const getScanHistory = useCallback(
+    async (query: ScanHistoryQuery = {}) => {
+      const response = await fetchPage(query);
+      setScans(response.scans);
+      setNextCursor(response.next_cursor);
+    },
+    [fetchPage]
+  );
+
+  // loadMore appends the next page; query must match the one of the first page.
+  const loadMore = useCallback(
+    async (query: ScanHistoryQuery = {}) => {
+      if (!nextCursor) {
+        return;
+      }
+      const response = await fetchPage(query, nextCursor);
+      setScans((previous) => [...previous, ...response.scans]);
+      setNextCursor(response.next_cursor);
+    },
+    [fetchPage, nextCursor]
+  );
+
+  return { scans, getScanHistory, loadMore, hasMore: nextCursor !== "" };
 };
 
 export default useScanHistory;
@@ -0,0 +1,22 @@
+import { fetcher } from "@/lib/fetcher";
+import { Usage } from "@/types/usage";
+import { useAuth } from "@clerk/nextjs";
+import { useState, useCallback } from "react";
+
+const useUsage = () => {
+  const [usage, setUsage] = useState<Usage | null>(null);
+  const { getToken } = useAuth();
+
+  const getUsage = useCallback(async () => {
+    const response = await fetcher<Usage>(
+      `/usage`,
+      undefined,
+      await getToken()
+    );
+    setUsage(response);
+  }, [getToken]);
+
+  return { usage, getUsage };
+};
+
+export default useUsage;
@@ -9,10 +9,15 @@ const axiosInstance = axios.create({
 
 export const fetcher = async <T>(
   url: string,
-  config?: AxiosRequestConfig
+  config?: AxiosRequestConfig,
+  token?: string | null
 ): Promise<T> => {
   const response = await axiosInstance(url, {
     ...config,
+    headers: {
+      ...config?.headers,
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
+    },
   });
   return response.data;
 };
@@ -2,6 +2,7 @@ import DashboardDataGrid from "@/components/Dashboard/DashboardDataGrid";
 import { DashboardLayout } from "@/components/DashboardLayout";
 import { fetcher } from "@/lib/fetcher";
 import { ScanResult } from "@/types/scan";
+import { getAuth } from "@clerk/nextjs/server";
 import { GetServerSidePropsContext } from "next";
 
 export default function History({ scan }: { scan: ScanResult }) {
@@ -21,7 +22,12 @@ export async function getServerSideProps(context: GetServerSidePropsContext) {
   }
 
   try {
-    const scan = await fetcher<ScanResult>(`/scan/${id}`);
+    const { getToken } = getAuth(context.req);
+    const scan = await fetcher<ScanResult>(
+      `/scan/${id}`,
+      undefined,
+      await getToken()
+    );
 
     if (!scan) {
       return {
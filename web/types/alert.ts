@@ -0,0 +1,42 @@
+export interface AlertChannel {
+  type: "email" | "slack" | "teams";
+  // Recipients of email channels.
+  to?: string[];
+  // Incoming webhook of chat channels.
+  url?: string;
+}
+
+export interface AlertRule {
+  id: string;
+  project_id: string;
+  name: string;
+  url?: string;
+  bytes_increase_percent?: number;
+  finding_severity?: "critical" | "warning" | "info";
+  budget_violations: boolean;
+  channels: AlertChannel[];
+  created_by: string;
+  created_at: string;
+}
+
+export interface AlertReason {
+  kind: "bytes" | "finding" | "budget";
+  key: string;
+  message: string;
+}
+
+export interface Alert {
+  id: string;
+  rule_id: string;
+  project_id: string;
+  url: string;
+  baseline_scan_id?: string;
+  scan_id: string;
+  reasons: AlertReason[];
+  scans: number;
+  first_seen_at: string;
+  last_seen_at: string;
+  notified_at?: string;
+  resolved_at?: string;
+  errors?: string[];
+}
@@ -66,19 +66,115 @@ export interface ImageScanResult {
   network: NetworkInfo;
   timing: TimingInfo;
   ai_recommendation: AIRecommendation;
+  content_hash?: string;
+  perceptual_hash?: string;
+  lcp: boolean;
+  above_fold: boolean;
+  optimization?: ImageOptimization;
+}
+
+export interface ImageOptimization {
+  format: string;
+  quality: number;
+  width: number;
+  height: number;
+  original_size: number;
+  size: number;
+  savings: number;
+  psnr: number;
+  artifact_key?: string;
+}
+
+export interface Finding {
+  rule_id: string;
+  severity: "critical" | "warning" | "info";
+  message: string;
+  images: string[];
+}
+
+export interface BudgetCheck {
+  metric: string;
+  limit: number;
+  actual: number;
+  passed: boolean;
+  images?: string[];
+}
+
+export interface BudgetResult {
+  budget_id?: string;
+  name: string;
+  passed: boolean;
+  checks: BudgetCheck[];
+}
+
+export interface DuplicateGroup {
+  kind: "exact" | "near";
+  perceptual_hash: string;
+  images: {
+    src: string;
+    format: string;
+    width: number;
+    height: number;
+    size: number;
+  }[];
+  wasted_bytes: number;
+}
+
+export interface ScanConditions {
+  profile: string;
+  network: {
+    download: number;
+    upload: number;
+    latency: number;
+  };
+  cpu_slowdown: number;
 }
 
 export interface Scan {
   id: string;
   scan_id: string;
   user_id: string;
+  project_id?: string;
   url: string;
   metadata: WebsiteMetadata;
   images: ImageScanResult[];
+  conditions: ScanConditions;
+  summary: ScanSummary;
+  archived: boolean;
+  previous_scan_id?: string;
   created_at: string;
+  schema_version: number;
+  images_purged_at?: string;
+}
+
+export interface ScanSummary {
+  image_count: number;
+  total_bytes: number;
+  findings_count: number;
+}
+
+export interface ScanHistoryQuery {
+  sort?: "created_at" | "total_bytes";
+  order?: "asc" | "desc";
+  limit?: number;
+  q?: string;
+  archived?: "exclude" | "include" | "only";
+  from?: string;
+  to?: string;
+  project_id?: string;
+}
+
+export interface ScanHistoryPage {
+  scans: Scan[];
+  next_cursor: string;
 }
 
 export interface ScanResult {
   images: ImageScanResult[];
+  matched_images: number;
   aggregations: Aggregations;
+  findings: Finding[];
+  duplicates: DuplicateGroup[];
+  budgets: BudgetResult[];
+  conditions: ScanConditions;
 }
@@ -0,0 +1,12 @@
+export interface Quota {
+  used: number;
+  // 0 means unlimited.
+  limit: number;
+  resets_at: string;
+}
+
+export interface Usage {
+  daily: Quota;
+  monthly: Quota;
+  requests_per_minute: number;
+}
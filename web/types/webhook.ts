@@ -0,0 +1,37 @@
+export type WebhookEvent =
+  | "scan.completed"
+  | "scan.failed"
+  | "budget.violated"
+  | "regression.detected";
+
+export interface Webhook {
+  id: string;
+  user_id: string;
+  project_id?: string;
+  url: string;
+  events: WebhookEvent[];
+  // Byte growth in percent that raises regression.detected; unset means 10.
+  regression_percent?: number;
+  created_at: string;
+}
+
+export interface WebhookAttempt {
+  at: string;
+  // 0 when no response arrived.
+  status_code: number;
+  error?: string;
+  duration_ms: number;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  endpoint_id: string;
+  event_id: string;
+  event: WebhookEvent | "ping";
+  payload: string;
+  status: "pending" | "succeeded" | "failed";
+  attempts: WebhookAttempt[];
+  next_attempt_at?: string;
+  replay_of?: string;
+  created_at: string;
+}